@@ -0,0 +1,79 @@
+package inject
+
+import (
+	"fmt"
+	"reflect"
+)
+
+// SetAdaptPointers controls whether this injector and its children adapt between pointers and
+// values: a request for *T that is not bound is satisfied by the binding of T, as a pointer to a
+// copy of its value, and a request for T by dereferencing the binding of *T. Bindings of exactly
+// the requested type always take precedence.
+//
+// Without adaptation, such requests fail with an error naming the type that is bound.
+func (s *SafeInjector) SetAdaptPointers(adapt bool) {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	s.adapt = adapt
+	s.generation++
+}
+
+// isAdaptingPointers returns true if s or any of its ancestors adapts between pointers and values.
+func (s *SafeInjector) isAdaptingPointers() bool {
+	for ; s != nil; s = s.parent {
+		s.lock.Lock()
+		adapt := s.adapt
+		s.lock.Unlock()
+		if adapt {
+			return true
+		}
+	}
+	return false
+}
+
+// adaptPointer returns a binding adapting the value or pointer bound to s or its ancestors to the
+// pointer or value key, or nil if adaptation is disabled or neither is bound.
+//
+// Only bindings of exactly the adapted type are considered, so that adaptation does not recurse.
+func (s *SafeInjector) adaptPointer(key Key) *Binding {
+	if !s.isAdaptingPointers() {
+		return nil
+	}
+	t := key.Type
+	from := Key{Type: reflect.PtrTo(t), Name: key.Name}
+	if t.Kind() == reflect.Ptr {
+		from.Type = t.Elem()
+	}
+	bound := false
+	for p := s; p != nil && !bound; p = p.parent {
+		_, bound = p.bindings[from]
+	}
+	if !bound {
+		return nil
+	}
+	binding := &Binding{
+		Provides:   t,
+		Name:       key.Name,
+		Requires:   []reflect.Type{from.Type},
+		annotation: "AdaptPointers",
+	}
+	binding.setBuild(func(r *resolving) (interface{}, error) {
+		v, err := s.getKey(r, from)
+		if err != nil {
+			return nil, err
+		}
+		if t.Kind() == reflect.Ptr {
+			out := reflect.New(t.Elem())
+			if v != nil {
+				out.Elem().Set(reflect.ValueOf(v))
+			}
+			return out.Interface(), nil
+		}
+		rv := reflect.ValueOf(v)
+		if v == nil || rv.IsNil() {
+			return nil, fmt.Errorf("can not adapt nil %s to %s", from.Type, t)
+		}
+		return rv.Elem().Interface(), nil
+	})
+	return binding
+}
@@ -2,8 +2,11 @@ package inject
 
 import (
 	"fmt"
+	"io"
 	"reflect"
+	"runtime"
 	"sync"
+	"time"
 )
 
 // An Annotation modifies how a type is built and retrieved from the SafeInjector.
@@ -48,8 +51,9 @@ func (l *literalAnnotation) String() string {
 
 func (l *literalAnnotation) Build(*SafeInjector) (*Binding, error) {
 	return &Binding{
-		Provides: reflect.TypeOf(l.v),
-		Build:    func() (interface{}, error) { return l.v, nil },
+		Provides:   reflect.TypeOf(l.v),
+		Build:      func() (interface{}, error) { return l.v, nil },
+		annotation: "Literal",
 	}, nil
 }
 
@@ -58,62 +62,99 @@ func (l *literalAnnotation) Is(annotation Annotation) bool {
 }
 
 type providerType struct {
-	v interface{}
+	v    interface{}
+	name string      // Overrides the name of the function, eg. for module methods.
+	id   interface{} // Overrides the identity of the function, eg. for module methods.
 }
 
 // Provider annotates a function to indicate it should be called whenever the type of its return
 // value is requested.
 func Provider(v interface{}) Annotation {
-	return &providerType{v}
+	return &providerType{v: v}
 }
 
 func (p *providerType) Build(i *SafeInjector) (*Binding, error) {
 	f := reflect.ValueOf(p.v)
+	if f.Kind() != reflect.Func {
+		return &Binding{}, fmt.Errorf("provider must be a function returning (<type>[, error]) but got %T", p.v)
+	}
 	ft := f.Type()
-	if ft.Kind() != reflect.Func {
-		return &Binding{}, fmt.Errorf("provider must be a function returning (<type>[, <error>])")
+	name := p.name
+	if name == "" {
+		name = funcName(f)
+	}
+	if err := checkProviderSignature(ft); err != nil {
+		// Functions that aren't module methods may have been intended as values.
+		if p.name == "" {
+			return &Binding{}, fmt.Errorf("invalid provider %s %s: %s; to bind the function itself, use Literal() or AsLiteral()", name, ft, err)
+		}
+		return &Binding{}, fmt.Errorf("invalid provider %s %s: %s", name, ft, err)
 	}
 	rt := ft.Out(0)
 	inputs := []reflect.Type{}
 	for i := 0; i < ft.NumIn(); i++ {
 		inputs = append(inputs, ft.In(i))
 	}
-	switch ft.NumOut() {
-	case 1:
-		if rt == errorType {
-			return &Binding{}, fmt.Errorf("provider must return (<type>[, <error>])")
+	var optional []reflect.Type
+	if ft.IsVariadic() {
+		optional = inputs[len(inputs)-1:]
+	}
+	id := p.id
+	if id == nil {
+		id = f.Pointer()
+	}
+	binding := &Binding{
+		Provides:   rt,
+		Requires:   inputs,
+		provider:   name,
+		providerID: id,
+		optional:   optional,
+		annotation: "Provider",
+	}
+	binding.setBuild(func(r *resolving) (interface{}, error) {
+		start := time.Now()
+		var v interface{}
+		failed := false
+		args, err := i.callArgs(r, p.v, nil, nil)
+		if err == nil {
+			var rv []interface{}
+			rv, err = invoke(p.v, args)
+			if failed = err != nil; !failed {
+				v, err = i.postProcess(rt, rv[0])
+			}
 		}
-		return &Binding{
-			Provides: rt,
-			Requires: inputs,
-			Build: func() (interface{}, error) {
-				rv, err := i.Call(p.v)
-				if err != nil {
-					return nil, err
-				}
-				return rv[0], nil
-			},
-		}, nil
-	case 2:
-		if ft.Out(1) != errorType {
-			return &Binding{}, fmt.Errorf("provider must return (<type>[, <error>])")
+		i.notifyBuilt(rt, v, err, time.Since(start))
+		if failed {
+			return nil, &BuildError{Key: Key{Type: rt}, Provider: name, Err: err}
 		}
-		return &Binding{
-			Provides: rt,
-			Requires: inputs,
-			Build: func() (interface{}, error) {
-				rv, err := i.Call(p.v)
-				if err != nil {
-					return nil, err
-				}
-				if rv[1] != nil {
-					return nil, rv[1].(error)
-				}
-				return rv[0], nil
-			},
-		}, nil
+		return v, err
+	})
+	return binding, nil
+}
+
+// checkProviderSignature checks that ft returns (<type>[, error]), describing what is wrong if not.
+func checkProviderSignature(ft reflect.Type) error {
+	switch n := ft.NumOut(); {
+	case n == 0:
+		return fmt.Errorf("it returns nothing, but must return (<type>[, error])")
+	case ft.Out(0) == errorType && n == 1:
+		return fmt.Errorf("it returns only an error, but must return (<type>, error)")
+	case ft.Out(0) == errorType:
+		return fmt.Errorf("it returns the error first, but must return (<type>, error)")
+	case n > 2:
+		return fmt.Errorf("it returns %d values, but must return (<type>[, error]); use a struct embedding Out to provide multiple values", n)
+	case n == 2 && ft.Out(1) != errorType:
+		return fmt.Errorf("its second return value is %s, but must be error", ft.Out(1))
+	}
+	return nil
+}
+
+// funcName returns the name of the function f.
+func funcName(f reflect.Value) string {
+	if fn := runtime.FuncForPC(f.Pointer()); fn != nil {
+		return fn.Name()
 	}
-	return &Binding{}, fmt.Errorf("provider must return (<type>[, <error>])")
+	return f.Type().String()
 }
 
 func (p *providerType) Is(annotation Annotation) bool {
@@ -145,27 +186,339 @@ func (s *singletonType) Build(i *SafeInjector) (*Binding, error) {
 	if !next.Is(&providerType{}) {
 		return &Binding{}, fmt.Errorf("only providers can be singletons")
 	}
+	if next.Is(&transientType{}) {
+		return &Binding{}, fmt.Errorf("Transient() providers can not be singletons")
+	}
+	if next.Is(&cachedType{}) {
+		return &Binding{}, fmt.Errorf("Cached() providers can not be singletons")
+	}
+	if next.Is(&pooledType{}) {
+		return &Binding{}, fmt.Errorf("Pooled() providers can not be singletons")
+	}
 	builder, err := next.Build(i)
-	if err != nil {
-		return &Binding{}, err
+	if err != nil || builder.disabled {
+		return builder, err
 	}
-	lock := sync.Mutex{}
-	isCached := false
-	var cached interface{}
-	return &Binding{
-		Provides: builder.Provides,
-		Requires: builder.Requires,
-		Build: func() (interface{}, error) {
-			lock.Lock()
-			defer lock.Unlock()
-			var err error
-			if !isCached {
-				cached, err = builder.Build()
-				isCached = true
+	if builder.Provides == contextType {
+		return &Binding{}, fmt.Errorf("context.Context providers can not be singletons")
+	}
+	cache := i.newSingleton(builder)
+	binding := *builder
+	binding.annotation = "Singleton"
+	binding.cache = cache
+	build := builder.builder()
+	binding.setBuild(func(r *resolving) (interface{}, error) {
+		return cache.get(r, build)
+	})
+	return &binding, nil
+}
+
+// Transient annotates a provider function to indicate that it should be called each time its value
+// is requested. This is the default for providers passed to Bind(), but makes the intent explicit,
+// and prevents providers passed to Install() from being bound as singletons. Any Singleton()
+// annotation of the provider is removed.
+//
+//		injector.Install(inject.Providers(NewConfig, inject.Transient(NewRequestID)))
+//
+func Transient(v interface{}) Annotation {
+	return &transientType{v}
+}
+
+type transientType struct {
+	v interface{}
+}
+
+func (t *transientType) Build(i *SafeInjector) (*Binding, error) {
+	v := t.v
+	if singleton, ok := v.(*singletonType); ok {
+		v = singleton.v
+	}
+	next := Annotate(v)
+	if !next.Is(&providerType{}) {
+		return &Binding{}, fmt.Errorf("only providers can be transient")
+	}
+	binding, err := next.Build(i)
+	if err != nil || binding.disabled {
+		return binding, err
+	}
+	binding.annotation = "Transient"
+	return binding, nil
+}
+
+func (t *transientType) Is(annotation Annotation) bool {
+	return reflect.TypeOf(annotation) == reflect.TypeOf(&transientType{}) ||
+		Annotate(t.v).Is(annotation)
+}
+
+// Timeout annotates a binding so that building its value fails with an error naming the binding if
+// it takes longer than d, rather than blocking indefinitely. The build continues in the background
+// and its result is discarded.
+//
+//		injector.Bind(Singleton(Timeout(5*time.Second, DialDatabase)))
+//
+func Timeout(d time.Duration, v interface{}) Annotation {
+	return &timeoutType{d, v}
+}
+
+type timeoutType struct {
+	d time.Duration
+	v interface{}
+}
+
+func (t *timeoutType) Build(i *SafeInjector) (*Binding, error) {
+	binding, err := Annotate(t.v).Build(i)
+	if err != nil || binding.disabled {
+		return binding, err
+	}
+	inner := *binding
+	binding.setBuild(func(r *resolving) (interface{}, error) {
+		key := Key{Type: binding.Provides, Name: binding.Name}
+		type result struct {
+			v   interface{}
+			err error
+		}
+		done := make(chan result, 1)
+		// The build is still part of the resolution of r, although it runs in another goroutine.
+		go func() {
+			v, err := buildRecovered(r, key, &inner)
+			done <- result{v, err}
+		}()
+		timer := time.NewTimer(t.d)
+		defer timer.Stop()
+		select {
+		case r := <-done:
+			return r.v, r.err
+		case <-timer.C:
+			if binding.provider != "" {
+				return nil, fmt.Errorf("provider %s of %s timed out after %s", binding.provider, key, t.d)
 			}
-			return cached, err
-		},
-	}, nil
+			return nil, fmt.Errorf("building %s timed out after %s", key, t.d)
+		}
+	})
+	return binding, nil
+}
+
+func (t *timeoutType) Is(annotation Annotation) bool {
+	return reflect.TypeOf(annotation) == reflect.TypeOf(&timeoutType{}) ||
+		Annotate(t.v).Is(annotation)
+}
+
+// Ref annotates a type, specified as with Has(), as being provided by the value already bound to
+// it. It lets BindTo() convert or adapt another binding, and chains of such conversions:
+//
+//		injector.Bind(MyString("hello"))
+//		injector.BindTo(YourString(""), Ref(MyString("")))
+//		injector.BindTo((*fmt.Stringer)(nil), Ref(YourString("")))
+//
+func Ref(t interface{}) Annotation {
+	return &refType{keyOf(t)}
+}
+
+type refType struct {
+	key Key
+}
+
+func (r *refType) Build(i *SafeInjector) (*Binding, error) {
+	binding := &Binding{
+		Provides:   r.key.Type,
+		Requires:   []reflect.Type{r.key.Type},
+		annotation: "Ref",
+	}
+	binding.setBuild(func(in *resolving) (interface{}, error) {
+		return i.getKey(in, r.key)
+	})
+	return binding, nil
+}
+
+func (r *refType) Is(annotation Annotation) bool {
+	return reflect.TypeOf(annotation) == reflect.TypeOf(&refType{})
+}
+
+// Cached annotates a provider function so that the value it builds is reused for ttl, then rebuilt
+// when next requested. Concurrent requests for an expired value share a single build. Errors are
+// not cached.
+//
+// This suits dependencies that must be refreshed periodically, such as signed credentials.
+//
+//		injector.Bind(Cached(time.Hour, NewSignedToken))
+//
+func Cached(ttl time.Duration, v interface{}) Annotation {
+	return &cachedType{ttl, v}
+}
+
+type cachedType struct {
+	ttl time.Duration
+	v   interface{}
+}
+
+func (c *cachedType) Build(i *SafeInjector) (*Binding, error) {
+	next := Annotate(c.v)
+	if !next.Is(&providerType{}) {
+		return &Binding{}, fmt.Errorf("only providers can be cached")
+	}
+	if next.Is(&singletonType{}) {
+		return &Binding{}, fmt.Errorf("Singleton() providers can not be cached")
+	}
+	binding, err := next.Build(i)
+	if err != nil || binding.disabled {
+		return binding, err
+	}
+	cache := &expiringCache{provides: binding.Provides}
+	build := binding.builder()
+	binding.annotation = "Cached"
+	binding.setBuild(func(r *resolving) (interface{}, error) {
+		return cache.get(c.ttl, func() (interface{}, error) { return build(r) })
+	})
+	return binding, nil
+}
+
+func (c *cachedType) Is(annotation Annotation) bool {
+	return reflect.TypeOf(annotation) == reflect.TypeOf(&cachedType{}) ||
+		Annotate(c.v).Is(annotation)
+}
+
+// expiringCache caches the value built by a Cached() binding until it expires.
+type expiringCache struct {
+	provides reflect.Type
+	lock     sync.Mutex
+	cached   interface{}
+	expires  time.Time
+	inflight *singletonCall
+}
+
+// get returns the cached value if it has not expired, building it otherwise.
+func (e *expiringCache) get(ttl time.Duration, build func() (interface{}, error)) (interface{}, error) {
+	e.lock.Lock()
+	if !e.expires.IsZero() && time.Now().Before(e.expires) {
+		cached := e.cached
+		e.lock.Unlock()
+		return cached, nil
+	}
+	if call := e.inflight; call != nil {
+		e.lock.Unlock()
+		<-call.done
+		return call.value, call.err
+	}
+	call := &singletonCall{done: make(chan struct{})}
+	e.inflight = call
+	e.lock.Unlock()
+
+	defer func() {
+		e.lock.Lock()
+		if call.err == nil {
+			e.cached = call.value
+			e.expires = time.Now().Add(ttl)
+		}
+		e.inflight = nil
+		e.lock.Unlock()
+		close(call.done)
+	}()
+	// Waiters see this error if build panics.
+	call.err = fmt.Errorf("provider of cached %s panicked", e.provides)
+	call.value, call.err = build()
+	return call.value, call.err
+}
+
+// singleton caches the value built by a Singleton() binding.
+//
+// Concurrent requests for a value that is not yet built share a single in-flight build rather
+// than each building their own.
+type singleton struct {
+	owner    *SafeInjector
+	provides reflect.Type
+	lock     sync.Mutex
+	isCached bool
+	cached   interface{}
+	inflight *singletonCall
+}
+
+type singletonCall struct {
+	done  chan struct{}
+	value interface{}
+	err   error
+}
+
+// get returns the cached value, building it as part of the resolution r if necessary. Errors are
+// not cached.
+func (s *singleton) get(r *resolving, build func(r *resolving) (interface{}, error)) (interface{}, error) {
+	s.lock.Lock()
+	if s.isCached {
+		cached := s.cached
+		s.lock.Unlock()
+		s.owner.notifyCacheHit(s.provides)
+		return cached, nil
+	}
+	if call := s.inflight; call != nil {
+		s.lock.Unlock()
+		if r.buildingSingleton(s) {
+			return nil, fmt.Errorf("recursive binding %s: it was requested again while being built", s.provides)
+		}
+		<-call.done
+		return call.value, call.err
+	}
+	call := &singletonCall{done: make(chan struct{})}
+	s.inflight = call
+	s.lock.Unlock()
+
+	defer func() {
+		s.lock.Lock()
+		if call.err == nil {
+			s.cached = call.value
+			s.isCached = true
+			s.owner.markBuilt(s)
+		}
+		s.inflight = nil
+		s.lock.Unlock()
+		close(call.done)
+		if call.err == nil {
+			s.owner.log("debug", "built singleton", "type", s.provides)
+		}
+	}()
+	// Waiters see this error if build panics.
+	call.err = fmt.Errorf("provider of singleton %s panicked", s.provides)
+	in := r.push(nil, s)
+	defer in.finish()
+	call.value, call.err = build(in)
+	return call.value, call.err
+}
+
+// discard closes the cached value if it implements io.Closer and runs the cleanups registered
+// while building it, in reverse order, then resets the singleton so that it will be rebuilt.
+func (s *singleton) discard() error {
+	v, ok := s.value()
+	if !ok {
+		return nil
+	}
+	var err error
+	if closer, ok := v.(io.Closer); ok {
+		if cerr := closer.Close(); cerr != nil {
+			err = fmt.Errorf("failed to close %s: %s", s.provides, cerr)
+		}
+	}
+	cleanups := s.owner.takeCleanups(s)
+	for j := len(cleanups) - 1; j >= 0; j-- {
+		cleanups[j]()
+	}
+	s.reset()
+	return err
+}
+
+// reset discards the cached value so that it will be rebuilt on next use.
+func (s *singleton) reset() {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	if s.isCached {
+		s.owner.unmarkBuilt(s)
+	}
+	s.cached = nil
+	s.isCached = false
+}
+
+// value returns the cached value, if any.
+func (s *singleton) value() (interface{}, bool) {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	return s.cached, s.isCached
 }
 
 func (s *singletonType) Is(annotation Annotation) bool {
@@ -196,30 +549,50 @@ func (s *sequenceType) Build(i *SafeInjector) (*Binding, error) {
 	if err != nil {
 		return &Binding{}, err
 	}
+	if binding.disabled {
+		return binding, nil
+	}
 	if binding.Provides.Kind() != reflect.Slice {
 		return &Binding{}, fmt.Errorf("Sequence() must be bound to a slice not %s", binding.Provides)
 	}
-	next, ok := i.bindings[binding.Provides]
-	return &Binding{
-		Provides: binding.Provides,
-		Requires: binding.Requires,
-		Build: func() (interface{}, error) {
-			out := reflect.MakeSlice(binding.Provides, 0, 0)
-			if ok {
-				v, err := next.Build()
-				if err != nil {
-					return nil, err
-				}
-				out = reflect.AppendSlice(out, reflect.ValueOf(v))
-			}
-			v, err := binding.Build()
+	next, ok := i.bindings[Key{Type: binding.Provides, Name: binding.Name}]
+	requires, optional := binding.Requires, binding.optional
+	var dedupe func(reflect.Value) reflect.Value
+	if ok {
+		requires = append(append([]reflect.Type{}, next.Requires...), requires...)
+		optional = append(append([]reflect.Type{}, next.optional...), optional...)
+		dedupe = next.dedupe
+	}
+	merged := &Binding{
+		Provides:   binding.Provides,
+		Name:       binding.Name,
+		Requires:   requires,
+		provider:   binding.provider,
+		providerID: binding.providerID,
+		optional:   optional,
+		dedupe:     dedupe,
+		annotation: "Sequence",
+	}
+	merged.setBuild(func(r *resolving) (interface{}, error) {
+		out := reflect.MakeSlice(binding.Provides, 0, 0)
+		if ok {
+			v, err := next.buildIn(r)
 			if err != nil {
 				return nil, err
 			}
 			out = reflect.AppendSlice(out, reflect.ValueOf(v))
-			return out.Interface(), nil
-		},
-	}, nil
+		}
+		v, err := binding.buildIn(r)
+		if err != nil {
+			return nil, err
+		}
+		out = reflect.AppendSlice(out, reflect.ValueOf(v))
+		if dedupe != nil {
+			out = dedupe(out)
+		}
+		return out.Interface(), nil
+	})
+	return merged, nil
 }
 
 func (s *sequenceType) Is(annotation Annotation) bool {
@@ -227,6 +600,173 @@ func (s *sequenceType) Is(annotation Annotation) bool {
 		Annotate(s.v).Is(annotation)
 }
 
+// Group annotates a value or provider as an element of the named value group of its type. Groups
+// are named sequences, so several independent collections of the same element type can coexist.
+//
+//		injector.Bind(Group("migrations", CreateUsers))
+//		injector.Bind(Group("migrations", AddEmailIndex))
+//
+//		key := Key{Type: reflect.TypeOf([]Migration{}), Name: "migrations"}
+//		migrations := injector.GetKey(key)
+//
+// Groups may also be injected into the fields of In structs tagged with `group:"..."`.
+func Group(name string, v interface{}) Annotation {
+	return Sequence(&namedType{name, &groupElementType{v}})
+}
+
+// groupElementType provides the value of v as a single element slice.
+type groupElementType struct {
+	v interface{}
+}
+
+func (g *groupElementType) Build(i *SafeInjector) (*Binding, error) {
+	binding, err := Annotate(g.v).Build(i)
+	if err != nil || binding.disabled {
+		return binding, err
+	}
+	elem := binding.Provides
+	provides := reflect.SliceOf(elem)
+	build := binding.builder()
+	binding.Provides = provides
+	binding.setBuild(func(r *resolving) (interface{}, error) {
+		v, err := build(r)
+		if err != nil {
+			return nil, err
+		}
+		ev := reflect.Zero(elem)
+		if v != nil {
+			ev = reflect.ValueOf(v)
+		}
+		return reflect.Append(reflect.MakeSlice(provides, 0, 1), ev).Interface(), nil
+	})
+	return binding, nil
+}
+
+func (g *groupElementType) Is(annotation Annotation) bool {
+	return reflect.TypeOf(annotation) == reflect.TypeOf(&groupElementType{}) ||
+		Annotate(g.v).Is(annotation)
+}
+
+type uniqueType struct {
+	key interface{}
+	v   interface{}
+}
+
+// Unique annotates a Sequence() to indicate that duplicate elements should be removed from the
+// merged slice, keeping the first occurrence. This applies to the sequence as merged so far and to
+// all Sequence() bindings of the same type that follow it.
+//
+// Elements are compared with ==, and incomparable values with reflect.DeepEqual. Functions are
+// never duplicates, as closures created by the same function share their code and can't be told
+// apart; use UniqueBy() to dedupe them by some other key.
+//
+//		injector.Bind(Unique(Sequence([]int{1, 2})))
+//		injector.Bind(Sequence([]int{2, 3}))
+//
+//		expected := []int{1, 2, 3}
+//		actual := injector.Get(reflect.TypeOf([]int{}))
+//		assert.Equal(t, actual, expected)
+//
+func Unique(v interface{}) Annotation {
+	return &uniqueType{v: v}
+}
+
+// UniqueBy is like Unique() but compares elements by the comparable value returned by key, which
+// must be a function of the form func(<element>) <key>.
+func UniqueBy(key interface{}, v interface{}) Annotation {
+	return &uniqueType{key: key, v: v}
+}
+
+func (u *uniqueType) Build(i *SafeInjector) (*Binding, error) {
+	next := Annotate(u.v)
+	if !next.Is(&sequenceType{}) {
+		return &Binding{}, fmt.Errorf("Unique() must wrap a Sequence()")
+	}
+	binding, err := next.Build(i)
+	if err != nil {
+		return &Binding{}, err
+	}
+	if binding.disabled {
+		return binding, nil
+	}
+	identity, err := uniqueIdentity(binding.Provides.Elem(), u.key)
+	if err != nil {
+		return &Binding{}, err
+	}
+	binding.dedupe = func(in reflect.Value) reflect.Value {
+		return dedupeSlice(in, identity)
+	}
+	build := binding.builder()
+	binding.setBuild(func(r *resolving) (interface{}, error) {
+		v, err := build(r)
+		if err != nil {
+			return nil, err
+		}
+		return binding.dedupe(reflect.ValueOf(v)).Interface(), nil
+	})
+	return binding, nil
+}
+
+func (u *uniqueType) Is(annotation Annotation) bool {
+	return reflect.TypeOf(annotation) == reflect.TypeOf(&uniqueType{}) ||
+		Annotate(u.v).Is(annotation)
+}
+
+// uniqueIdentity returns a function mapping elements of type elem to a comparable identity, or nil
+// if elements must be compared with reflect.DeepEqual.
+func uniqueIdentity(elem reflect.Type, key interface{}) (func(reflect.Value) interface{}, error) {
+	if key != nil {
+		kf := reflect.ValueOf(key)
+		kt := kf.Type()
+		if kt.Kind() != reflect.Func || kt.NumIn() != 1 || kt.NumOut() != 1 ||
+			!elem.AssignableTo(kt.In(0)) || !kt.Out(0).Comparable() {
+			return nil, fmt.Errorf("UniqueBy() key must be of the form func(%s) <comparable>, not %s", elem, kt)
+		}
+		return func(v reflect.Value) interface{} {
+			return kf.Call([]reflect.Value{v})[0].Interface()
+		}, nil
+	}
+	switch {
+	case elem.Kind() == reflect.Func:
+		// Each element is its own identity.
+		return func(v reflect.Value) interface{} { return new(int) }, nil
+	case elem.Kind() == reflect.Interface:
+		return nil, nil
+	case elem.Comparable():
+		return func(v reflect.Value) interface{} { return v.Interface() }, nil
+	}
+	return nil, nil
+}
+
+// dedupeSlice returns a copy of in with duplicate elements removed.
+func dedupeSlice(in reflect.Value, identity func(reflect.Value) interface{}) reflect.Value {
+	out := reflect.MakeSlice(in.Type(), 0, in.Len())
+	seen := map[interface{}]bool{}
+	for j := 0; j < in.Len(); j++ {
+		v := in.Index(j)
+		if identity != nil {
+			id := identity(v)
+			if seen[id] {
+				continue
+			}
+			seen[id] = true
+		} else if containsDeepEqual(out, v) {
+			continue
+		}
+		out = reflect.Append(out, v)
+	}
+	return out
+}
+
+func containsDeepEqual(slice reflect.Value, v reflect.Value) bool {
+	for j := 0; j < slice.Len(); j++ {
+		if reflect.DeepEqual(slice.Index(j).Interface(), v.Interface()) {
+			return true
+		}
+	}
+	return false
+}
+
 type mappingType struct {
 	v interface{}
 }
@@ -249,39 +789,138 @@ func (m *mappingType) Build(i *SafeInjector) (*Binding, error) {
 	if err != nil {
 		return &Binding{}, err
 	}
+	if binding.disabled {
+		return binding, nil
+	}
 	if binding.Provides.Kind() != reflect.Map {
 		return &Binding{}, fmt.Errorf("Mapping() must be bound to a map not %s", binding.Provides)
 	}
 	// Previous mapping binding. Capture it and merge when requested.
-	prev, havePrev := i.bindings[binding.Provides]
-	return &Binding{
-		Provides: binding.Provides,
-		Requires: binding.Requires,
-		Build: func() (interface{}, error) {
-			out := reflect.MakeMap(binding.Provides)
-			if havePrev {
-				v, err := prev.Build()
-				if err != nil {
-					return nil, err
-				}
-				prevMap := reflect.ValueOf(v)
-				for _, k := range prevMap.MapKeys() {
-					out.SetMapIndex(k, prevMap.MapIndex(k))
-				}
-			}
-			v, err := binding.Build()
+	prev, havePrev := i.bindings[Key{Type: binding.Provides, Name: binding.Name}]
+	requires, optional := binding.Requires, binding.optional
+	if havePrev {
+		requires = append(append([]reflect.Type{}, prev.Requires...), requires...)
+		optional = append(append([]reflect.Type{}, prev.optional...), optional...)
+	}
+	merged := &Binding{
+		Provides:   binding.Provides,
+		Name:       binding.Name,
+		Requires:   requires,
+		provider:   binding.provider,
+		providerID: binding.providerID,
+		optional:   optional,
+		annotation: "Mapping",
+	}
+	merged.setBuild(func(r *resolving) (interface{}, error) {
+		out := reflect.MakeMap(binding.Provides)
+		if havePrev {
+			v, err := prev.buildIn(r)
 			if err != nil {
 				return nil, err
 			}
-			nextMap := reflect.ValueOf(v)
-			for _, k := range nextMap.MapKeys() {
-				out.SetMapIndex(k, nextMap.MapIndex(k))
+			prevMap := reflect.ValueOf(v)
+			for _, k := range prevMap.MapKeys() {
+				out.SetMapIndex(k, prevMap.MapIndex(k))
 			}
-			return out.Interface(), nil
-		},
-	}, nil
+		}
+		v, err := binding.buildIn(r)
+		if err != nil {
+			return nil, err
+		}
+		nextMap := reflect.ValueOf(v)
+		for _, k := range nextMap.MapKeys() {
+			out.SetMapIndex(k, nextMap.MapIndex(k))
+		}
+		return out.Interface(), nil
+	})
+	return merged, nil
 }
 
 func (m *mappingType) Is(annotation Annotation) bool {
 	return reflect.TypeOf(annotation) == reflect.TypeOf(&mappingType{})
 }
+
+type defaultType struct {
+	v interface{}
+}
+
+// Default annotates a binding as a default, which is only used if nothing else is bound to its type.
+//
+// A default is silently ignored if its type is already bound, and silently replaced by any
+// subsequent binding of its type. This allows library modules to provide defaults that
+// applications can override.
+//
+//		injector.Install(&LoggingModule{}) // Binds Default(NewStderrLogger)
+//		injector.Bind(NewSyslogLogger)     // Replaces the default
+//
+func Default(v interface{}) Annotation {
+	return &defaultType{v}
+}
+
+func (d *defaultType) Build(i *SafeInjector) (*Binding, error) {
+	binding, err := Annotate(d.v).Build(i)
+	if err != nil {
+		return &Binding{}, err
+	}
+	binding.isDefault = true
+	return binding, nil
+}
+
+func (d *defaultType) Is(annotation Annotation) bool {
+	return reflect.TypeOf(annotation) == reflect.TypeOf(&defaultType{}) ||
+		Annotate(d.v).Is(annotation)
+}
+
+type conditionalType struct {
+	cond func(*Injector) bool
+	v    interface{}
+}
+
+// If annotates a binding so that it is only bound if cond returns true. cond is called with the
+// injector at the time of binding, so it only observes bindings made before it.
+//
+//		injector.Bind(If(func(i *Injector) bool { return i.Has(&TLSConfig{}) }, NewTLSListener))
+//
+func If(cond func(*Injector) bool, v interface{}) Annotation {
+	return &conditionalType{cond, v}
+}
+
+func (c *conditionalType) Build(i *SafeInjector) (*Binding, error) {
+	if !c.cond(&Injector{safe: i}) {
+		return &Binding{disabled: true}, nil
+	}
+	return Annotate(c.v).Build(i)
+}
+
+func (c *conditionalType) Is(annotation Annotation) bool {
+	return reflect.TypeOf(annotation) == reflect.TypeOf(&conditionalType{}) ||
+		Annotate(c.v).Is(annotation)
+}
+
+type describeType struct {
+	description string
+	v           interface{}
+}
+
+// Describe annotates a binding with a human-readable description. Descriptions are included in
+// graph exports, Bindings(), and errors from building the binding.
+//
+//		injector.Bind(Describe("primary mongo database", Singleton(DialMongo)))
+//
+func Describe(description string, v interface{}) Annotation {
+	return &describeType{description, v}
+}
+
+func (d *describeType) Build(i *SafeInjector) (*Binding, error) {
+	binding, err := Annotate(d.v).Build(i)
+	if err != nil {
+		return &Binding{}, err
+	}
+	binding.Description = d.description
+	return binding, nil
+}
+
+func (d *describeType) Is(annotation Annotation) bool {
+	return reflect.TypeOf(annotation) == reflect.TypeOf(&describeType{}) ||
+		Annotate(d.v).Is(annotation)
+}
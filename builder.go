@@ -0,0 +1,65 @@
+package inject
+
+// A Builder configures a SafeInjector fluently, accumulating errors rather than returning them
+// from each call. Create one with SafeInjector.Builder().
+//
+//	b := injector.Builder()
+//	b.Bind(NewDB, NewLogger).BindTo((*Store)(nil), &SQLStore{}).Install(&ServerModule{})
+//	if err := b.Err(); err != nil {
+//		return err
+//	}
+type Builder struct {
+	injector *SafeInjector
+	errs     Errors
+}
+
+// Builder returns a Builder configuring this injector.
+func (s *SafeInjector) Builder() *Builder {
+	return &Builder{injector: s}
+}
+
+// Bind values as with SafeInjector.Bind(), recording any error.
+func (b *Builder) Bind(things ...interface{}) *Builder {
+	return b.record(b.injector.Bind(things...))
+}
+
+// BindTo binds impl to the interface as, as with SafeInjector.BindTo(), recording any error.
+func (b *Builder) BindTo(as interface{}, impl interface{}) *Builder {
+	return b.record(b.injector.BindTo(as, impl))
+}
+
+// Override bindings as with SafeInjector.Override(), recording any error.
+func (b *Builder) Override(things ...interface{}) *Builder {
+	return b.record(b.injector.Override(things...))
+}
+
+// Provide binds provider under name, as with SafeInjector.Provide(), recording any error.
+func (b *Builder) Provide(name string, provider interface{}) *Builder {
+	return b.record(b.injector.Provide(name, provider))
+}
+
+// Install modules as with SafeInjector.Install(), recording any error.
+func (b *Builder) Install(modules ...interface{}) *Builder {
+	return b.record(b.injector.Install(modules...))
+}
+
+func (b *Builder) record(err error) *Builder {
+	if err != nil {
+		b.errs = append(b.errs, err)
+	}
+	return b
+}
+
+// Err returns nil if every call succeeded, or an Errors value of the errors of the calls that
+// failed, in order. Calls after a failure are still made, so that all errors are reported at once.
+func (b *Builder) Err() error {
+	if len(b.errs) == 0 {
+		return nil
+	}
+	return append(Errors{}, b.errs...)
+}
+
+// Injector returns the injector being configured.
+func (b *Builder) Injector() *SafeInjector {
+	return b.injector
+}
@@ -0,0 +1,139 @@
+package inject
+
+import (
+	"reflect"
+	"sync"
+)
+
+// A ChildOption configures a child injector created with ChildWithOptions().
+type ChildOption interface {
+	applyChildOption(options *childOptions)
+}
+
+type childOptionFunc func(options *childOptions)
+
+func (c childOptionFunc) applyChildOption(options *childOptions) { c(options) }
+
+type childOptions struct {
+	shareSingletons bool
+	parents         []*SafeInjector
+}
+
+// ShareSingletons controls whether Singleton() bindings made in the child share their cached
+// values with those made by the same provider in sibling children that also share singletons.
+// Providers are the same if they are the same function, or the same method of the same module.
+// Closures created by the same function literal are the same function.
+//
+// By default each child caches its own singletons, so a provider bound as a singleton in a
+// per-request child is called once per request. Shared singletons are instead built once, by
+// whichever child first requests them, and are owned by the parent: they are closed by the
+// parent's Close() and reset by its ResetAllSingletons().
+//
+// Singletons bound in the parent are always cached by the parent, regardless of this option.
+func ShareSingletons(share bool) ChildOption {
+	return childOptionFunc(func(options *childOptions) { options.shareSingletons = share })
+}
+
+// WithParents gives the child additional parents, consulted in order for keys that neither the
+// child nor its primary parent, the injector it was created from, can resolve. This lets a
+// per-request injector overlay both an application injector and, say, a per-tenant injector.
+//
+//	request := app.ChildWithOptions(inject.WithParents(tenant))
+//
+// Each key is resolved from the first parent that can resolve it, so sequences and mappings are
+// not merged across parents. Tracers, loggers, middleware and other settings are inherited from
+// the primary parent only.
+func WithParents(parents ...*SafeInjector) ChildOption {
+	return childOptionFunc(func(options *childOptions) { options.parents = append(options.parents, parents...) })
+}
+
+// ChildWithOptions creates a child injector configured by options. See Child().
+func (s *SafeInjector) ChildWithOptions(options ...ChildOption) *SafeInjector {
+	opts := &childOptions{}
+	for _, option := range options {
+		option.applyChildOption(opts)
+	}
+	c := s.Child()
+	if opts.shareSingletons {
+		c.shared = s.sharedSingletons()
+	}
+	c.parents = opts.parents
+	return c
+}
+
+// sharedSingletons returns the singleton caches shared between children of s.
+func (s *SafeInjector) sharedSingletons() *sharedSingletons {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	if s.childSingletons == nil {
+		s.childSingletons = &sharedSingletons{owner: s, caches: map[sharedSingletonKey]*singleton{}}
+	}
+	return s.childSingletons
+}
+
+// sharedSingletons are singleton caches shared between children of owner, keyed by provider.
+type sharedSingletons struct {
+	owner  *SafeInjector
+	lock   sync.Mutex
+	caches map[sharedSingletonKey]*singleton
+}
+
+// sharedSingletonKey identifies the provider of a shared singleton: the code pointer of a function,
+// or a moduleMethod.
+type sharedSingletonKey struct {
+	provides reflect.Type
+	provider interface{}
+}
+
+// moduleMethod identifies a provider method of a module.
+type moduleMethod struct {
+	module interface{}
+	method string
+}
+
+// methodID returns the identity of the provider method name of module.
+func methodID(module reflect.Value, name string) moduleMethod {
+	// Modules that aren't comparable are identified by their type.
+	if !module.Type().Comparable() {
+		return moduleMethod{module.Type(), name}
+	}
+	return moduleMethod{module.Interface(), name}
+}
+
+func (s *sharedSingletons) get(builder *Binding) *singleton {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	key := sharedSingletonKey{builder.Provides, builder.providerID}
+	cache, ok := s.caches[key]
+	if !ok {
+		cache = &singleton{owner: s.owner, provides: builder.Provides}
+		s.caches[key] = cache
+	}
+	return cache
+}
+
+// discard discards the values of all shared singletons, returning any errors closing them.
+func (s *sharedSingletons) discard() Errors {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	errs := Errors{}
+	for _, cache := range s.caches {
+		if err := cache.discard(); err != nil {
+			errs = append(errs, err)
+		}
+	}
+	return errs
+}
+
+// newSingleton returns the cache for a Singleton() binding of builder in s.
+func (s *SafeInjector) newSingleton(builder *Binding) *singleton {
+	if s.host != nil {
+		return s.host.newSingleton(builder)
+	}
+	if s.shared != nil {
+		return s.shared.get(builder)
+	}
+	cache := &singleton{owner: s, provides: builder.Provides}
+	s.singletons = append(s.singletons, cache)
+	return cache
+}
@@ -0,0 +1,14 @@
+// Command injectcheck statically reports mistakes in github.com/alecthomas/inject wiring.
+//
+//	go vet -vettool=$(which injectcheck) ./...
+package main
+
+import (
+	"golang.org/x/tools/go/analysis/singlechecker"
+
+	"github.com/alecthomas/inject/injectcheck"
+)
+
+func main() {
+	singlechecker.Main(injectcheck.Analyzer)
+}
@@ -0,0 +1,39 @@
+// Command injectproxy generates proxies for interfaces, so that their method calls can be
+// intercepted with github.com/alecthomas/inject's Intercept().
+//
+//	//go:generate injectproxy -type Store,Mailer
+package main
+
+import (
+	"flag"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/alecthomas/inject/injectproxy"
+)
+
+func main() {
+	types := flag.String("type", "", "comma-separated names of the interfaces to generate proxies for")
+	output := flag.String("output", "inject_proxy.go", "file to write, in the package directory")
+	flag.Parse()
+	dir := "."
+	if flag.NArg() > 0 {
+		dir = flag.Arg(0)
+	}
+	if *types == "" {
+		fmt.Fprintln(os.Stderr, "injectproxy: -type is required")
+		os.Exit(2)
+	}
+	source, err := injectproxy.Generate(dir, *output, strings.Split(*types, ",")...)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "injectproxy: %s\n", err)
+		os.Exit(1)
+	}
+	if err := ioutil.WriteFile(filepath.Join(dir, *output), source, 0644); err != nil {
+		fmt.Fprintf(os.Stderr, "injectproxy: %s\n", err)
+		os.Exit(1)
+	}
+}
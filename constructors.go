@@ -0,0 +1,43 @@
+package inject
+
+import (
+	"fmt"
+	"reflect"
+)
+
+// InstallConstructors binds each of constructors, typically the NewX functions of a package, as a
+// Singleton() provider. Constructors may return an error as their second value.
+//
+// Unlike Install(), every constructor is bound even if some fail, in which case an Errors value
+// describing each failure is returned.
+//
+//	injector.InstallConstructors(NewDB, NewUserStore, NewServer)
+func (s *SafeInjector) InstallConstructors(constructors ...interface{}) error {
+	return s.strictly(func() error {
+		errs := Errors{}
+		for _, constructor := range constructors {
+			if err := s.bindConstructor(constructor); err != nil {
+				errs = append(errs, err)
+			}
+		}
+		if len(errs) > 0 {
+			return errs
+		}
+		return nil
+	})
+}
+
+func (s *SafeInjector) bindConstructor(constructor interface{}) error {
+	f := reflect.ValueOf(constructor)
+	if f.Kind() != reflect.Func {
+		return fmt.Errorf("constructor must be a function but got %T", constructor)
+	}
+	name := funcName(f)
+	if err := checkProviderSignature(f.Type()); err != nil {
+		return fmt.Errorf("constructor %s: %s", name, err)
+	}
+	if _, err := s.bind(Singleton(constructor)); err != nil {
+		return fmt.Errorf("constructor %s: %s", name, err)
+	}
+	return nil
+}
@@ -0,0 +1,56 @@
+package inject
+
+import (
+	"context"
+)
+
+// SetContext sets the context.Context injected by this injector and its children, unless they set
+// their own. The root injector injects context.Background() by default.
+//
+// context.Context is a pseudo-binding: it is resolved each time it is injected, so providers see
+// the context of the injector or call they are built for. As a context is usually scoped to a
+// request or operation, Singleton() providers can not provide one, and ValidateScopes() reports
+// those that require one.
+//
+//	request := app.Child()
+//	request.SetContext(r.Context())
+func (s *SafeInjector) SetContext(ctx context.Context) {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	s.ctx = ctx
+	s.generation++
+}
+
+// CallContext calls f as with CallWith(), injecting ctx into its context.Context parameters.
+//
+// Dependencies built for f are injected with the context of the injector that binds them. Use a
+// child injector with SetContext() for ctx to reach them too.
+func (s *SafeInjector) CallContext(ctx context.Context, f interface{}, extras ...interface{}) ([]interface{}, error) {
+	return s.tracedCall(f, append([]interface{}{ctx}, extras...), nil)
+}
+
+// contextBinding returns the context.Context pseudo-binding of s, or nil if s has no context of
+// its own and defers to its parent.
+func (s *SafeInjector) contextBinding() *Binding {
+	if s.host != nil {
+		return s.host.contextBinding()
+	}
+	s.lock.Lock()
+	ctx := s.ctx
+	s.lock.Unlock()
+	if ctx == nil && s.parent != nil {
+		return nil
+	}
+	return &Binding{
+		Provides:   contextType,
+		annotation: "Context",
+		Build: func() (interface{}, error) {
+			s.lock.Lock()
+			defer s.lock.Unlock()
+			if s.ctx == nil {
+				return context.Background(), nil
+			}
+			return s.ctx, nil
+		},
+	}
+}
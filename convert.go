@@ -0,0 +1,57 @@
+package inject
+
+import (
+	"reflect"
+)
+
+// convertibleTo returns true if values of type from can be converted to type to, either directly
+// or, for slices, arrays and maps, element by element.
+func convertibleTo(from, to reflect.Type) bool {
+	if from.ConvertibleTo(to) {
+		return true
+	}
+	switch {
+	case from.Kind() == reflect.Slice && to.Kind() == reflect.Slice:
+		return convertibleTo(from.Elem(), to.Elem())
+	case from.Kind() == reflect.Array && to.Kind() == reflect.Array:
+		return from.Len() == to.Len() && convertibleTo(from.Elem(), to.Elem())
+	case from.Kind() == reflect.Map && to.Kind() == reflect.Map:
+		return convertibleTo(from.Key(), to.Key()) && convertibleTo(from.Elem(), to.Elem())
+	}
+	return false
+}
+
+// convert v to type t, which must satisfy convertibleTo(). Collections are copied.
+func convert(v reflect.Value, t reflect.Type) reflect.Value {
+	if v.Type().ConvertibleTo(t) {
+		return v.Convert(t)
+	}
+	switch t.Kind() {
+	case reflect.Slice:
+		if v.IsNil() {
+			return reflect.Zero(t)
+		}
+		out := reflect.MakeSlice(t, v.Len(), v.Len())
+		for j := 0; j < v.Len(); j++ {
+			out.Index(j).Set(convert(v.Index(j), t.Elem()))
+		}
+		return out
+	case reflect.Array:
+		out := reflect.New(t).Elem()
+		for j := 0; j < v.Len(); j++ {
+			out.Index(j).Set(convert(v.Index(j), t.Elem()))
+		}
+		return out
+	case reflect.Map:
+		if v.IsNil() {
+			return reflect.Zero(t)
+		}
+		out := reflect.MakeMapWithSize(t, v.Len())
+		iter := v.MapRange()
+		for iter.Next() {
+			out.SetMapIndex(convert(iter.Key(), t.Key()), convert(iter.Value(), t.Elem()))
+		}
+		return out
+	}
+	panic("unreachable")
+}
@@ -0,0 +1,107 @@
+package inject
+
+import (
+	"fmt"
+	"reflect"
+)
+
+type copyType struct {
+	v interface{}
+}
+
+func (c *copyType) String() string {
+	return fmt.Sprintf("%v", c.v)
+}
+
+func (c *copyType) Build(*SafeInjector) (*Binding, error) {
+	if c.v == nil {
+		return &Binding{}, fmt.Errorf("can not bind a copy of nil")
+	}
+	stored := deepCopy(reflect.ValueOf(c.v), map[uintptr]reflect.Value{})
+	return &Binding{
+		Provides: stored.Type(),
+		Build: func() (interface{}, error) {
+			return deepCopy(stored, map[uintptr]reflect.Value{}).Interface(), nil
+		},
+		annotation: "BindCopy",
+	}, nil
+}
+
+func (c *copyType) Is(annotation Annotation) bool {
+	return reflect.TypeOf(annotation) == reflect.TypeOf(&copyType{})
+}
+
+// BindCopy binds a deep copy of the literal v, and provides a fresh deep copy of it each time it
+// is injected, so that consumers can not modify shared state such as maps or slices of
+// configuration, nor observe each other's modifications. Bind options such as Name() may be given.
+//
+// Pointers, slices, maps, arrays, interfaces and the exported fields of structs are copied
+// recursively, preserving aliasing within the value. Unexported struct fields, functions and
+// channels are shared with the original.
+func (s *SafeInjector) BindCopy(v interface{}, options ...BindOption) error {
+	things := []interface{}{&copyType{v}}
+	for _, option := range options {
+		things = append(things, option)
+	}
+	return s.Bind(things...)
+}
+
+// deepCopy returns a deep copy of v, as described by BindCopy(). copied maps the addresses of
+// pointers already copied to their copies.
+func deepCopy(v reflect.Value, copied map[uintptr]reflect.Value) reflect.Value {
+	switch v.Kind() {
+	case reflect.Ptr:
+		if v.IsNil() {
+			return v
+		}
+		if out, ok := copied[v.Pointer()]; ok {
+			return out
+		}
+		out := reflect.New(v.Type().Elem())
+		copied[v.Pointer()] = out
+		out.Elem().Set(deepCopy(v.Elem(), copied))
+		return out
+	case reflect.Interface:
+		if v.IsNil() {
+			return v
+		}
+		out := reflect.New(v.Type()).Elem()
+		out.Set(deepCopy(v.Elem(), copied))
+		return out
+	case reflect.Slice:
+		if v.IsNil() {
+			return v
+		}
+		out := reflect.MakeSlice(v.Type(), v.Len(), v.Len())
+		for j := 0; j < v.Len(); j++ {
+			out.Index(j).Set(deepCopy(v.Index(j), copied))
+		}
+		return out
+	case reflect.Array:
+		out := reflect.New(v.Type()).Elem()
+		for j := 0; j < v.Len(); j++ {
+			out.Index(j).Set(deepCopy(v.Index(j), copied))
+		}
+		return out
+	case reflect.Map:
+		if v.IsNil() {
+			return v
+		}
+		out := reflect.MakeMapWithSize(v.Type(), v.Len())
+		iter := v.MapRange()
+		for iter.Next() {
+			out.SetMapIndex(deepCopy(iter.Key(), copied), deepCopy(iter.Value(), copied))
+		}
+		return out
+	case reflect.Struct:
+		out := reflect.New(v.Type()).Elem()
+		out.Set(v)
+		for j := 0; j < v.NumField(); j++ {
+			if out.Field(j).CanSet() {
+				out.Field(j).Set(deepCopy(v.Field(j), copied))
+			}
+		}
+		return out
+	}
+	return v
+}
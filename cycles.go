@@ -0,0 +1,202 @@
+package inject
+
+import (
+	"fmt"
+	"reflect"
+	"strings"
+	"sync/atomic"
+)
+
+// addAcyclicBinding adds a binding, failing without modifying the injector if it would introduce a
+// dependency cycle.
+func (s *SafeInjector) addAcyclicBinding(key Key, binding *Binding) error {
+	prev, hadPrev := s.bindings[key]
+	s.addBinding(key, binding)
+	if cycle := s.findCycle(key); cycle != nil {
+		if hadPrev {
+			s.addBinding(key, prev)
+		} else {
+			s.unbind(key)
+		}
+		return fmt.Errorf("recursive binding %s", formatCycle(cycle))
+	}
+	return nil
+}
+
+type cycleNode struct {
+	owner *SafeInjector
+	key   Key
+}
+
+// findCycle returns the types forming a dependency cycle reachable from key, or nil.
+//
+// Cycles are found by walking the declared requirements of each binding when it is bound, so that
+// requests don't pay for the walk. Requirements only known at runtime are checked by each request,
+// see resolving.
+func (s *SafeInjector) findCycle(key Key) []reflect.Type {
+	return s.walkCycle(key, nil, map[cycleNode]bool{})
+}
+
+func (s *SafeInjector) walkCycle(key Key, path []cycleNode, done map[cycleNode]bool) []reflect.Type {
+	node := cycleNode{s, key}
+	for j, p := range path {
+		if p == node {
+			cycle := []reflect.Type{}
+			for _, n := range path[j:] {
+				cycle = append(cycle, n.key.Type)
+			}
+			return append(cycle, key.Type)
+		}
+	}
+	if done[node] {
+		return nil
+	}
+	binding, owner, err := s.resolveOwner(key)
+	// Unbound types are reported elsewhere.
+	if err != nil {
+		done[node] = true
+		return nil
+	}
+	path = append(path[:len(path):len(path)], node)
+	for _, req := range binding.Requires {
+		if cycle := owner.walkCycle(Key{Type: req}, path, done); cycle != nil {
+			return cycle
+		}
+	}
+	done[node] = true
+	return nil
+}
+
+func formatCycle(cycle []reflect.Type) string {
+	parts := make([]string, len(cycle))
+	for j, t := range cycle {
+		parts[j] = t.String()
+	}
+	return strings.Join(parts, " -> ")
+}
+
+// resolving is the state of a value being built, passed down explicitly through its build so that
+// the values it requires are built as part of the same resolution.
+//
+// Static cycle detection can't see a provider that requests its own type at runtime, such as
+// through an injected *SafeInjector, so each request checks that its binding isn't already being
+// built by the resolution it is part of, rather than recursing or waiting on itself forever.
+type resolving struct {
+	parent    *resolving
+	binding   *Binding   // Binding being built, if any.
+	singleton *singleton // Singleton being built, if any.
+	done      int32      // Set once the build has finished, see finish().
+}
+
+// push returns the state of building binding or cache as part of r.
+func (r *resolving) push(binding *Binding, cache *singleton) *resolving {
+	return &resolving{parent: r, binding: binding, singleton: cache}
+}
+
+// requester returns the build that requires r, if r is not nil. Values such as injectors and
+// factories that request more values after being built do so as part of it.
+func (r *resolving) requester() *resolving {
+	if r == nil {
+		return nil
+	}
+	return r.parent
+}
+
+// finish marks the build of r as finished, so that requests from the views of injectors injected
+// into it start new resolutions.
+func (r *resolving) finish() {
+	atomic.StoreInt32(&r.done, 1)
+}
+
+// live returns r, or nil if its build has finished.
+func (r *resolving) live() *resolving {
+	if r == nil || atomic.LoadInt32(&r.done) != 0 {
+		return nil
+	}
+	return r
+}
+
+// building returns true if binding is being built as part of r.
+func (r *resolving) building(binding *Binding) bool {
+	for ; r != nil; r = r.parent {
+		if r.binding == binding {
+			return true
+		}
+	}
+	return false
+}
+
+// singletonOwner returns the innermost singleton being built as part of r, if any, which owns the
+// resources acquired for it.
+func (r *resolving) singletonOwner() *singleton {
+	for ; r != nil; r = r.parent {
+		if r.singleton != nil {
+			return r.singleton
+		}
+	}
+	return nil
+}
+
+// buildingSingleton returns true if cache is being built as part of r.
+func (r *resolving) buildingSingleton(cache *singleton) bool {
+	for ; r != nil; r = r.parent {
+		if r.singleton == cache {
+			return true
+		}
+	}
+	return false
+}
+
+// view returns a view of s whose requests are part of the resolution r, or s itself if r is nil.
+// Views are injected in place of the injector, so that providers requesting values at runtime
+// can't wait on their own builds.
+func (s *SafeInjector) view(r *resolving) *SafeInjector {
+	if r == nil {
+		return s
+	}
+	return &SafeInjector{injectorState: s.injectorState, requester: r}
+}
+
+// bindViews makes the bindings of types, which provide s or a wrapper of it, provide view(r) to the
+// resolution r requesting them instead.
+func (s *SafeInjector) bindViews(view func(r *resolving) interface{}, types ...reflect.Type) {
+	for _, t := range types {
+		s.bindings[Key{Type: t}].setBuild(func(r *resolving) (interface{}, error) {
+			return view(r.requester()), nil
+		})
+	}
+}
+
+// setBuild sets the Build function of b to build, which is also passed the state of the resolution
+// that b is built in when it is built by an injector.
+func (b *Binding) setBuild(build func(r *resolving) (interface{}, error)) {
+	b.build = build
+	b.Build = detached(build)
+}
+
+// detached returns a Build function that calls build as a new resolution.
+func detached(build func(r *resolving) (interface{}, error)) func() (interface{}, error) {
+	return func() (interface{}, error) { return build(nil) }
+}
+
+// detachedCode is the code of the functions returned by detached(), which are all closures of the
+// same function.
+var detachedCode = reflect.ValueOf(detached(nil)).Pointer()
+
+// buildIn builds the value of b as part of the resolution r.
+//
+// A binding whose Build function has been replaced since setBuild(), such as by an annotation
+// outside this package, is built as a new resolution.
+func (b *Binding) buildIn(r *resolving) (interface{}, error) {
+	if b.build != nil && reflect.ValueOf(b.Build).Pointer() == detachedCode {
+		return b.build(r)
+	}
+	return b.Build()
+}
+
+// builder returns a function building the value of b as it is now, unaffected by later changes to
+// b, for annotations that wrap the binding they annotate.
+func (b *Binding) builder() func(r *resolving) (interface{}, error) {
+	current := *b
+	return current.buildIn
+}
@@ -0,0 +1,81 @@
+package inject
+
+import (
+	"fmt"
+	"reflect"
+)
+
+// Deprecated annotates a binding as deprecated. Building its value records a warning including
+// message, retrievable with Warnings(), so that consumers of the binding can be found and migrated
+// before it is removed.
+//
+//	injector.Bind(Deprecated("use NewHTTPClient", NewLegacyClient))
+func Deprecated(message string, v interface{}) Annotation {
+	return &deprecatedType{message, v}
+}
+
+type deprecatedType struct {
+	message string
+	v       interface{}
+}
+
+func (d *deprecatedType) Build(i *SafeInjector) (*Binding, error) {
+	binding, err := Annotate(d.v).Build(i)
+	if err != nil {
+		return &Binding{}, err
+	}
+	binding.deprecated = d.message
+	return binding, nil
+}
+
+func (d *deprecatedType) Is(annotation Annotation) bool {
+	return reflect.TypeOf(annotation) == reflect.TypeOf(&deprecatedType{}) ||
+		Annotate(d.v).Is(annotation)
+}
+
+// A DeprecatedModule is a module whose bindings are all deprecated. Deprecated returns the
+// message included in warnings. See Deprecated().
+type DeprecatedModule interface {
+	Deprecated() string
+}
+
+// deprecateModule marks the bindings of the installed module of type t as deprecated.
+func (s *SafeInjector) deprecateModule(t reflect.Type, message string) {
+	for _, key := range s.moduleKeys[t] {
+		if binding, ok := s.bindings[key]; ok && binding.deprecated == "" {
+			binding.deprecated = fmt.Sprintf("module %s: %s", t, message)
+		}
+	}
+}
+
+// warnDeprecated records a warning if binding, bound to key in this injector, is deprecated. Each
+// key is only warned about once.
+func (s *SafeInjector) warnDeprecated(key Key, binding *Binding) {
+	if binding.deprecated == "" {
+		return
+	}
+	s.lock.Lock()
+	if s.warned[key] {
+		s.lock.Unlock()
+		return
+	}
+	if s.warned == nil {
+		s.warned = map[Key]bool{}
+	}
+	s.warned[key] = true
+	warning := fmt.Sprintf("%s is deprecated: %s", key, binding.deprecated)
+	if binding.provider != "" {
+		warning = fmt.Sprintf("%s (provided by %s) is deprecated: %s", key, binding.provider, binding.deprecated)
+	}
+	s.warnings = append(s.warnings, warning)
+	s.lock.Unlock()
+	s.log("warn", warning)
+}
+
+// Warnings returns the warnings recorded when building deprecated bindings of this injector, in
+// the order they were first built.
+func (s *SafeInjector) Warnings() []string {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	return append([]string{}, s.warnings...)
+}
@@ -0,0 +1,124 @@
+package inject
+
+import (
+	"fmt"
+	"reflect"
+	"strings"
+)
+
+// ChangeKind is how a binding differs between two injectors. See Diff().
+type ChangeKind string
+
+const (
+	// BindingAdded bindings are only in the second injector.
+	BindingAdded ChangeKind = "added"
+	// BindingRemoved bindings are only in the first injector.
+	BindingRemoved ChangeKind = "removed"
+	// BindingChanged bindings are in both injectors, but differ.
+	BindingChanged ChangeKind = "changed"
+)
+
+// A BindingChange is a difference in the binding of a key between two injectors.
+type BindingChange struct {
+	Kind ChangeKind
+	Key  Key
+	// Before is the binding in the first injector, or nil if it was added.
+	Before *BindingInfo
+	// After is the binding in the second injector, or nil if it was removed.
+	After *BindingInfo
+	// Changes describe each attribute of a changed binding that differs, eg.
+	// "annotation: Provider -> Singleton".
+	Changes []string
+}
+
+func (c BindingChange) String() string {
+	switch c.Kind {
+	case BindingAdded:
+		return fmt.Sprintf("added %s (%s)", c.Key, describeOrigin(c.After))
+	case BindingRemoved:
+		return fmt.Sprintf("removed %s (%s)", c.Key, describeOrigin(c.Before))
+	default:
+		return fmt.Sprintf("changed %s: %s", c.Key, strings.Join(c.Changes, "; "))
+	}
+}
+
+// Diff returns the differences between the bindings visible to a and those visible to b, as
+// described by their Graph(): the keys bound only in b, those bound only in a, and those bound in
+// both whose annotation, scope, provider, module, requirements, description or deprecation
+// differ. Bound values themselves are not compared.
+//
+// Removed and changed bindings are returned in the order of a's graph, followed by added bindings
+// in the order of b's. This is useful for verifying that injectors built for different
+// deployment targets differ only as intended.
+func Diff(a, b *SafeInjector) []BindingChange {
+	before, after := a.Graph(), b.Graph()
+	out := []BindingChange{}
+	for _, info := range before.Nodes() {
+		other, ok := after.Node(info.Key)
+		if !ok {
+			out = append(out, BindingChange{Kind: BindingRemoved, Key: info.Key, Before: info})
+			continue
+		}
+		if changes := diffBindingInfo(info, other); len(changes) > 0 {
+			out = append(out, BindingChange{Kind: BindingChanged, Key: info.Key, Before: info, After: other, Changes: changes})
+		}
+	}
+	for _, info := range after.Nodes() {
+		if _, ok := before.Node(info.Key); !ok {
+			out = append(out, BindingChange{Kind: BindingAdded, Key: info.Key, After: info})
+		}
+	}
+	return out
+}
+
+// diffBindingInfo describes each attribute that differs between a and b.
+func diffBindingInfo(a, b *BindingInfo) []string {
+	changes := []string{}
+	diff := func(attribute, before, after string) {
+		if before != after {
+			changes = append(changes, fmt.Sprintf("%s: %s -> %s", attribute, orNone(before), orNone(after)))
+		}
+	}
+	diff("annotation", a.Annotation, b.Annotation)
+	diff("scope", string(a.Scope), string(b.Scope))
+	diff("provider", a.Provider, b.Provider)
+	diff("module", typeName(a.Module), typeName(b.Module))
+	diff("requires", typeNames(a.Requires), typeNames(b.Requires))
+	diff("description", a.Description, b.Description)
+	diff("deprecated", a.Deprecated, b.Deprecated)
+	return changes
+}
+
+// describeOrigin summarises the annotation and origin of info.
+func describeOrigin(info *BindingInfo) string {
+	parts := []string{orNone(info.Annotation)}
+	if info.Provider != "" {
+		parts = append(parts, "provided by "+info.Provider)
+	}
+	if info.Module != nil {
+		parts = append(parts, "from module "+info.Module.String())
+	}
+	return strings.Join(parts, ", ")
+}
+
+func typeName(t reflect.Type) string {
+	if t == nil {
+		return ""
+	}
+	return t.String()
+}
+
+func typeNames(types []reflect.Type) string {
+	names := make([]string, len(types))
+	for j, t := range types {
+		names[j] = t.String()
+	}
+	return strings.Join(names, ", ")
+}
+
+func orNone(s string) string {
+	if s == "" {
+		return "none"
+	}
+	return s
+}
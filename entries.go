@@ -0,0 +1,100 @@
+package inject
+
+import (
+	"fmt"
+	"reflect"
+	"sort"
+)
+
+// MapEntry is embedded in a struct with exported Key and Value fields to request the entries of a
+// merged Mapping() in a deterministic order. Injecting a slice of such structs injects the map
+// whose key and value types match the Key and Value fields, as entries sorted by key.
+//
+// Strings and numbers are sorted by their natural order, other keys by their formatted value.
+//
+//	type Route struct {
+//		inject.MapEntry
+//
+//		Key   string
+//		Value http.Handler
+//	}
+//
+//	injector.Call(func(routes []Route) { ... })
+type MapEntry struct{}
+
+var mapEntryType = reflect.TypeOf(MapEntry{})
+
+// entryFields returns the indices of the Key and Value fields of t, if it is a MapEntry struct.
+func entryFields(t reflect.Type) (key int, value int, ok bool) {
+	if !embeds(t, mapEntryType) {
+		return 0, 0, false
+	}
+	kf, kok := t.FieldByName("Key")
+	vf, vok := t.FieldByName("Value")
+	if !kok || !vok || len(kf.Index) != 1 || len(vf.Index) != 1 || kf.PkgPath != "" || vf.PkgPath != "" {
+		return 0, 0, false
+	}
+	return kf.Index[0], vf.Index[0], true
+}
+
+// resolveEntries returns a binding that builds the slice of MapEntry structs t from the map bound
+// to the same name as key, or nil if t is not such a slice or the map is not bound.
+func (s *SafeInjector) resolveEntries(key Key) *Binding {
+	t := key.Type
+	if t.Kind() != reflect.Slice {
+		return nil
+	}
+	et := t.Elem()
+	kj, vj, ok := entryFields(et)
+	if !ok {
+		return nil
+	}
+	mapKey := Key{Type: reflect.MapOf(et.Field(kj).Type, et.Field(vj).Type), Name: key.Name}
+	if !s.canResolve(mapKey) {
+		return nil
+	}
+	binding := &Binding{
+		Provides: t,
+		Name:     key.Name,
+		Requires: []reflect.Type{mapKey.Type},
+	}
+	binding.setBuild(func(r *resolving) (interface{}, error) {
+		v, err := s.getKey(r, mapKey)
+		if err != nil {
+			return nil, err
+		}
+		m := reflect.ValueOf(v)
+		keys := m.MapKeys()
+		sortValues(keys)
+		out := reflect.MakeSlice(t, 0, len(keys))
+		for _, k := range keys {
+			entry := reflect.New(et).Elem()
+			entry.Field(kj).Set(k)
+			entry.Field(vj).Set(m.MapIndex(k))
+			out = reflect.Append(out, entry)
+		}
+		return out.Interface(), nil
+	})
+	return binding
+}
+
+// sortValues sorts values, which must all be of the same type, in ascending order.
+func sortValues(values []reflect.Value) {
+	if len(values) == 0 {
+		return
+	}
+	var less func(a, b reflect.Value) bool
+	switch values[0].Kind() {
+	case reflect.String:
+		less = func(a, b reflect.Value) bool { return a.String() < b.String() }
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		less = func(a, b reflect.Value) bool { return a.Int() < b.Int() }
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64, reflect.Uintptr:
+		less = func(a, b reflect.Value) bool { return a.Uint() < b.Uint() }
+	case reflect.Float32, reflect.Float64:
+		less = func(a, b reflect.Value) bool { return a.Float() < b.Float() }
+	default:
+		less = func(a, b reflect.Value) bool { return fmt.Sprint(a.Interface()) < fmt.Sprint(b.Interface()) }
+	}
+	sort.SliceStable(values, func(a, b int) bool { return less(values[a], values[b]) })
+}
@@ -0,0 +1,166 @@
+package inject
+
+import (
+	"fmt"
+	"os"
+	"reflect"
+	"strconv"
+	"sync"
+	"time"
+)
+
+// EnvModule returns a module that populates each config, a pointer to a struct, from environment
+// variables and binds it.
+//
+// Fields are populated from the variable named by their `env` tag, falling back to the `default`
+// tag if the variable is not set. Fields that are also tagged with `name` are bound individually
+// as named values, for retrieval with GetKey() or CallKeyed().
+//
+//	type Config struct {
+//		Port  int    `env:"PORT" default:"8080" name:"port"`
+//		Debug bool   `env:"DEBUG"`
+//		DSN   string `env:"DATABASE_URL"`
+//	}
+//
+//	injector.Install(inject.EnvModule(&Config{}))
+//
+// Supported field types are strings, bools, integers, floats and time.Duration.
+func EnvModule(configs ...interface{}) []interface{} {
+	out := []interface{}{}
+	for _, config := range configs {
+		env := &envType{config: config}
+		out = append(out, env)
+		t := reflect.TypeOf(config)
+		if t.Kind() != reflect.Ptr || t.Elem().Kind() != reflect.Struct {
+			continue
+		}
+		for j := 0; j < t.Elem().NumField(); j++ {
+			field := t.Elem().Field(j)
+			if name, ok := field.Tag.Lookup("name"); ok && field.Tag.Get("env") != "" {
+				out = append(out, &namedType{name, &envFieldType{env, j}})
+			}
+		}
+	}
+	return out
+}
+
+// envType binds a config struct populated from the environment.
+type envType struct {
+	config interface{}
+	once   sync.Once
+	err    error
+}
+
+func (e *envType) populate() error {
+	e.once.Do(func() { e.err = populateFromEnv(e.config) })
+	return e.err
+}
+
+func (e *envType) Build(*SafeInjector) (*Binding, error) {
+	if err := e.populate(); err != nil {
+		return &Binding{}, err
+	}
+	return &Binding{
+		Provides: reflect.TypeOf(e.config),
+		Build:    func() (interface{}, error) { return e.config, nil },
+	}, nil
+}
+
+func (e *envType) Is(annotation Annotation) bool {
+	return reflect.TypeOf(annotation) == reflect.TypeOf(&envType{})
+}
+
+// envFieldType binds a single field of a config struct populated from the environment.
+type envFieldType struct {
+	env   *envType
+	field int
+}
+
+func (e *envFieldType) Build(*SafeInjector) (*Binding, error) {
+	if err := e.env.populate(); err != nil {
+		return &Binding{}, err
+	}
+	v := reflect.ValueOf(e.env.config).Elem().Field(e.field)
+	return &Binding{
+		Provides: v.Type(),
+		Build:    func() (interface{}, error) { return v.Interface(), nil },
+	}, nil
+}
+
+func (e *envFieldType) Is(annotation Annotation) bool {
+	return reflect.TypeOf(annotation) == reflect.TypeOf(&envFieldType{})
+}
+
+var durationType = reflect.TypeOf(time.Duration(0))
+
+// populateFromEnv sets the `env` tagged fields of the struct pointed to by config.
+func populateFromEnv(config interface{}) error {
+	v := reflect.ValueOf(config)
+	if v.Kind() != reflect.Ptr || v.Elem().Kind() != reflect.Struct {
+		return fmt.Errorf("EnvModule() requires a pointer to a struct but got %T", config)
+	}
+	v = v.Elem()
+	t := v.Type()
+	for j := 0; j < t.NumField(); j++ {
+		field := t.Field(j)
+		env := field.Tag.Get("env")
+		if env == "" {
+			continue
+		}
+		if field.PkgPath != "" {
+			return fmt.Errorf("environment field %s.%s must be exported", t, field.Name)
+		}
+		value, ok := os.LookupEnv(env)
+		if !ok {
+			if value, ok = field.Tag.Lookup("default"); !ok {
+				continue
+			}
+		}
+		if err := setFromString(v.Field(j), value); err != nil {
+			return fmt.Errorf("invalid value %q for $%s (%s.%s): %s", value, env, t, field.Name, err)
+		}
+	}
+	return nil
+}
+
+func setFromString(v reflect.Value, value string) error {
+	if v.Type() == durationType {
+		d, err := time.ParseDuration(value)
+		if err != nil {
+			return err
+		}
+		v.SetInt(int64(d))
+		return nil
+	}
+	switch v.Kind() {
+	case reflect.String:
+		v.SetString(value)
+	case reflect.Bool:
+		b, err := strconv.ParseBool(value)
+		if err != nil {
+			return err
+		}
+		v.SetBool(b)
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		n, err := strconv.ParseInt(value, 0, v.Type().Bits())
+		if err != nil {
+			return err
+		}
+		v.SetInt(n)
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		n, err := strconv.ParseUint(value, 0, v.Type().Bits())
+		if err != nil {
+			return err
+		}
+		v.SetUint(n)
+	case reflect.Float32, reflect.Float64:
+		n, err := strconv.ParseFloat(value, v.Type().Bits())
+		if err != nil {
+			return err
+		}
+		v.SetFloat(n)
+	default:
+		return fmt.Errorf("unsupported field type %s", v.Type())
+	}
+	return nil
+}
@@ -0,0 +1,99 @@
+package inject
+
+import (
+	"fmt"
+	"reflect"
+	"strings"
+)
+
+// Errors is a collection of errors.
+type Errors []error
+
+func (e Errors) Error() string {
+	parts := make([]string, len(e))
+	for j, err := range e {
+		parts[j] = err.Error()
+	}
+	return strings.Join(parts, "; ")
+}
+
+// ProviderError is an error binding a provider method of a module. Install() returns an Errors
+// value with a ProviderError for every provider method of a module that could not be bound.
+type ProviderError struct {
+	Module reflect.Type
+	Method string
+	Err    error
+}
+
+func (p *ProviderError) Error() string {
+	return fmt.Sprintf("module %s: method %s: %s", p.Module, p.Method, p.Err)
+}
+
+// PanicError is returned by SafeInjector when building a binding, or installing a module, panics.
+type PanicError struct {
+	Key      Key
+	Provider string       // Name of the provider function, if known.
+	Module   reflect.Type // Module being installed, if known.
+	Value    interface{}
+	Stack    []byte
+}
+
+func (p *PanicError) Error() string {
+	if p.Key.Type == nil {
+		if p.Module != nil {
+			return fmt.Sprintf("installing module %s panicked: %v", p.Module, p.Value)
+		}
+		return fmt.Sprintf("installing modules panicked: %v", p.Value)
+	}
+	if p.Provider != "" {
+		return fmt.Sprintf("provider %s of %s panicked: %v", p.Provider, p.Key, p.Value)
+	}
+	return fmt.Sprintf("building %s panicked: %v", p.Key, p.Value)
+}
+
+// BuildError is returned by SafeInjector when a provider returns an error while building a value,
+// however deeply nested the build. It identifies the provider that failed, and the chain of keys
+// that were being built when it did. Use errors.As() to retrieve it.
+type BuildError struct {
+	Key      Key          // Key the failing provider was building.
+	Provider string       // Name of the provider function.
+	Module   reflect.Type // Module that bound the provider, if any.
+	// Description of the failing binding, if any. See Describe().
+	Description string
+	// Chain of keys being built, from the outermost request to Key. Empty until the error has
+	// been returned by the injector.
+	Chain []Key
+	Err   error // Returned by the provider.
+}
+
+func (b *BuildError) Error() string {
+	chain := make([]string, len(b.Chain))
+	for j, key := range b.Chain {
+		chain[j] = key.String()
+	}
+	if len(chain) == 0 {
+		chain = append(chain, b.Key.String())
+	}
+	if b.Description != "" {
+		chain[len(chain)-1] += fmt.Sprintf(" (%s)", b.Description)
+	}
+	return fmt.Sprintf("building %s: provider %s failed: %s", strings.Join(chain, " -> "), b.Provider, b.Err)
+}
+
+// Unwrap returns the error returned by the provider.
+func (b *BuildError) Unwrap() error {
+	return b.Err
+}
+
+// requiredBy returns a copy of b with key, being built by binding in owner, prepended to its chain.
+// The innermost key is the one that was requested from the failing provider's binding.
+func (b *BuildError) requiredBy(key Key, owner *SafeInjector, binding *Binding) *BuildError {
+	out := *b
+	if len(out.Chain) == 0 {
+		out.Key = key
+		out.Module, _ = owner.moduleOf(key)
+		out.Description = binding.Description
+	}
+	out.Chain = append([]Key{key}, b.Chain...)
+	return &out
+}
@@ -0,0 +1,67 @@
+package inject
+
+import (
+	"reflect"
+)
+
+// resolveFactories returns a binding for a factory map of type map[string]func() (T, error), whose
+// entries build the binding of T with each name bound in this injector or its ancestors, or nil if
+// key is not such a map or there are no named bindings of T.
+//
+// Only the values that are requested from the map are built, so that one of many expensive
+// implementations can be chosen at runtime:
+//
+//	injector.Bind(inject.Singleton(NewS3Storage), inject.Name("s3"))
+//	injector.Bind(inject.Singleton(NewGCSStorage), inject.Name("gcs"))
+//	injector.Call(func(storage map[string]func() (Storage, error)) error {
+//		s, err := storage[config.Backend]()
+//		...
+//	})
+func (s *SafeInjector) resolveFactories(key Key) *Binding {
+	t := key.Type
+	if key.Name != "" || t.Kind() != reflect.Map || t.Key().Kind() != reflect.String {
+		return nil
+	}
+	ft := t.Elem()
+	if ft.Kind() != reflect.Func || ft.NumIn() != 0 || ft.NumOut() != 2 || ft.Out(1) != errorType {
+		return nil
+	}
+	et := ft.Out(0)
+	names := []string{}
+	seen := map[string]bool{}
+	for p := s; p != nil; p = p.parent {
+		for _, bk := range p.bindingOrder {
+			if bk.Type == et && bk.Name != "" && !seen[bk.Name] {
+				names = append(names, bk.Name)
+				seen[bk.Name] = true
+			}
+		}
+	}
+	if len(names) == 0 {
+		return nil
+	}
+	binding := &Binding{Provides: t}
+	binding.setBuild(func(r *resolving) (interface{}, error) {
+		// Factories called by the build they are injected into are part of its resolution.
+		requester := r.requester()
+		out := reflect.MakeMapWithSize(t, len(names))
+		for _, name := range names {
+			named := Key{Type: et, Name: name}
+			factory := reflect.MakeFunc(ft, func([]reflect.Value) []reflect.Value {
+				v, err := s.getKey(requester.live(), named)
+				rv := reflect.Zero(et)
+				if err == nil && v != nil {
+					rv = reflect.ValueOf(v)
+				}
+				rerr := reflect.Zero(errorType)
+				if err != nil {
+					rerr = reflect.ValueOf(&err).Elem()
+				}
+				return []reflect.Value{rv, rerr}
+			})
+			out.SetMapIndex(reflect.ValueOf(name).Convert(t.Key()), factory)
+		}
+		return out.Interface(), nil
+	})
+	return binding
+}
@@ -0,0 +1,58 @@
+package inject
+
+import (
+	"fmt"
+)
+
+// A Resolver is a fallback consulted for keys that are not bound to an injector or any of its
+// parents. It returns a binding for key, or nil if it can not resolve key either.
+//
+// The binding must provide key.Type. It is not cached, so a Resolver that is consulted repeatedly
+// for the same key should return the same binding, or wrap an expensive Build in a cache.
+type Resolver func(key Key) (*Binding, error)
+
+// AddResolver appends resolver to the fallback resolvers of this injector. When a key can not be
+// resolved from bindings, the resolvers of the injector's ancestors are consulted first, then
+// those of the injector itself, each in the order they were added. The first binding returned is
+// used.
+//
+// An injector has no fallback resolvers by default.
+//
+//	// Resolve anything unbound as its zero value.
+//	injector.AddResolver(func(key inject.Key) (*inject.Binding, error) {
+//		return &inject.Binding{
+//			Provides: key.Type,
+//			Build:    func() (interface{}, error) { return reflect.Zero(key.Type).Interface(), nil },
+//		}, nil
+//	})
+func (s *SafeInjector) AddResolver(resolver Resolver) {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	s.resolvers = append(s.resolvers, resolver)
+	s.generation++
+}
+
+// fallback consults the resolvers of this injector for key, which could not otherwise be resolved
+// because of unbound.
+func (s *SafeInjector) fallback(key Key, unbound error) (*Binding, *SafeInjector, error) {
+	if binding := s.adaptPointer(key); binding != nil {
+		return binding, s, nil
+	}
+	s.lock.Lock()
+	resolvers := s.resolvers
+	s.lock.Unlock()
+	for _, resolver := range resolvers {
+		binding, err := resolver(key)
+		if err != nil {
+			return &Binding{}, nil, fmt.Errorf("resolving %s: %s", key, err)
+		}
+		if binding == nil {
+			continue
+		}
+		if binding.Provides != key.Type {
+			return &Binding{}, nil, fmt.Errorf("resolver returned a binding of %s for %s", binding.Provides, key)
+		}
+		return binding, s, nil
+	}
+	return &Binding{}, nil, unbound
+}
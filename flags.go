@@ -0,0 +1,65 @@
+package inject
+
+import (
+	"fmt"
+	"reflect"
+)
+
+type flaggedType struct {
+	as      interface{}
+	enabled func() bool
+	on, off interface{}
+}
+
+func (f *flaggedType) Build(i *SafeInjector) (*Binding, error) {
+	t := keyOf(f.as).Type
+	bindings := []*Binding{}
+	requires := []reflect.Type{}
+	for _, v := range []interface{}{f.on, f.off} {
+		if v == nil {
+			return &Binding{}, fmt.Errorf("flagged implementations of %s can not be nil", t)
+		}
+		binding, err := Annotate(v).Build(i)
+		if err != nil {
+			return &Binding{}, err
+		}
+		if !binding.Provides.AssignableTo(t) {
+			return &Binding{}, fmt.Errorf("flagged implementation %s is not assignable to %s", binding.Provides, t)
+		}
+		bindings = append(bindings, binding)
+		requires = append(requires, binding.Requires...)
+	}
+	on, off := bindings[0], bindings[1]
+	binding := &Binding{
+		Provides:   t,
+		Requires:   requires,
+		annotation: "Flagged",
+	}
+	binding.setBuild(func(r *resolving) (interface{}, error) {
+		if f.enabled() {
+			return on.buildIn(r)
+		}
+		return off.buildIn(r)
+	})
+	return binding, nil
+}
+
+func (f *flaggedType) Is(annotation Annotation) bool {
+	return reflect.TypeOf(annotation) == reflect.TypeOf(&flaggedType{})
+}
+
+// BindFlagged binds as, specified as with BindTo(), to one of two implementations chosen by a
+// feature flag each time it is injected: on if enabled returns true, otherwise off. Either
+// implementation may be a value, a provider or an annotated provider such as Singleton(), and is
+// only built when chosen. Bind options such as Name() may be given.
+//
+// enabled is called from every goroutine that injects as, so must be safe for concurrent use.
+//
+//	injector.BindFlagged((*Search)(nil), flags.Enabled("new-search"), NewElasticSearch, NewSQLSearch)
+func (s *SafeInjector) BindFlagged(as interface{}, enabled func() bool, on, off interface{}, options ...BindOption) error {
+	things := []interface{}{&flaggedType{as: as, enabled: enabled, on: on, off: off}}
+	for _, option := range options {
+		things = append(things, option)
+	}
+	return s.Bind(things...)
+}
@@ -0,0 +1,165 @@
+//go:build go1.18
+// +build go1.18
+
+package inject
+
+import (
+	"fmt"
+	"reflect"
+	"strings"
+)
+
+// BindAs binds impl to the interface I. Unlike BindTo(), the compiler checks that impl implements
+// I.
+//
+//	inject.BindAs[fmt.Stringer](injector, &MyStringer{})
+func BindAs[I any](binder SafeBinder, impl I) error {
+	t := reflect.TypeOf((*I)(nil))
+	if t.Elem().Kind() != reflect.Interface {
+		return fmt.Errorf("BindAs() requires an interface type but got %s", t.Elem())
+	}
+	return binder.BindTo((*I)(nil), impl)
+}
+
+// Provide binds a provider of T with no dependencies.
+func Provide[T any](binder SafeBinder, provider func() (T, error)) error {
+	return binder.Bind(provider)
+}
+
+// ProvideWith binds a provider of T with a single dependency of type P. Providers with multiple
+// dependencies can accept them as a struct embedding In.
+//
+//	type ServerParams struct {
+//		inject.In
+//		DB  *sql.DB
+//		Log *log.Logger
+//	}
+//
+//	inject.ProvideWith(injector, func(p ServerParams) (*Server, error) { ... })
+func ProvideWith[T, P any](binder SafeBinder, provider func(P) (T, error)) error {
+	return binder.Bind(provider)
+}
+
+// GetAs acquires a value of type T from the injector.
+func GetAs[T any](injector *SafeInjector) (T, error) {
+	var zero T
+	v, err := injector.GetKey(Key{Type: reflect.TypeOf((*T)(nil)).Elem()})
+	if err != nil || v == nil {
+		return zero, err
+	}
+	return v.(T), nil
+}
+
+// Call1 calls f, injecting any arguments, and returns its result. f must return (R1[, error]).
+//
+//	srv, err := inject.Call1[*Server](injector, func(db *sql.DB) (*Server, error) { ... })
+func Call1[R1 any](injector *SafeInjector, f interface{}) (R1, error) {
+	var r1 R1
+	out, err := callTyped(injector, "Call1", f, reflect.TypeOf((*R1)(nil)).Elem())
+	if err != nil {
+		return r1, err
+	}
+	r1, _ = out[0].(R1)
+	return r1, nil
+}
+
+// Call2 calls f, injecting any arguments, and returns its results. f must return
+// (R1, R2[, error]).
+func Call2[R1, R2 any](injector *SafeInjector, f interface{}) (R1, R2, error) {
+	var (
+		r1 R1
+		r2 R2
+	)
+	out, err := callTyped(injector, "Call2", f, reflect.TypeOf((*R1)(nil)).Elem(), reflect.TypeOf((*R2)(nil)).Elem())
+	if err != nil {
+		return r1, r2, err
+	}
+	r1, _ = out[0].(R1)
+	r2, _ = out[1].(R2)
+	return r1, r2, nil
+}
+
+// callTyped calls f after checking that it returns values assignable to results, optionally
+// followed by an error.
+func callTyped(injector *SafeInjector, name string, f interface{}, results ...reflect.Type) ([]interface{}, error) {
+	ft := reflect.TypeOf(f)
+	if ft == nil || ft.Kind() != reflect.Func {
+		return nil, fmt.Errorf("%s() requires a function but got %T", name, f)
+	}
+	n := ft.NumOut()
+	if n > 0 && ft.Out(n-1) == errorType {
+		n--
+	}
+	ok := n == len(results)
+	for j := 0; ok && j < n; j++ {
+		ok = ft.Out(j).AssignableTo(results[j])
+	}
+	if !ok {
+		return nil, fmt.Errorf("%s() requires a function returning (%s[, error]) but got %s", name, typeList(results), ft)
+	}
+	return injector.Call(f)
+}
+
+// typeList returns types separated by commas.
+func typeList(types []reflect.Type) string {
+	names := make([]string, len(types))
+	for j, t := range types {
+		names[j] = t.String()
+	}
+	return strings.Join(names, ", ")
+}
+
+// GetAll builds every bound value whose type implements the interface T. See
+// SafeInjector.GetAll().
+func GetAll[T any](injector *SafeInjector) ([]T, error) {
+	values, err := injector.GetAll((*T)(nil))
+	if err != nil {
+		return nil, err
+	}
+	out := make([]T, 0, len(values))
+	for _, v := range values {
+		t, _ := v.(T)
+		out = append(out, t)
+	}
+	return out, nil
+}
+
+// GetGroup acquires the elements of the named value group of T from the injector. See Group().
+func GetGroup[T any](injector *SafeInjector, name string) ([]T, error) {
+	v, err := injector.GetKey(Key{Type: reflect.TypeOf([]T{}), Name: name})
+	if err != nil || v == nil {
+		return nil, err
+	}
+	return v.([]T), nil
+}
+
+// BindInstantiations binds providers that are instantiations of the same generic provider function.
+// Go can not instantiate generic functions at runtime, so each instantiation that is required must
+// be listed.
+//
+//	func NewRepo[T any](db *sql.DB) *Repo[T] { ... }
+//
+//	inject.BindInstantiations(injector, NewRepo[User], NewRepo[Order])
+func BindInstantiations(binder SafeBinder, providers ...interface{}) error {
+	generic := ""
+	for _, provider := range providers {
+		f := reflect.ValueOf(provider)
+		if f.Kind() != reflect.Func {
+			return fmt.Errorf("BindInstantiations() requires functions but got %T", provider)
+		}
+		name := funcName(f)
+		if !strings.HasSuffix(name, "[...]") {
+			return fmt.Errorf("BindInstantiations() requires instantiations of a generic function but got %s", name)
+		}
+		if generic != "" && name != generic {
+			return fmt.Errorf("BindInstantiations() requires instantiations of a single generic function but got %s and %s", generic, name)
+		}
+		generic = name
+	}
+	for _, provider := range providers {
+		if err := binder.Bind(provider); err != nil {
+			return err
+		}
+	}
+	return nil
+}
@@ -0,0 +1,106 @@
+//go:build go1.18
+// +build go1.18
+
+package inject
+
+import (
+	"fmt"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+type testGenericParams struct {
+	In
+	N int
+	S string
+}
+
+func TestGenericHelpers(t *testing.T) {
+	i := SafeNew()
+	require.NoError(t, BindAs[fmt.Stringer](i, stringer("hello")))
+	require.Error(t, BindAs[int](i, 1))
+	require.NoError(t, Provide(i, func() (int, error) { return 2, nil }))
+	require.NoError(t, ProvideWith(i, func(s fmt.Stringer) (string, error) { return s.String(), nil }))
+	require.NoError(t, ProvideWith(i, func(p testGenericParams) (float64, error) {
+		return float64(p.N) + float64(len(p.S)), nil
+	}))
+
+	f, err := GetAs[float64](i)
+	require.NoError(t, err)
+	require.Equal(t, 7.0, f)
+	s, err := GetAs[fmt.Stringer](i)
+	require.NoError(t, err)
+	require.Equal(t, "hello", s.String())
+	_, err = GetAs[bool](i)
+	require.Error(t, err)
+}
+
+func TestCallTyped(t *testing.T) {
+	i := SafeNew()
+	require.NoError(t, i.Bind(2))
+	n, err := Call1[int](i, func(n int) int { return n * 2 })
+	require.NoError(t, err)
+	require.Equal(t, 4, n)
+	s, err := Call1[fmt.Stringer](i, func(n int) (stringer, error) { return stringer(fmt.Sprint(n)), nil })
+	require.NoError(t, err)
+	require.Equal(t, "2", s.String())
+	_, err = Call1[int](i, func(n int) (int, error) { return 0, fmt.Errorf("failed") })
+	require.EqualError(t, err, "failed")
+	_, err = Call1[string](i, func(n int) int { return n })
+	require.EqualError(t, err, "Call1() requires a function returning (string[, error]) but got func(int) int")
+	_, err = Call1[int](i, 1)
+	require.EqualError(t, err, "Call1() requires a function but got int")
+
+	a, b, err := Call2[int, string](i, func(n int) (int, string, error) { return n, "two", nil })
+	require.NoError(t, err)
+	require.Equal(t, 2, a)
+	require.Equal(t, "two", b)
+	_, _, err = Call2[int, string](i, func(s string) (int, string) { return 0, s })
+	require.Error(t, err)
+}
+
+func TestGetAllGeneric(t *testing.T) {
+	i := SafeNew()
+	require.NoError(t, i.Bind(stringer("a"), Name("b")))
+	require.NoError(t, i.BindTo((*fmt.Stringer)(nil), stringer("c")))
+	values, err := GetAll[fmt.Stringer](i)
+	require.NoError(t, err)
+	require.Equal(t, []fmt.Stringer{stringer("a"), stringer("c")}, values)
+	_, err = GetAll[int](i)
+	require.Error(t, err)
+}
+
+func TestGetGroup(t *testing.T) {
+	i := SafeNew()
+	require.NoError(t, i.Bind(Group("up", "create"), Group("up", func() string { return "index" })))
+	require.NoError(t, i.Bind(Group("down", "drop")))
+	up, err := GetGroup[string](i, "up")
+	require.NoError(t, err)
+	require.Equal(t, []string{"create", "index"}, up)
+	_, err = GetGroup[string](i, "sideways")
+	require.Error(t, err)
+}
+
+type testRepo[T any] struct{ name string }
+
+func newTestRepo[T any](name string) *testRepo[T] { return &testRepo[T]{name} }
+
+func newTestOtherRepo[T any]() *testRepo[T] { return &testRepo[T]{} }
+
+func TestBindInstantiations(t *testing.T) {
+	i := SafeNew()
+	require.NoError(t, i.Bind("db"))
+	require.NoError(t, BindInstantiations(i, newTestRepo[int], newTestRepo[string]))
+	repo, err := GetAs[*testRepo[string]](i)
+	require.NoError(t, err)
+	require.Equal(t, "db", repo.name)
+	_, err = GetAs[*testRepo[bool]](i)
+	require.EqualError(t, err, "unbound type *inject.testRepo[bool] (only *inject.testRepo[int], *inject.testRepo[string] are bound; "+
+		"generic providers must be bound for each instantiation, see BindInstantiations())")
+
+	require.EqualError(t, BindInstantiations(i, newTestRepo[bool], newTestOtherRepo[bool]),
+		"BindInstantiations() requires instantiations of a single generic function but got "+
+			"github.com/alecthomas/inject.newTestRepo[...] and github.com/alecthomas/inject.newTestOtherRepo[...]")
+	require.Error(t, BindInstantiations(i, func() int { return 1 }))
+}
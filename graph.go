@@ -0,0 +1,304 @@
+package inject
+
+import (
+	"fmt"
+	"io"
+	"reflect"
+	"strings"
+)
+
+// WriteMermaid writes a Mermaid flowchart of the bindings in this injector and their dependencies
+// to w.
+//
+// The output can be embedded directly in Markdown within a ```mermaid fenced block.
+func (s *SafeInjector) WriteMermaid(w io.Writer) error {
+	ids := map[Key]string{}
+	lines := []string{"flowchart LR"}
+	node := func(key Key, description string) string {
+		if id, ok := ids[key]; ok {
+			return id
+		}
+		id := fmt.Sprintf("n%d", len(ids))
+		ids[key] = id
+		label := mermaidEscape(key.String())
+		if description != "" {
+			label += "<br/>" + mermaidEscape(description)
+		}
+		lines = append(lines, fmt.Sprintf("  %s[\"%s\"]", id, label))
+		return id
+	}
+	edges := []string{}
+	for _, binding := range s.Bindings() {
+		from := node(Key{Type: binding.Provides, Name: binding.Name}, binding.Description)
+		for _, req := range binding.Requires {
+			edges = append(edges, fmt.Sprintf("  %s --> %s", from, node(Key{Type: req}, "")))
+		}
+	}
+	lines = append(lines, edges...)
+	_, err := io.WriteString(w, strings.Join(lines, "\n")+"\n")
+	return err
+}
+
+func mermaidEscape(s string) string {
+	return strings.NewReplacer(`"`, "#quot;", "<", "#lt;", ">", "#gt;").Replace(s)
+}
+
+// TopoOrder returns the bindings of this injector, excluding those of any parent, sorted so that
+// each binding comes after the bindings it requires. Bindings that do not depend on each other
+// remain in the order they were first bound.
+//
+// An error is returned if the bindings contain a dependency cycle.
+func (s *SafeInjector) TopoOrder() ([]*Binding, error) {
+	const (
+		visiting = 1
+		visited  = 2
+	)
+	out := []*Binding{}
+	state := map[Key]int{}
+	var visit func(key Key, path []reflect.Type) error
+	visit = func(key Key, path []reflect.Type) error {
+		path = append(path[:len(path):len(path)], key.Type)
+		switch state[key] {
+		case visited:
+			return nil
+		case visiting:
+			for j, t := range path {
+				if t == key.Type {
+					return fmt.Errorf("recursive binding %s", formatCycle(path[j:]))
+				}
+			}
+		}
+		binding, owner, err := s.resolveOwner(key)
+		// Unbound requirements and those of parents don't affect the order of this injector.
+		if err != nil || owner != s {
+			return nil
+		}
+		state[key] = visiting
+		for _, req := range binding.Requires {
+			if err := visit(Key{Type: req}, path); err != nil {
+				return err
+			}
+		}
+		state[key] = visited
+		if s.bindings[key] == binding {
+			out = append(out, binding)
+		}
+		return nil
+	}
+	for _, key := range s.bindingOrder {
+		if err := visit(key, nil); err != nil {
+			return nil, err
+		}
+	}
+	return out, nil
+}
+
+// BindingScope is how long a value built by a binding is reused.
+type BindingScope string
+
+const (
+	// ScopeTransient bindings build a new value each time they are injected.
+	ScopeTransient BindingScope = "transient"
+	// ScopeSingleton bindings build their value once. See Singleton().
+	ScopeSingleton BindingScope = "singleton"
+	// ScopeCached bindings reuse their value until it expires. See Cached().
+	ScopeCached BindingScope = "cached"
+	// ScopeLiteral bindings provide a value bound as-is.
+	ScopeLiteral BindingScope = "literal"
+	// ScopePooled bindings reuse values returned to a pool. See Pooled().
+	ScopePooled BindingScope = "pooled"
+)
+
+// BindingInfo describes a binding, for tooling that inspects the dependency graph of an injector.
+type BindingInfo struct {
+	Key Key
+	// Requires are the types required by the binding, including optional requirements.
+	Requires []reflect.Type
+	// Optional are the requirements that may be left unbound, such as variadic parameters.
+	Optional []reflect.Type
+	// Annotation that created the binding, such as "Singleton", if known.
+	Annotation string
+	Scope      BindingScope
+	// Provider is the name of the function providing the value, if any.
+	Provider string
+	// Module that bound the binding, or nil if it was bound directly.
+	Module reflect.Type
+	// Injector that the binding belongs to.
+	Injector    *SafeInjector
+	Description string
+	// Deprecated is the deprecation message of the binding, if any. See Deprecated().
+	Deprecated string
+	// Binding described.
+	Binding *Binding
+}
+
+// A Graph of the bindings visible to an injector, with an edge from each binding to each binding
+// that its requirements resolve to. Create one with SafeInjector.Graph().
+//
+// The graph is a snapshot: it does not change when bindings are added.
+type Graph struct {
+	nodes      []*BindingInfo
+	byKey      map[Key]*BindingInfo
+	deps       map[Key][]Key
+	dependents map[Key][]Key
+}
+
+// Graph returns the dependency graph of the bindings of this injector and its ancestors. Where a
+// key is bound by both, only the nearest binding is included. Nodes are ordered ancestors first,
+// then in the order they were bound.
+//
+// Requirements that resolve implicitly, such as interfaces satisfied by a bound implementation,
+// have an edge to the binding they resolve to. Requirements resolved by merging bindings, such as
+// sequences, and unbound requirements have no edges.
+func (s *SafeInjector) Graph() *Graph {
+	g := &Graph{byKey: map[Key]*BindingInfo{}, deps: map[Key][]Key{}, dependents: map[Key][]Key{}}
+	chain := []*SafeInjector{}
+	for p := s; p != nil; p = p.parent {
+		chain = append([]*SafeInjector{p}, chain...)
+	}
+	byBinding := map[*Binding]Key{}
+	for _, p := range chain {
+		for _, key := range p.bindingOrder {
+			// Keys also bound by a descendant are described by its binding.
+			binding, owner, err := s.resolveOwner(key)
+			if err != nil || owner != p {
+				continue
+			}
+			info := bindingInfo(key, binding, owner)
+			g.nodes = append(g.nodes, info)
+			g.byKey[key] = info
+			byBinding[binding] = key
+		}
+	}
+	for _, info := range g.nodes {
+		for _, req := range info.Requires {
+			binding, _, err := s.resolveOwner(Key{Type: req})
+			if err != nil {
+				continue
+			}
+			if dep, ok := byBinding[binding]; ok && !containsKey(g.deps[info.Key], dep) {
+				g.deps[info.Key] = append(g.deps[info.Key], dep)
+				g.dependents[dep] = append(g.dependents[dep], info.Key)
+			}
+		}
+	}
+	return g
+}
+
+// bindingInfo describes binding, bound to key in owner.
+func bindingInfo(key Key, binding *Binding, owner *SafeInjector) *BindingInfo {
+	scope := ScopeTransient
+	switch {
+	case binding.cache != nil:
+		scope = ScopeSingleton
+	case binding.annotation == "Cached":
+		scope = ScopeCached
+	case binding.annotation == "Literal":
+		scope = ScopeLiteral
+	case binding.release != nil:
+		scope = ScopePooled
+	}
+	module, _ := owner.moduleOf(key)
+	return &BindingInfo{
+		Key:         key,
+		Requires:    append([]reflect.Type{}, binding.Requires...),
+		Optional:    append([]reflect.Type{}, binding.optional...),
+		Annotation:  binding.annotation,
+		Scope:       scope,
+		Provider:    binding.provider,
+		Module:      module,
+		Injector:    owner,
+		Description: binding.Description,
+		Deprecated:  binding.deprecated,
+		Binding:     binding,
+	}
+}
+
+// Nodes returns every binding in the graph.
+func (g *Graph) Nodes() []*BindingInfo {
+	return append([]*BindingInfo{}, g.nodes...)
+}
+
+// Node returns the binding of key, if it is in the graph.
+func (g *Graph) Node(key Key) (*BindingInfo, bool) {
+	info, ok := g.byKey[key]
+	return info, ok
+}
+
+// Dependencies returns the bindings that the requirements of key resolve to.
+func (g *Graph) Dependencies(key Key) []*BindingInfo {
+	return g.infos(g.deps[key])
+}
+
+// Dependents returns the bindings with a requirement that resolves to key.
+func (g *Graph) Dependents(key Key) []*BindingInfo {
+	return g.infos(g.dependents[key])
+}
+
+func (g *Graph) infos(keys []Key) []*BindingInfo {
+	out := make([]*BindingInfo, 0, len(keys))
+	for _, key := range keys {
+		out = append(out, g.byKey[key])
+	}
+	return out
+}
+
+// WalkDepthFirst calls visit for the binding of from, then depth first for each binding it
+// transitively depends on, with each binding's distance from from. Each binding is visited once.
+// Returning false from visit skips the dependencies of that binding.
+func (g *Graph) WalkDepthFirst(from Key, visit func(info *BindingInfo, depth int) bool) {
+	seen := map[Key]bool{}
+	var walk func(key Key, depth int)
+	walk = func(key Key, depth int) {
+		info, ok := g.byKey[key]
+		if !ok || seen[key] {
+			return
+		}
+		seen[key] = true
+		if !visit(info, depth) {
+			return
+		}
+		for _, dep := range g.deps[key] {
+			walk(dep, depth+1)
+		}
+	}
+	walk(from, 0)
+}
+
+// WalkBreadthFirst calls visit for the binding of from, then breadth first for each binding it
+// transitively depends on, with each binding's distance from from. Each binding is visited once.
+// Returning false from visit skips the dependencies of that binding.
+func (g *Graph) WalkBreadthFirst(from Key, visit func(info *BindingInfo, depth int) bool) {
+	if _, ok := g.byKey[from]; !ok {
+		return
+	}
+	type item struct {
+		key   Key
+		depth int
+	}
+	seen := map[Key]bool{from: true}
+	queue := []item{{from, 0}}
+	for len(queue) > 0 {
+		next := queue[0]
+		queue = queue[1:]
+		if !visit(g.byKey[next.key], next.depth) {
+			continue
+		}
+		for _, dep := range g.deps[next.key] {
+			if !seen[dep] {
+				seen[dep] = true
+				queue = append(queue, item{dep, next.depth + 1})
+			}
+		}
+	}
+}
+
+// Reachable returns true if the binding of from transitively depends on the binding of to.
+func (g *Graph) Reachable(from, to Key) bool {
+	found := false
+	g.WalkDepthFirst(from, func(info *BindingInfo, depth int) bool {
+		found = found || (depth > 0 && info.Key == to)
+		return !found
+	})
+	return found
+}
@@ -1,7 +1,11 @@
 package inject
 
 import (
+	"context"
+	"fmt"
+	"io"
 	"reflect"
+	"time"
 )
 
 var errorType = reflect.TypeOf((*error)(nil)).Elem()
@@ -9,8 +13,77 @@ var errorType = reflect.TypeOf((*error)(nil)).Elem()
 // Binding represents a function that resolves to a value given a set of input values.
 type Binding struct {
 	Provides reflect.Type
+	Name     string // Optional name distinguishing this binding from others of the same type.
 	Requires []reflect.Type
 	Build    func() (interface{}, error)
+	// Description is an optional human-readable description of the binding. See Describe().
+	Description string
+
+	provider   string                                  // Name of the provider function, if any.
+	providerID interface{}                             // Identity of the provider function, if any. See sharedSingletonKey.
+	dedupe     func(reflect.Value) reflect.Value       // Deduplicates merged Sequence() values. See Unique().
+	isDefault  bool                                    // Replaced by any later binding. See Default().
+	disabled   bool                                    // Not bound because of a failed If() condition.
+	optional   []reflect.Type                          // Requirements that may be unbound, ie. variadic parameters.
+	annotation string                                  // Annotation that created the binding, for Explain().
+	cache      *singleton                              // Cache of a Singleton() binding.
+	deprecated string                                  // Deprecation message. See Deprecated().
+	release    func(v interface{})                     // Returns a value to its pool. See Pooled().
+	build      func(r *resolving) (interface{}, error) // Build as part of a resolution. See setBuild().
+}
+
+// Provider returns the name of the function providing the binding's value, if any.
+func (b *Binding) Provider() string {
+	return b.provider
+}
+
+// Annotation returns the name of the annotation that created the binding, such as "Singleton", if
+// known.
+func (b *Binding) Annotation() string {
+	return b.annotation
+}
+
+// Cached returns true if the binding is a singleton whose value has already been built.
+func (b *Binding) Cached() bool {
+	if b.cache == nil {
+		return false
+	}
+	_, ok := b.cache.value()
+	return ok
+}
+
+// Wrap replaces the Build function of the binding with wrap, which is passed the function it
+// replaces. Annotations that wrap another binding should use Wrap() rather than assigning Build,
+// so that the value is still built as part of the request for it, and a binding requested again
+// while it is being built is reported rather than waited on.
+func (b *Binding) Wrap(wrap func(build func() (interface{}, error)) (interface{}, error)) {
+	build := b.builder()
+	b.setBuild(func(r *resolving) (interface{}, error) {
+		return wrap(func() (interface{}, error) { return build(r) })
+	})
+}
+
+// isOptional returns true if requirement t of the binding may be left unbound.
+func (b *Binding) isOptional(t reflect.Type) bool {
+	for _, o := range b.optional {
+		if o == t {
+			return true
+		}
+	}
+	return false
+}
+
+// Key identifies a binding by its type and optional name.
+type Key struct {
+	Type reflect.Type
+	Name string
+}
+
+func (k Key) String() string {
+	if k.Name == "" {
+		return k.Type.String()
+	}
+	return fmt.Sprintf("%s(%q)", k.Type, k.Name)
 }
 
 // Binder is an interface allowing bindings to be added.
@@ -18,6 +91,12 @@ type Binder interface {
 	Bind(things ...interface{}) Binder
 	BindTo(to interface{}, impl interface{}) Binder
 	Install(module ...interface{}) Binder
+	// Has returns true if a value of type t can be resolved.
+	Has(t interface{}) bool
+	// Override binds values, replacing any existing bindings of the same keys.
+	Override(things ...interface{}) Binder
+	// Provide binds a provider under name.
+	Provide(name string, provider interface{}) Binder
 }
 
 var _ Binder = &Injector{}
@@ -27,6 +106,19 @@ type Module interface {
 	Configure(binder Binder) error
 }
 
+// A SafeModule is a Module whose Configure() method receives a SafeBinder, which returns binding
+// errors rather than panicking, so that the module can handle them. It is checked before Module.
+type SafeModule interface {
+	Configure(binder SafeBinder) error
+}
+
+// A ValidatingModule is a module that checks its own fields, such as configuration, before it is
+// installed. Validate() is called by Install() once the module's fields have been injected, before
+// Configure() and before any of its providers are bound.
+type ValidatingModule interface {
+	Validate() error
+}
+
 // SafeInjector is an IoC container.
 type Injector struct {
 	safe *SafeInjector
@@ -41,22 +133,41 @@ func New() *Injector {
 	i := &Injector{safe: SafeNew()}
 	i.Bind(i)
 	i.BindTo((*Binder)(nil), i)
+	i.safe.history = nil
+	i.safe.bindViews(func(r *resolving) interface{} {
+		if r == nil {
+			return i
+		}
+		return &Injector{safe: i.safe.view(r)}
+	}, reflect.TypeOf(i), reflect.TypeOf((*Binder)(nil)).Elem())
 	return i
 }
 
 // Install a module. A module is a struct whose methods are providers. This is useful for grouping
-// configuration data together with providers.
+// configuration data together with providers. Modules passed by value are copied, so that their
+// methods with pointer receivers are also used.
+//
+// Small modules may instead be plain provider functions, which are bound as singletons, or
+// bundles of providers created with Providers(). Annotations may also be installed directly.
 //
-// Duplicate modules are allowed as long as all fields are identical or either the existing module,
-// or the new module, are zero value.
+// Duplicate modules are merged field by field: non-zero exported fields of the new module fill zero
+// fields of the existing module, and it is an error for a field to be non-zero but different in
+// both. Unexported fields are merged as a whole, in the same way.
 //
 // Any method starting with "Provide" will be bound as a Provider. If the method name contains
 // "Multi" it will not be a singleton provider. If the method name contains "Sequence" it must
 // return a slice which is merged with slices of the same type. If the method name contains
 // "Mapping" it must return a mapping which will be merged with mappings of the same type. Mapping
-// and Sequence can not be used simultaneously.
+// and Sequence can not be used simultaneously. The prefix and markers can be changed by passing
+// WithPrefix() and WithMarkers() options alongside the modules. The providers of a PrivateModule
+// can only be injected into its other providers, unless their type is exposed.
+//
+// Every provider method of a module is bound before any errors are reported, as an Errors value
+// with a ProviderError for each method that could not be bound.
 //
-// Arguments to provider methods are injected.
+// Arguments to provider methods are injected. Exported module fields tagged with `inject:""` are
+// also injected from existing bindings before the module is configured, allowing a module to
+// consume the outputs of previously installed modules.
 //
 // For example, the following method will be called only once:
 //
@@ -74,8 +185,66 @@ func (i *Injector) Install(modules ...interface{}) Binder {
 	return i
 }
 
+// InstallConstructors binds each of constructors as a Singleton() provider. Panics with every
+// failure if any fail. See SafeInjector.InstallConstructors() for details.
+func (i *Injector) InstallConstructors(constructors ...interface{}) Binder {
+	if err := i.safe.InstallConstructors(constructors...); err != nil {
+		panic(err)
+	}
+	return i
+}
+
+// InstallRegistered installs the modules registered with Register() that are selected by filter,
+// or every registered module if filter is nil. Panics on error.
+func (i *Injector) InstallRegistered(filter ModuleFilter) Binder {
+	if err := i.safe.InstallRegistered(filter); err != nil {
+		panic(err)
+	}
+	return i
+}
+
+// Has returns true if a value of type t can be resolved by the injector, including from bindings
+// of parent injectors.
+//
+// This allows modules to conditionally bind values from Configure():
+//
+//		if !binder.Has((*Logger)(nil)) {
+//			binder.Bind(NewLogger)
+//		}
+func (i *Injector) Has(t interface{}) bool {
+	return i.safe.Has(t)
+}
+
+// Override binds values as with Bind(), replacing any existing bindings of the same keys.
+func (i *Injector) Override(things ...interface{}) Binder {
+	if err := i.safe.Override(things...); err != nil {
+		panic(err)
+	}
+	return i
+}
+
+// Provide binds provider under name, for retrieval with GetKey() or CallKeyed().
+func (i *Injector) Provide(name string, provider interface{}) Binder {
+	if err := i.safe.Provide(name, provider); err != nil {
+		panic(err)
+	}
+	return i
+}
+
+// Providers bundles provider functions (or annotations) into a module that can be installed with
+// Install(). Plain functions in the bundle are bound as singletons.
+//
+//		injector.Install(inject.Providers(NewDB, NewLogger))
+//
+func Providers(providers ...interface{}) []interface{} {
+	return providers
+}
+
 // Bind binds a value to the injector. Panics on error. See the README
 // (https://github.com/alecthomas/inject/blob/master/README.md) for more details.
+//
+// BindOptions, such as Name() and As(), may be passed alongside the values to modify how they are
+// bound.
 func (i *Injector) Bind(things ...interface{}) Binder {
 	if err := i.safe.Bind(things...); err != nil {
 		panic(err)
@@ -83,6 +252,24 @@ func (i *Injector) Bind(things ...interface{}) Binder {
 	return i
 }
 
+// BindCopy binds a deep copy of the literal v, providing a fresh deep copy each time it is
+// injected. Panics on error. See SafeInjector.BindCopy() for details.
+func (i *Injector) BindCopy(v interface{}, options ...BindOption) Binder {
+	if err := i.safe.BindCopy(v, options...); err != nil {
+		panic(err)
+	}
+	return i
+}
+
+// BindFlagged binds as to on or off, chosen each time it is injected by whether enabled returns
+// true. Panics on error. See SafeInjector.BindFlagged() for details.
+func (i *Injector) BindFlagged(as interface{}, enabled func() bool, on, off interface{}, options ...BindOption) Binder {
+	if err := i.safe.BindFlagged(as, enabled, on, off, options...); err != nil {
+		panic(err)
+	}
+	return i
+}
+
 // BindTo binds an interface to a value. Panics on error.
 //
 // "as" should either be a nil pointer to the required interface:
@@ -93,6 +280,13 @@ func (i *Injector) Bind(things ...interface{}) Binder {
 //
 // 		i.BindTo(int64(0), 10)
 //
+// Slices, arrays and maps are converted element by element if necessary:
+//
+// 		i.BindTo([]string{}, []MyString{"a"})
+//
+// Values provided as an interface may also be bound to a concrete type implementing it, in which
+// case building the binding fails if the value is not of that type.
+//
 func (i *Injector) BindTo(iface interface{}, impl interface{}) Binder {
 	if err := i.safe.BindTo(iface, impl); err != nil {
 		panic(err)
@@ -100,11 +294,26 @@ func (i *Injector) BindTo(iface interface{}, impl interface{}) Binder {
 	return i
 }
 
-// Get acquires a value of type t from the injector.
+// Get acquires a value of type t from the injector. It is equivalent to GetType(), and to
+// SafeInjector.GetType() rather than SafeInjector.Get(), which takes an example value.
 //
 // It is usually preferable to use Call().
 func (i *Injector) Get(t reflect.Type) interface{} {
-	v, err := i.safe.Get(t)
+	return i.GetType(t)
+}
+
+// GetType acquires a value of type t from the injector. Panics on error.
+func (i *Injector) GetType(t reflect.Type) interface{} {
+	v, err := i.safe.GetType(t)
+	if err != nil {
+		panic(err)
+	}
+	return v
+}
+
+// GetKey acquires the value bound to key from the injector.
+func (i *Injector) GetKey(key Key) interface{} {
+	v, err := i.safe.GetKey(key)
 	if err != nil {
 		panic(err)
 	}
@@ -120,18 +329,315 @@ func (i *Injector) Call(f interface{}) []interface{} {
 	return r
 }
 
+// CallKeyed calls f, injecting any arguments, resolving parameters whose type matches that of one
+// of keys using that key. Panics if the function errors.
+func (i *Injector) CallKeyed(f interface{}, keys ...Key) []interface{} {
+	r, err := i.safe.CallKeyed(f, keys...)
+	if err != nil {
+		panic(err)
+	}
+	return r
+}
+
+// CallWith calls f, injecting any arguments, with values in extras taking precedence over bindings
+// for matching parameter types. Panics if the function errors.
+func (i *Injector) CallWith(f interface{}, extras ...interface{}) []interface{} {
+	r, err := i.safe.CallWith(f, extras...)
+	if err != nil {
+		panic(err)
+	}
+	return r
+}
+
+// CallContext calls f, injecting ctx into its context.Context parameters. Panics if the function
+// errors. See SafeInjector.CallContext() for details.
+func (i *Injector) CallContext(ctx context.Context, f interface{}, extras ...interface{}) []interface{} {
+	r, err := i.safe.CallContext(ctx, f, extras...)
+	if err != nil {
+		panic(err)
+	}
+	return r
+}
+
+// Merge the bindings and modules of other into this injector, resolving conflicting bindings
+// according to policy. Panics on error.
+//
+// See SafeInjector.Merge() for details.
+func (i *Injector) Merge(other *Injector, policy ConflictPolicy) {
+	if err := i.safe.Merge(other.safe, policy); err != nil {
+		panic(err)
+	}
+}
+
 // Child creates a child Injector whose bindings overlay those of the parent.
 //
-// The parent will never be modified by the child.
+// The parent will never be modified by the child. Singletons bound in the parent are cached by the
+// parent, while singletons bound in the child are cached by the child.
 func (i *Injector) Child() *Injector {
 	return &Injector{safe: i.safe.Child()}
 }
 
+// ChildWithOptions creates a child Injector configured by options, such as ShareSingletons().
+func (i *Injector) ChildWithOptions(options ...ChildOption) *Injector {
+	return &Injector{safe: i.safe.ChildWithOptions(options...)}
+}
+
+// ResetSingleton discards the cached value of the singleton providing the type of t, so that it
+// will be rebuilt the next time it is requested. Panics if no such singleton is bound, or its
+// value fails to close. See SafeInjector.ResetSingleton() for details.
+func (i *Injector) ResetSingleton(t interface{}) {
+	if err := i.safe.ResetSingleton(t); err != nil {
+		panic(err)
+	}
+}
+
+// ResetAllSingletons discards the cached values of all singletons in this injector. Panics if any
+// value fails to close, once all have been reset. See SafeInjector.ResetAllSingletons() for
+// details.
+func (i *Injector) ResetAllSingletons() {
+	if err := i.safe.ResetAllSingletons(); err != nil {
+		panic(err)
+	}
+}
+
+// Close calls Cleanup functions registered by this injector's providers and closes singleton
+// values built by it that implement io.Closer, in reverse order. See SafeInjector.Close() for
+// details.
+func (i *Injector) Close() error {
+	return i.safe.Close()
+}
+
+// Run runs the Workers bound in sequences until they have all returned, cancelling them all if any
+// fails. See SafeInjector.Run() for details.
+func (i *Injector) Run(ctx context.Context) error {
+	return i.safe.Run(ctx)
+}
+
+// Start calls the Start(ctx, deps...) method of each installed module that has one, in dependency
+// order. See SafeInjector.Start() for details.
+func (i *Injector) Start(ctx context.Context) error {
+	return i.safe.Start(ctx)
+}
+
+// Stop calls the Stop(ctx) method of each started module that has one, in reverse order. See
+// SafeInjector.Stop() for details.
+func (i *Injector) Stop(ctx context.Context) error {
+	return i.safe.Stop(ctx)
+}
+
+// Reload re-provides the bindings of types and everything that depends on them, restarting
+// affected modules. See SafeInjector.Reload() for details.
+func (i *Injector) Reload(ctx context.Context, types ...interface{}) error {
+	return i.safe.Reload(ctx, types...)
+}
+
+// WatchFile calls Reload() with types each time the file at path changes, until ctx is done. See
+// SafeInjector.WatchFile() for details.
+func (i *Injector) WatchFile(ctx context.Context, path string, interval time.Duration, onError func(error), types ...interface{}) error {
+	return i.safe.WatchFile(ctx, path, interval, onError, types...)
+}
+
+// AddResolver appends a fallback resolver consulted for keys that are not bound. See
+// SafeInjector.AddResolver() for details.
+func (i *Injector) AddResolver(resolver Resolver) {
+	i.safe.AddResolver(resolver)
+}
+
+// Warnings returns the warnings recorded when building deprecated bindings of this injector. See
+// Deprecated().
+func (i *Injector) Warnings() []string {
+	return i.safe.Warnings()
+}
+
+// OnBuild registers a listener that is called each time a provider bound to this injector, or to
+// any of its children, is called. See SafeInjector.OnBuild() for details.
+func (i *Injector) OnBuild(listener BuildListener) {
+	i.safe.OnBuild(listener)
+}
+
+// AfterBuild registers processor to be applied to each value of type t built by a provider of this
+// injector or its children. See SafeInjector.AfterBuild() for details.
+func (i *Injector) AfterBuild(t interface{}, processor PostProcessor) {
+	i.safe.AfterBuild(t, processor)
+}
+
+// Observe registers an Observer of provider calls and singleton cache hits in this injector and
+// its children.
+func (i *Injector) Observe(observer Observer) {
+	i.safe.Observe(observer)
+}
+
+// Use appends middleware to the chain wrapping every value resolved by this injector and its
+// children. See SafeInjector.Use() for details.
+func (i *Injector) Use(middleware Middleware) {
+	i.safe.Use(middleware)
+}
+
+// SetCollectBindings controls whether requests for []T also collect individually bound values
+// assignable to T. See SafeInjector.SetCollectBindings() for details.
+func (i *Injector) SetCollectBindings(collect bool) {
+	i.safe.SetCollectBindings(collect)
+}
+
+// SetConflictPolicy sets how Bind(), BindTo() and Provide() handle a binding for an already bound
+// key. See SafeInjector.SetConflictPolicy() for details.
+func (i *Injector) SetConflictPolicy(policy ConflictPolicy) {
+	i.safe.SetConflictPolicy(policy)
+}
+
+// SetLogger logs the internal events of this injector and its children to logger. See
+// SafeInjector.SetLogger() for details.
+func (i *Injector) SetLogger(logger Logger) {
+	i.safe.SetLogger(logger)
+}
+
+// SetContext sets the context.Context injected by this injector and its children. See
+// SafeInjector.SetContext() for details.
+func (i *Injector) SetContext(ctx context.Context) {
+	i.safe.SetContext(ctx)
+}
+
+// SetTracer records the resolution of every subsequent Get() and Call() on this injector, and its
+// children, into tracer. A nil tracer disables tracing.
+func (i *Injector) SetTracer(tracer *Tracer) {
+	i.safe.SetTracer(tracer)
+}
+
+// SetParallelism builds the injected arguments of each provider concurrently, with at most workers
+// of them being built at once. See SafeInjector.SetParallelism() for details.
+func (i *Injector) SetParallelism(workers int) {
+	i.safe.SetParallelism(workers)
+}
+
+// OnScope registers a function that configures each child injector created by Scoped(). See
+// SafeInjector.OnScope() for details.
+func (i *Injector) OnScope(setup func(key interface{}, scope *SafeInjector) error) {
+	i.safe.OnScope(setup)
+}
+
+// Scoped returns the child injector for key, creating it if there is none. Panics if configuring
+// a new child fails. See SafeInjector.Scoped() for details.
+func (i *Injector) Scoped(key interface{}) *Injector {
+	child, err := i.safe.Scoped(key)
+	if err != nil {
+		panic(err)
+	}
+	return &Injector{safe: child}
+}
+
+// Evict closes and discards the child injector for key created by Scoped(), if any.
+func (i *Injector) Evict(key interface{}) error {
+	return i.safe.Evict(key)
+}
+
+// EvictAll closes and discards every child injector created by Scoped().
+func (i *Injector) EvictAll() error {
+	return i.safe.EvictAll()
+}
+
+// Implementations returns the bound types that implement the interface iface, without building
+// them. See SafeInjector.Implementations() for details.
+func (i *Injector) Implementations(iface interface{}) []reflect.Type {
+	return i.safe.Implementations(iface)
+}
+
+// Intercept wraps the method calls of values of the interface iface in a proxy that calls
+// interceptor. Panics on error. See SafeInjector.Intercept() for details.
+func (i *Injector) Intercept(iface interface{}, interceptor Interceptor) {
+	if err := i.safe.Intercept(iface, interceptor); err != nil {
+		panic(err)
+	}
+}
+
+// GetAll builds every bound value whose type implements the interface iface. Panics on error. See
+// SafeInjector.GetAll() for details.
+func (i *Injector) GetAll(iface interface{}) []interface{} {
+	out, err := i.safe.GetAll(iface)
+	if err != nil {
+		panic(err)
+	}
+	return out
+}
+
+// Explain describes how a value of type t would be resolved, as a tree of the bindings that would
+// be used. See SafeInjector.Explain() for details.
+func (i *Injector) Explain(t interface{}) string {
+	return i.safe.Explain(t)
+}
+
+// Prewarm eagerly builds the values of the given types and everything they require. See
+// SafeInjector.Prewarm() for details.
+func (i *Injector) Prewarm(types ...interface{}) error {
+	return i.safe.Prewarm(types...)
+}
+
+// SetStrict makes binding fail if a provider requires a type that is not bound. See
+// SafeInjector.SetStrict() for details.
+func (i *Injector) SetStrict(strict bool) {
+	i.safe.SetStrict(strict)
+}
+
+// Expect declares types that will be bound later, for strict binding. See SetStrict().
+func (i *Injector) Expect(types ...interface{}) {
+	i.safe.Expect(types...)
+}
+
+// SetAdaptPointers controls whether requests for *T are satisfied by a binding of T, and vice versa.
+// See SafeInjector.SetAdaptPointers() for details.
+func (i *Injector) SetAdaptPointers(adapt bool) {
+	i.safe.SetAdaptPointers(adapt)
+}
+
+// ValidateScopes checks that no binding visible to this injector outlives a value it requires. See
+// SafeInjector.ValidateScopes() for details.
+func (i *Injector) ValidateScopes() error {
+	return i.safe.ValidateScopes()
+}
+
 // Validate that the function f can be called by the injector.
 func (i *Injector) Validate(f interface{}) error {
 	return i.safe.Validate(f)
 }
 
+// Modules returns the modules installed in this injector, in the order they were installed.
+func (i *Injector) Modules() []interface{} {
+	return i.safe.Modules()
+}
+
+// ModuleBindings returns the bindings contributed by an installed module, from both its Provide*
+// methods and its Configure method.
+func (i *Injector) ModuleBindings(module interface{}) []*Binding {
+	bindings, err := i.safe.ModuleBindings(module)
+	if err != nil {
+		panic(err)
+	}
+	return bindings
+}
+
+// Bindings returns the bindings of this injector, excluding those of any parent, in the order they
+// were first bound.
+func (i *Injector) Bindings() []*Binding {
+	return i.safe.Bindings()
+}
+
+// TopoOrder returns the bindings of this injector sorted so that each binding comes after the
+// bindings it requires. See SafeInjector.TopoOrder() for details.
+func (i *Injector) TopoOrder() ([]*Binding, error) {
+	return i.safe.TopoOrder()
+}
+
+// Plan returns the bindings whose providers would run to satisfy entrypoints, in the order they
+// would run, without running any of them. See SafeInjector.Plan() for details.
+func (i *Injector) Plan(entrypoints ...interface{}) ([]*BindingInfo, error) {
+	return i.safe.Plan(entrypoints...)
+}
+
+// WriteMermaid writes a Mermaid flowchart of the bindings in this injector and their dependencies
+// to w.
+func (i *Injector) WriteMermaid(w io.Writer) error {
+	return i.safe.WriteMermaid(w)
+}
+
 // Safe returns the underlying SafeInjector.
 func (i *Injector) Safe() *SafeInjector {
 	return i.safe
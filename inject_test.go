@@ -2,8 +2,18 @@ package inject
 
 import (
 	"bytes"
+	"context"
+	"errors"
 	"fmt"
+	"io"
+	"io/ioutil"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"sync"
+	"sync/atomic"
 	"testing"
+	"time"
 
 	"github.com/stretchr/testify/require"
 )
@@ -62,6 +72,48 @@ func TestInjectorBindToTypeAlias(t *testing.T) {
 	require.Equal(t, int64(10), w)
 }
 
+type testMyString string
+
+func TestInjectorBindToCollectionConversion(t *testing.T) {
+	i := SafeNew()
+	require.NoError(t, i.BindTo([]string{}, []testMyString{"a", "b"}))
+	require.NoError(t, i.BindTo(map[string][]string{}, map[testMyString][]testMyString{"k": {"v"}}))
+	require.NoError(t, i.BindTo([2]int64{}, [2]int{1, 2}))
+	v, err := i.Get([]string{})
+	require.NoError(t, err)
+	require.Equal(t, []string{"a", "b"}, v)
+	v, err = i.Get(map[string][]string{})
+	require.NoError(t, err)
+	require.Equal(t, map[string][]string{"k": {"v"}}, v)
+	v, err = i.Get([2]int64{})
+	require.NoError(t, err)
+	require.Equal(t, [2]int64{1, 2}, v)
+	require.EqualError(t, i.BindTo([]int{}, []string{}), "implementation []string can not be converted to []int")
+}
+
+type testYourString string
+
+func (y testYourString) String() string { return string(y) }
+
+func TestInjectorBindToRefChain(t *testing.T) {
+	i := SafeNew()
+	require.NoError(t, i.Bind(Singleton(func() testMyString { return "hello" })))
+	require.NoError(t, i.BindTo(testYourString(""), Ref(testMyString(""))))
+	require.NoError(t, i.BindTo((*fmt.Stringer)(nil), Ref(testYourString(""))))
+	require.NoError(t, i.BindTo([]byte{}, Ref(testYourString(""))))
+	v, err := i.Get((*fmt.Stringer)(nil))
+	require.NoError(t, err)
+	require.Equal(t, testYourString("hello"), v)
+	v, err = i.Get([]byte{})
+	require.NoError(t, err)
+	require.Equal(t, []byte("hello"), v)
+
+	require.EqualError(t, i.BindTo(0, Ref(testMyString(""))), "implementation inject.testMyString can not be converted to int")
+	c := i.Child()
+	require.NoError(t, c.BindTo(int64(0), Ref(0)))
+	require.EqualError(t, c.BindTo(0, Ref(int64(0))), "recursive binding int -> int64 -> int")
+}
+
 func TestInjectorBindToInvalidImplementation(t *testing.T) {
 	i := SafeNew()
 	s := "hello"
@@ -143,268 +195,3025 @@ func TestSingletonToNonProviderPanics(t *testing.T) {
 	})
 }
 
-func TestDynamicInjection(t *testing.T) {
+func TestTransientAnnotation(t *testing.T) {
 	i := SafeNew()
-	called := 0
-	i.Bind(func() *string {
-		called++
-		s := new(string)
-		*s = fmt.Sprintf("hello:%d", called)
-		return s
-	})
-	p := new(string)
-	a, err := i.Get(p)
-	require.NoError(t, err)
-	b, err := i.Get(p)
-	require.NoError(t, err)
-	require.NotEqual(t, a, b)
-	require.Equal(t, 2, called)
+	calls := 0
+	require.NoError(t, i.Install(Providers(Transient(func() string {
+		calls++
+		return "hello"
+	}))))
+	require.NoError(t, i.Bind(Transient(Singleton(func() int {
+		calls++
+		return 1
+	}))))
+	i.Get("")
+	i.Get("")
+	i.Get(0)
+	i.Get(0)
+	require.Equal(t, 4, calls)
+	require.Contains(t, i.Explain(""), "via Transient()")
+	require.Error(t, i.Bind(Transient(1.0)))
+	require.Error(t, i.Bind(Singleton(Transient(func() float64 { return 1 }))))
 }
 
-func TestSequenceAnnotation(t *testing.T) {
+func TestTimeoutAnnotation(t *testing.T) {
 	i := SafeNew()
-	i.Bind(Sequence([]int{1}))
-	i.Bind(Sequence([]int{2}))
-	i.Bind(Sequence(Singleton(func() []int { return []int{3} })))
-	v, err := i.Get([]int{})
+	release := make(chan struct{})
+	defer close(release)
+	require.NoError(t, i.Bind(Timeout(10*time.Millisecond, func() string {
+		<-release
+		return "slow"
+	})))
+	require.NoError(t, i.Bind(Singleton(Timeout(time.Second, func() int { return 1 }))))
+	require.NoError(t, i.Bind(Timeout(time.Second, func() float64 { panic("boom") })))
+	_, err := i.Get("")
+	require.Error(t, err)
+	require.Regexp(t, `^provider .*TestTimeoutAnnotation.func1 of string timed out after 10ms$`, err.Error())
+	v, err := i.Get(0)
 	require.NoError(t, err)
-	require.Equal(t, []int{1, 2, 3}, v)
+	require.Equal(t, 1, v)
+	_, err = i.Get(1.0)
+	require.IsType(t, &PanicError{}, err)
 }
 
-func TestMappingAnnotation(t *testing.T) {
+func TestTimeoutContinuesResolution(t *testing.T) {
+	// A provider requesting its own singleton fails rather than waiting for the timeout.
 	i := SafeNew()
-	i.Bind(Mapping(map[string]int{"one": 1}))
-	i.Bind(Mapping(map[string]int{"two": 2}))
-	i.Bind(Mapping(func() map[string]int { return map[string]int{"three": 3} }))
-	v, err := i.Get(map[string]int{})
+	require.NoError(t, i.Bind(Singleton(Timeout(5*time.Second, func(s *SafeInjector) (string, error) {
+		_, err := s.Get("")
+		return "", err
+	}))))
+	_, err := i.Get("")
+	require.Error(t, err)
+	require.Contains(t, err.Error(), "recursive binding string: it was requested again while being built")
+
+	// Cleanups registered under a timeout belong to the singleton.
+	closed := []string{}
+	i = SafeNew()
+	require.NoError(t, i.Bind(Singleton(Timeout(5*time.Second, func(cleanup Cleanup) int {
+		cleanup(func() { closed = append(closed, "int") })
+		return 1
+	}))))
+	_, err = i.Get(0)
 	require.NoError(t, err)
-	require.Equal(t, map[string]int{"one": 1, "two": 2, "three": 3}, v)
-	called := false
-	i.Call(func(m map[string]int) {
-		called = true
-		require.Equal(t, map[string]int{"one": 1, "two": 2, "three": 3}, m)
-	})
-	require.True(t, called)
+	require.NoError(t, i.ResetSingleton(0))
+	require.Equal(t, []string{"int"}, closed)
 }
 
-func TestLiteral(t *testing.T) {
+type testDeprecatedModule struct{}
+
+func (testDeprecatedModule) Deprecated() string    { return "use testModule" }
+func (testDeprecatedModule) ProvideInt() int       { return 1 }
+func (testDeprecatedModule) ProvideFloat() float64 { return 2 }
+
+func TestDeprecated(t *testing.T) {
 	i := SafeNew()
-	buf := bytes.Buffer{}
-	i.Bind(Literal(buf.WriteString))
-	i.Call(func(write func(string) (int, error)) {
-		write("hello world")
-	})
-	require.Equal(t, "hello world", buf.String())
+	require.NoError(t, i.Bind(Deprecated("use int", Singleton(func() string { return "legacy" }))))
+	require.NoError(t, i.Install(testDeprecatedModule{}))
+	require.Empty(t, i.Warnings())
+	_, err := i.Get("")
+	require.NoError(t, err)
+	_, err = i.Get("")
+	require.NoError(t, err)
+	_, err = i.Get(0)
+	require.NoError(t, err)
+	warnings := i.Warnings()
+	require.Len(t, warnings, 2)
+	require.Regexp(t, `^string \(provided by .*TestDeprecated.func1\) is deprecated: use int$`, warnings[0])
+	require.Equal(t, "int (provided by github.com/alecthomas/inject.testDeprecatedModule.ProvideInt) is deprecated: module inject.testDeprecatedModule: use testModule", warnings[1])
 }
 
-type UserName string
-
-func TestPseudoBoundValues(t *testing.T) {
+func TestParallelism(t *testing.T) {
+	var active, peak int32
+	dial := func(v interface{}) interface{} {
+		n := atomic.AddInt32(&active, 1)
+		for {
+			p := atomic.LoadInt32(&peak)
+			if n <= p || atomic.CompareAndSwapInt32(&peak, p, n) {
+				break
+			}
+		}
+		time.Sleep(20 * time.Millisecond)
+		atomic.AddInt32(&active, -1)
+		return v
+	}
 	i := SafeNew()
-	i.Bind(UserName("bob"))
-	name := ""
-	i.Call(func(user UserName) {
-		name = string(user)
+	i.SetParallelism(2)
+	require.NoError(t, i.Bind(
+		func() string { return dial("a").(string) },
+		func() int { return dial(1).(int) },
+		func() float64 { return dial(2.0).(float64) },
+		func() bool { return dial(true).(bool) },
+	))
+	out, err := i.Call(func(s string, n int, f float64, b bool) string {
+		return fmt.Sprintf("%s %d %g %v", s, n, f, b)
 	})
-	require.Equal(t, "bob", name)
+	require.NoError(t, err)
+	require.Equal(t, []interface{}{"a 1 2 true"}, out)
+	require.Equal(t, int32(2), atomic.LoadInt32(&peak))
+
+	// Errors are reported for the first failing argument.
+	c := i.Child()
+	require.NoError(t, c.Bind(func() (int, error) { return 0, fmt.Errorf("no int") }))
+	_, err = c.Call(func(s string, n int, b bool) {})
+	require.EqualError(t, err, "building int: provider github.com/alecthomas/inject.TestParallelism.func7 failed: no int")
 }
 
-type myModule struct{}
+type testStrictConfig struct{}
 
-func (m *myModule) ProvideString(i int) string { return fmt.Sprintf("hello:%d", i) }
+func TestUnboundTypeSuggestions(t *testing.T) {
+	tests := []struct {
+		bound    []interface{}
+		get      interface{}
+		expected string
+	}{
+		{[]interface{}{testStrictConfig{}}, &testStrictConfig{},
+			"unbound type *inject.testStrictConfig (did you mean inject.testStrictConfig? it is bound as a value, not a pointer; see SetAdaptPointers())"},
+		{[]interface{}{&testStrictConfig{}}, testStrictConfig{},
+			"unbound type inject.testStrictConfig (did you mean *inject.testStrictConfig? it is bound as a pointer, not a value; see SetAdaptPointers())"},
+		{[]interface{}{&bytes.Reader{}}, &strings.Reader{},
+			`unbound type *strings.Reader (did you mean *bytes.Reader from package "bytes"?)`},
+		{[]interface{}{1, Name("port")}, 0,
+			`unbound type int (did you mean int("port")? it is bound with a name)`},
+		{[]interface{}{testHandlerA{}, Name("a")}, (*testHandler)(nil),
+			`unbound type inject.testHandler (did you mean inject.testHandlerA("a")? it is bound with a name)`},
+		{[]interface{}{testHandlerB{}}, (*testHandler)(nil),
+			"unbound type inject.testHandler (did you mean to bind *inject.testHandlerB? inject.testHandlerB is bound, but only *inject.testHandlerB implements inject.testHandler)"},
+		{[]interface{}{"unrelated"}, 0, "unbound type int"},
+	}
+	for _, test := range tests {
+		i := SafeNew()
+		require.NoError(t, i.Bind(test.bound...))
+		_, err := i.Child().Get(test.get)
+		require.EqualError(t, err, test.expected)
+	}
+}
 
-func TestModule(t *testing.T) {
+type testAdaptedConfig struct {
+	Name string
+}
+
+func TestAdaptPointers(t *testing.T) {
 	i := SafeNew()
-	err := i.Bind(123)
+	i.SetAdaptPointers(true)
+	require.NoError(t, i.Bind(testAdaptedConfig{Name: "value"}))
+	require.NoError(t, i.Bind(func() *testStrictConfig { return &testStrictConfig{} }))
+	c := i.Child()
+
+	v, err := c.Get(&testAdaptedConfig{})
 	require.NoError(t, err)
-	err = i.Install(&myModule{})
+	ptr := v.(*testAdaptedConfig)
+	require.Equal(t, "value", ptr.Name)
+	ptr.Name = "changed"
+	v, err = c.Get(testAdaptedConfig{})
 	require.NoError(t, err)
-	actual, err := i.Get("")
+	require.Equal(t, "value", v.(testAdaptedConfig).Name)
+
+	v, err = c.Get(testStrictConfig{})
 	require.NoError(t, err)
-	require.Equal(t, "hello:123", actual)
+	require.Equal(t, testStrictConfig{}, v)
+
+	require.NoError(t, c.Bind(&testAdaptedConfig{Name: "exact"}))
+	v, err = c.Get(&testAdaptedConfig{})
+	require.NoError(t, err)
+	require.Equal(t, "exact", v.(*testAdaptedConfig).Name)
+
+	i = SafeNew()
+	require.NoError(t, i.Bind(func() *testStrictConfig { return nil }))
+	i.SetAdaptPointers(true)
+	_, err = i.Get(testStrictConfig{})
+	require.EqualError(t, err, "can not adapt nil *inject.testStrictConfig to inject.testStrictConfig")
 }
 
-func TestCallError(t *testing.T) {
-	f := func() error {
-		return fmt.Errorf("failed")
-	}
+func TestBuilder(t *testing.T) {
 	i := SafeNew()
-	_, err := i.Call(f)
+	b := i.Builder()
+	require.NoError(t, b.Bind(1).Provide("greeting", func() string { return "hello" }).Err())
+	b.BindTo((*fmt.Stringer)(nil), 1).
+		Override(2).
+		Bind(3).
+		Install(testStrictModule{})
+	err := b.Err()
 	require.Error(t, err)
+	errs, ok := err.(Errors)
+	require.True(t, ok)
+	require.Len(t, errs, 3)
+	require.EqualError(t, errs[1], "int is already bound")
+	require.Equal(t, i, b.Injector())
+	v, err := i.Get(0)
+	require.NoError(t, err)
+	require.Equal(t, 2, v)
 }
 
-type notQuiteStringer int
+type testStrictModule struct{}
 
-func (n notQuiteStringer) String() string { return fmt.Sprintf("%d", n) }
+func (testStrictModule) ProvideString(n int) string { return fmt.Sprint(n) }
+func (testStrictModule) ProvideInt() int            { return 1 }
 
-type notQuiteAnotherStringer float32
+func TestStrict(t *testing.T) {
+	i := SafeNew()
+	i.SetStrict(true)
+	require.NoError(t, i.Bind(testStrictConfig{}))
+	err := i.Bind(func(*testStrictConfig) bool { return true })
+	require.EqualError(t, err, "no binding for *inject.testStrictConfig required by bool (did you mean inject.testStrictConfig? it is bound as a value, not a pointer; see SetAdaptPointers())")
 
-func (n notQuiteAnotherStringer) String() string { return fmt.Sprintf("%f", n) }
+	// Bindings made together may require each other in any order.
+	require.NoError(t, i.Child().Bind(func(n int) string { return "" }, func() int { return 1 }))
+	require.NoError(t, i.Child().Install(testStrictModule{}))
 
-func TestInterfaceConversion(t *testing.T) {
-	f := func(s fmt.Stringer) error {
-		return nil
-	}
-	i := SafeNew()
-	i.Bind(notQuiteStringer(10))
-	_, err := i.Call(f)
+	c := i.Child()
+	require.EqualError(t, c.Bind(func(float64) string { return "" }), "no binding for float64 required by string")
+	c = i.Child()
+	c.Expect(0.0)
+	require.NoError(t, c.Bind(func(float64) string { return "" }))
+	require.NoError(t, c.Bind(1.0))
+	v, err := c.Get("")
 	require.NoError(t, err)
+	require.Equal(t, "", v)
 }
 
-func TestSliceInterfaceConversion(t *testing.T) {
-	expected := []fmt.Stringer{notQuiteStringer(10), notQuiteAnotherStringer(20)}
-	actual := []fmt.Stringer{}
-	f := func(s []fmt.Stringer) error {
-		actual = s
-		return nil
-	}
+type testReadWriter struct{ *bytes.Buffer }
+
+func TestInterfaceProviders(t *testing.T) {
+	concrete := &bytes.Buffer{}
+	explicit := testReadWriter{&bytes.Buffer{}}
 	i := SafeNew()
-	i.Bind(Sequence([]notQuiteStringer{10}))
-	i.Bind(Sequence([]notQuiteAnotherStringer{20}))
-	_, err := i.Call(f)
+	require.NoError(t, i.Bind(concrete))
+	require.NoError(t, i.Bind(func() io.ReadWriter { return explicit }))
+	v, err := i.Get((*io.Writer)(nil))
 	require.NoError(t, err)
-	require.Equal(t, expected, actual)
-}
+	require.Equal(t, explicit, v)
+	v, err = i.Get(&bytes.Buffer{})
+	require.NoError(t, err)
+	require.Equal(t, concrete, v)
 
-func TestMapValueInterfaceConversion(t *testing.T) {
-	expected := map[string]fmt.Stringer{"a": notQuiteStringer(10), "b": notQuiteAnotherStringer(20)}
-	actual := map[string]fmt.Stringer{}
-	f := func(s map[string]fmt.Stringer) error {
-		actual = s
-		return nil
-	}
-	i := SafeNew()
-	i.Bind(Mapping(map[string]notQuiteStringer{"a": notQuiteStringer(10)}))
-	i.Bind(Mapping(map[string]notQuiteAnotherStringer{"b": notQuiteAnotherStringer(20)}))
-	_, err := i.Call(f)
+	// Interface values can be bound to the concrete type implementing them.
+	c := i.Child()
+	require.NoError(t, c.BindTo(testReadWriter{}, func() io.Writer { return explicit }))
+	v, err = c.Get(testReadWriter{})
 	require.NoError(t, err)
-	require.Equal(t, expected, actual)
+	require.Equal(t, explicit, v)
+	c = i.Child()
+	require.NoError(t, c.BindTo(testReadWriter{}, func() io.Writer { return concrete }))
+	_, err = c.Get(testReadWriter{})
+	require.EqualError(t, err, "io.Writer value *bytes.Buffer can not be converted to inject.testReadWriter")
 }
 
-func TestSliceIsNotImplicitlyProvided(t *testing.T) {
-	f := func(s []string) {}
+func TestAsLiteral(t *testing.T) {
+	buf := &bytes.Buffer{}
 	i := SafeNew()
-	_, err := i.Call(f)
-	require.Error(t, err)
+	require.NoError(t, i.Bind(buf.WriteString, Name("buf"), AsLiteral()))
+	require.NoError(t, i.Bind(buf.WriteString, AsLiteral()))
+	_, err := i.Call(func(write func(string) (int, error)) error {
+		_, err := write("hello")
+		return err
+	})
+	require.NoError(t, err)
+	require.Equal(t, "hello", buf.String())
+	v, err := i.GetKey(Key{Type: reflect.TypeOf(buf.WriteString), Name: "buf"})
+	require.NoError(t, err)
+	require.NotNil(t, v)
 }
 
-func TestMappingIsNotImplicitlyProvided(t *testing.T) {
-	f := func(s map[string]string) {}
-	i := SafeNew()
-	_, err := i.Call(f)
-	require.Error(t, err)
-}
+type testRegisteredModule struct{}
 
-func TestIs(t *testing.T) {
-	require.True(t, Sequence([]int{1, 2}).Is(&sequenceType{}))
-}
+func (testRegisteredModule) ProvideRegistered() string { return "registered" }
 
-func TestDuplicateNamedBindErrors(t *testing.T) {
-	type Named string
+func TestRegistry(t *testing.T) {
+	registry := NewRegistry()
+	registry.Register(&testRegisteredModule{}, func() int { return 1 })
+	require.Len(t, registry.Modules(nil), 2)
+	require.Equal(t, []interface{}{&testRegisteredModule{}}, registry.Modules(InPackages("github.com/alecthomas/inject")))
+	require.Empty(t, registry.Modules(InPackages("example.com/")))
 
+	Register(&testRegisteredModule{})
 	i := SafeNew()
-	err := i.Bind(Named("alec"))
+	require.NoError(t, i.InstallRegistered(InPackages("github.com/alecthomas/inject")))
+	v, err := i.Get("")
 	require.NoError(t, err)
-	err = i.Bind(Named("bob"))
-	require.Error(t, err)
+	require.Equal(t, "registered", v)
 }
 
-func TestValidate(t *testing.T) {
-	i := SafeNew()
-
-	err := i.Validate(func(string) {})
-	require.Error(t, err)
-
-	i.Bind(func(int) string { return "hello" })
-	err = i.Validate(func(string) {})
-	require.Error(t, err)
-	i.Bind(10)
+type testReloadConfig struct{ version int }
 
-	// Verify that Validate doesn't call anything.
-	var actual string
-	err = i.Validate(func(s string) { actual = s })
-	require.NoError(t, err)
-	require.Equal(t, "", actual)
+type testReloadService struct {
+	config *testReloadConfig
+	closed bool
 }
 
-type testModuleA struct {
-	param int
+func (s *testReloadService) Close() error {
+	s.closed = true
+	return nil
 }
 
-func (t *testModuleA) ProvideInt(string) int { return 0 }
+type testReloadModule struct{ starts, stops int }
 
-type testModuleB struct{}
+func (m *testReloadModule) Start(ctx context.Context, service *testReloadService) error {
+	m.starts++
+	return nil
+}
 
-func (t *testModuleB) ProvideString(int) string { return "" }
+func (m *testReloadModule) Stop(ctx context.Context) error {
+	m.stops++
+	return nil
+}
 
-func TestProviderCycle(t *testing.T) {
+func TestReload(t *testing.T) {
+	version := 0
+	unrelated := 0
 	i := SafeNew()
-	i.Install(&testModuleA{})
-	i.Install(&testModuleB{})
-	_, err := i.Get(int(0))
-	require.Error(t, err)
+	require.NoError(t, i.Bind(
+		Singleton(func() *testReloadConfig {
+			version++
+			return &testReloadConfig{version}
+		}),
+		Singleton(func(config *testReloadConfig) *testReloadService { return &testReloadService{config: config} }),
+		Singleton(func() string {
+			unrelated++
+			return "unrelated"
+		}),
+	))
+	module := &testReloadModule{}
+	require.NoError(t, i.Install(module))
+	require.NoError(t, i.Start(context.Background()))
+	_, err := i.Get("")
+	require.NoError(t, err)
+	v, err := i.Get(&testReloadService{})
+	require.NoError(t, err)
+	old := v.(*testReloadService)
+	require.Equal(t, 1, old.config.version)
+
+	built := []reflect.Type{}
+	i.OnBuild(func(t reflect.Type, v interface{}, err error) { built = append(built, t) })
+	require.NoError(t, i.Reload(context.Background(), &testReloadConfig{}))
+	require.True(t, old.closed)
+	require.Equal(t, []reflect.Type{reflect.TypeOf(&testReloadConfig{}), reflect.TypeOf(&testReloadService{})}, built)
+	v, err = i.Get(&testReloadService{})
+	require.NoError(t, err)
+	require.Equal(t, 2, v.(*testReloadService).config.version)
+	require.Equal(t, 1, unrelated)
+	require.Equal(t, 2, module.starts)
+	require.Equal(t, 1, module.stops)
 }
 
-func TestInstallIdenticalDuplicateModule(t *testing.T) {
+func TestWatchFile(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "config")
+	require.NoError(t, ioutil.WriteFile(path, []byte("1"), 0600))
 	i := SafeNew()
-	err := i.Install(&testModuleA{})
-	require.NoError(t, err)
-	err = i.Install(&testModuleA{})
+	require.NoError(t, i.Bind(Singleton(func() (*testReloadConfig, error) {
+		data, err := ioutil.ReadFile(path)
+		if err != nil {
+			return nil, err
+		}
+		return &testReloadConfig{len(data)}, nil
+	})))
+	_, err := i.Get(&testReloadConfig{})
 	require.NoError(t, err)
+	reloaded := make(chan interface{}, 1)
+	i.OnBuild(func(t reflect.Type, v interface{}, err error) {
+		select {
+		case reloaded <- v:
+		default:
+		}
+	})
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan error)
+	go func() { done <- i.WatchFile(ctx, path, time.Millisecond, nil, &testReloadConfig{}) }()
+	// Keep changing the file, as the watcher may not have started yet.
+	timeout := time.After(5 * time.Second)
+	for data := "22"; ; data += "2" {
+		require.NoError(t, ioutil.WriteFile(path, []byte(data), 0600))
+		select {
+		case v := <-reloaded:
+			require.True(t, v.(*testReloadConfig).version > 1)
+		case <-time.After(10 * time.Millisecond):
+			continue
+		case <-timeout:
+			t.Fatal("config was not reloaded")
+		}
+		break
+	}
+	cancel()
+	require.NoError(t, <-done)
 }
 
-func TestInstallDifferingDuplicateModule(t *testing.T) {
+func TestLogger(t *testing.T) {
+	events := []string{}
 	i := SafeNew()
-	err := i.Install(&testModuleA{param: 1})
+	i.SetLogger(func(level, msg string, kv ...interface{}) {
+		events = append(events, fmt.Sprintf("%s %s %v", level, msg, kv))
+	})
+	require.NoError(t, i.Bind(Singleton(func() *bytes.Buffer { return &bytes.Buffer{} })))
+	require.NoError(t, i.Install(&testRegisteredModule{}))
+	_, err := i.Get((*io.Writer)(nil))
 	require.NoError(t, err)
-	err = i.Install(&testModuleA{param: 2})
-	require.Error(t, err)
+	c := i.Child()
+	require.NoError(t, c.Bind(Deprecated("no", 1)))
+	_, err = c.Get(0)
+	require.NoError(t, err)
+	require.Equal(t, []string{
+		"debug bound [key *bytes.Buffer annotation Singleton]",
+		"debug bound [key string annotation Singleton]",
+		"debug installed module [module inject.testRegisteredModule]",
+		"debug resolved interface to implementation [key io.Writer implementation *bytes.Buffer]",
+		"debug built singleton [type *bytes.Buffer]",
+		"debug created child injector []",
+		"debug bound [key int annotation Literal]",
+		"warn int is deprecated: no []",
+	}, events)
 }
 
-type testConfigurableModuleA struct{}
+func TestMiddleware(t *testing.T) {
+	audit := []string{}
+	i := SafeNew()
+	require.NoError(t, i.Bind("hello", func(s string) int { return len(s) }))
+	i.Use(func(req Request, next Next) (interface{}, error) {
+		audit = append(audit, "parent "+req.Key.String())
+		return next()
+	})
+	c := i.Child()
+	c.Use(func(req Request, next Next) (interface{}, error) {
+		audit = append(audit, "child "+req.Key.String())
+		if req.Key.Type == reflect.TypeOf("") {
+			v, err := next()
+			if err != nil {
+				return nil, err
+			}
+			return v.(string) + " world", nil
+		}
+		return next()
+	})
+	out, err := c.Call(func(s string, n int) string { return fmt.Sprintf("%s %d", s, n) })
+	require.NoError(t, err)
+	require.Equal(t, []interface{}{"hello world 5"}, out)
+	require.Equal(t, []string{"parent string", "child string", "parent int", "child int", "parent string"}, audit)
 
-func (t *testConfigurableModuleA) Configure(binder Binder) error {
-	binder.Bind(10)
-	return nil
+	c.Use(func(req Request, next Next) (interface{}, error) {
+		if req.Key.Type == reflect.TypeOf(0) {
+			return nil, fmt.Errorf("int is gated")
+		}
+		return 1.0, nil
+	})
+	_, err = c.Get(0)
+	require.EqualError(t, err, "int is gated")
+	_, err = c.Get("")
+	require.EqualError(t, err, "middleware returned float64 for string")
 }
 
-type testConfigurableModuleB struct{}
+func testNewName() string { return "name" }
 
-func (t *testConfigurableModuleB) Configure(binder Binder) error {
-	binder.Install(&testConfigurableModuleA{})
-	return nil
-}
+func testNewGreeting(name string) (fmt.Stringer, error) { return stringer("hello " + name), nil }
 
-func TestInstallConfigurableModule(t *testing.T) {
+func testNewBroken() {}
+
+func TestInstallConstructors(t *testing.T) {
 	i := SafeNew()
-	i.Install(&testConfigurableModuleB{})
-	v, err := i.Get(0)
+	require.NoError(t, i.InstallConstructors(testNewName, testNewGreeting))
+	v, err := i.Get((*fmt.Stringer)(nil))
 	require.NoError(t, err)
-	require.Equal(t, 10, v.(int))
-}
+	require.Equal(t, stringer("hello name"), v)
 
-type testModuleParam struct{ param int }
-
-func (t *testModuleParam) ProvideInt() int { return t.param }
+	i = SafeNew()
+	err = i.InstallConstructors(testNewBroken, testNewName, 1, testNewName)
+	require.EqualError(t, err, "constructor github.com/alecthomas/inject.testNewBroken: it returns nothing, but must return (<type>[, error]); "+
+		"constructor must be a function but got int; "+
+		"constructor github.com/alecthomas/inject.testNewName: string is already bound")
+	require.True(t, i.Has(""))
+}
 
-func TestInstallNewZeroModuleKeepsExisting(t *testing.T) {
+func TestConflictPolicy(t *testing.T) {
 	i := SafeNew()
-	err := i.Install(&testModuleParam{param: 123})
+	require.NoError(t, i.Bind("first"))
+	require.EqualError(t, i.Bind("second"), "string is already bound")
+	require.NoError(t, i.Bind("second", OnConflict(ConflictKeep)))
+	v, err := i.Get("")
 	require.NoError(t, err)
-	err = i.Install(&testModuleParam{})
+	require.Equal(t, "first", v)
+
+	i.SetConflictPolicy(ConflictReplace)
+	require.NoError(t, i.Bind("third"))
+	require.NoError(t, i.BindTo((*fmt.Stringer)(nil), stringer("a")))
+	require.NoError(t, i.BindTo((*fmt.Stringer)(nil), stringer("b")))
+	v, err = i.Get("")
 	require.NoError(t, err)
-	v, err := i.Get(0)
+	require.Equal(t, "third", v)
+	v, err = i.Get((*fmt.Stringer)(nil))
 	require.NoError(t, err)
-	require.Equal(t, 123, v)
-}
+	require.Equal(t, stringer("b"), v)
+	require.EqualError(t, i.Bind("fourth", OnConflict(ConflictError)), "string is already bound")
+
+	i.SetConflictPolicy(ConflictPanic)
+	require.PanicsWithError(t, "string is already bound", func() { _ = i.Bind("fifth") })
+	require.NoError(t, i.Bind(1))
+	require.Equal(t, "panic", ConflictPanic.String())
+
+	// Replaced bindings are only built once.
+	conditions := 0
+	cond := func(*Injector) bool {
+		conditions++
+		return true
+	}
+	i = SafeNew()
+	require.NoError(t, i.Bind(1))
+	require.NoError(t, i.Override(If(cond, 2)))
+	require.NoError(t, i.BindTo((*fmt.Stringer)(nil), stringer("a")))
+	i.SetConflictPolicy(ConflictReplace)
+	require.NoError(t, i.BindTo((*fmt.Stringer)(nil), If(cond, stringer("b"))))
+	require.Equal(t, 2, conditions)
+}
+
+type testBackgroundWorker struct {
+	err     error
+	stopped chan struct{}
+}
+
+func (w *testBackgroundWorker) Run(ctx context.Context) error {
+	if w.err != nil {
+		return w.err
+	}
+	<-ctx.Done()
+	close(w.stopped)
+	return nil
+}
+
+func TestRun(t *testing.T) {
+	require.NoError(t, SafeNew().Run(context.Background()))
+
+	waiting := &testBackgroundWorker{stopped: make(chan struct{})}
+	i := SafeNew()
+	require.NoError(t, i.Bind(Sequence([]*testBackgroundWorker{waiting})))
+	require.NoError(t, i.Bind(Sequence([]Worker{&testBackgroundWorker{err: fmt.Errorf("boom")}})))
+	err := i.Run(context.Background())
+	require.EqualError(t, err, "worker *inject.testBackgroundWorker failed: boom")
+	<-waiting.stopped
+
+	waiting = &testBackgroundWorker{stopped: make(chan struct{})}
+	i = SafeNew()
+	require.NoError(t, i.Bind(Sequence([]Worker{waiting})))
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	require.NoError(t, i.Run(ctx))
+	<-waiting.stopped
+}
+
+type testHandler interface{ Handle() string }
+
+type testHandlerA struct{}
+
+func (testHandlerA) Handle() string { return "a" }
+
+type testHandlerB struct{}
+
+func (*testHandlerB) Handle() string { return "b" }
+
+func TestCollectBindings(t *testing.T) {
+	i := SafeNew()
+	require.NoError(t, i.Bind(testHandlerA{}, &testHandlerB{}, Sequence([]testHandler{testHandlerA{}})))
+	v, err := i.Get([]testHandler{})
+	require.NoError(t, err)
+	require.Len(t, v, 1)
+	require.False(t, i.Has([]testHandlerA{}))
+
+	i.SetCollectBindings(true)
+	v, err = i.Get([]testHandler{})
+	require.NoError(t, err)
+	require.Len(t, v, 1, "explicit binding of []testHandler takes precedence")
+	v, err = i.Get([]fmt.Stringer{})
+	require.NoError(t, err)
+	require.Empty(t, v)
+	v, err = i.Get([]testHandlerA{})
+	require.NoError(t, err)
+	require.Equal(t, []testHandlerA{{}}, v)
+
+	c := SafeNew()
+	c.SetCollectBindings(true)
+	require.NoError(t, c.Bind(testHandlerA{}, &testHandlerB{}, Sequence([]testHandlerA{{}})))
+	v, err = c.Get([]testHandler{})
+	require.NoError(t, err)
+	handled := []string{}
+	for _, h := range v.([]testHandler) {
+		handled = append(handled, h.Handle())
+	}
+	require.Equal(t, []string{"a", "b", "a"}, handled)
+}
+
+func TestCachedAnnotation(t *testing.T) {
+	var calls int32
+	i := SafeNew()
+	require.NoError(t, i.Bind(Cached(200*time.Millisecond, func() int {
+		return int(atomic.AddInt32(&calls, 1))
+	})))
+	for j := 0; j < 3; j++ {
+		v, err := i.Get(0)
+		require.NoError(t, err)
+		require.Equal(t, 1, v)
+	}
+	time.Sleep(250 * time.Millisecond)
+	wg := sync.WaitGroup{}
+	for j := 0; j < 5; j++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			v, err := i.Get(0)
+			require.NoError(t, err)
+			require.Equal(t, 2, v)
+		}()
+	}
+	wg.Wait()
+	require.Equal(t, int32(2), atomic.LoadInt32(&calls))
+
+	require.EqualError(t, i.Bind(Singleton(Cached(time.Second, func() string { return "" }))), "Cached() providers can not be singletons")
+	require.EqualError(t, i.Bind(Cached(time.Second, Singleton(func() string { return "" }))), "Singleton() providers can not be cached")
+	require.EqualError(t, i.Bind(Cached(time.Second, "")), "only providers can be cached")
+}
+
+type testScopeRequest struct{}
+
+func TestValidateScopes(t *testing.T) {
+	parent := SafeNew()
+	require.NoError(t, parent.Bind(Singleton(func(*testScopeRequest) string { return "captive" })))
+	require.NoError(t, parent.Bind(func(float64) int { return 0 }))
+	require.NoError(t, parent.ValidateScopes())
+
+	child := parent.ChildWithOptions(ShareSingletons(true))
+	require.NoError(t, child.Bind(&testScopeRequest{}, 1.0))
+	require.NoError(t, child.Bind(Singleton(func(*testScopeRequest) bool { return true })))
+	require.NoError(t, child.Bind(func(*testScopeRequest) []byte { return nil }))
+	err := child.ValidateScopes()
+	require.EqualError(t, err, "bool (Singleton) requires *inject.testScopeRequest, which is only bound in a shorter-lived child injector; "+
+		"string (Singleton) requires *inject.testScopeRequest, which is only bound in a shorter-lived child injector; "+
+		"int (Provider) requires float64, which is only bound in a shorter-lived child injector")
+
+	child = parent.Child()
+	require.NoError(t, child.Bind(Singleton(func(*testScopeRequest) bool { return true }), &testScopeRequest{}))
+	require.Len(t, child.ValidateScopes(), 1)
+}
+
+func TestResolutionCache(t *testing.T) {
+	root := SafeNew()
+	tenant := SafeNew()
+	request := root.Child().ChildWithOptions(WithParents(tenant))
+	key := Key{Type: reflect.TypeOf((*testHandler)(nil)).Elem()}
+	require.False(t, request.Has(key))
+
+	require.NoError(t, root.Bind(testHandlerA{}))
+	binding, owner, err := request.resolveOwner(key)
+	require.NoError(t, err)
+	require.Equal(t, root, owner)
+	cached, _, _ := request.resolveOwner(key)
+	require.True(t, binding == cached, "resolution should be cached")
+
+	require.NoError(t, root.BindTo((*testHandler)(nil), &testHandlerB{}))
+	v, err := request.GetKey(key)
+	require.NoError(t, err)
+	require.Equal(t, "b", v.(testHandler).Handle())
+
+	require.False(t, request.Has(""))
+	require.NoError(t, tenant.Bind("tenant"))
+	require.True(t, request.Has(""))
+
+	require.False(t, request.Has(0))
+	root.AddResolver(func(key Key) (*Binding, error) {
+		return &Binding{Provides: key.Type, Build: func() (interface{}, error) { return 1, nil }}, nil
+	})
+	require.True(t, request.Has(0))
+}
+
+type testContextKey struct{}
+
+func TestContext(t *testing.T) {
+	app := SafeNew()
+	require.NoError(t, app.Bind(func(ctx context.Context) string {
+		v, _ := ctx.Value(testContextKey{}).(string)
+		return v
+	}))
+	v, err := app.Get((*context.Context)(nil))
+	require.NoError(t, err)
+	require.Equal(t, context.Background(), v)
+
+	request := app.Child()
+	request.SetContext(context.WithValue(context.Background(), testContextKey{}, "request"))
+	require.NoError(t, request.Bind(func(ctx context.Context) int {
+		return len(ctx.Value(testContextKey{}).(string))
+	}))
+	_, err = request.Call(func(s string, n int, ctx context.Context) {
+		require.Equal(t, "", s, "providers bound in the parent see its context")
+		require.Equal(t, len("request"), n)
+		require.Equal(t, "request", ctx.Value(testContextKey{}))
+	})
+	require.NoError(t, err)
+
+	call := context.WithValue(context.Background(), testContextKey{}, "call")
+	_, err = request.CallContext(call, func(ctx context.Context, n int) {
+		require.Equal(t, "call", ctx.Value(testContextKey{}))
+		require.Equal(t, len("request"), n)
+	})
+	require.NoError(t, err)
+
+	err = app.Bind(Singleton(func() context.Context { return call }))
+	require.EqualError(t, err, "context.Context providers can not be singletons")
+	require.NoError(t, request.Bind(Singleton(func(ctx context.Context) bool { return true })))
+	require.EqualError(t, request.ValidateScopes(), "bool (Singleton) requires context.Context, which would be captured by the first value built")
+}
+
+func TestWithParents(t *testing.T) {
+	app := SafeNew()
+	require.NoError(t, app.Bind("app", 1))
+	tenant := SafeNew()
+	require.NoError(t, tenant.Bind("tenant", 2.0, Singleton(func(n float64) int64 { return int64(n) })))
+	request := app.ChildWithOptions(WithParents(tenant))
+	require.NoError(t, request.Bind(true))
+	_, err := request.Call(func(s string, n int, f float64, b bool, i int64) {
+		require.Equal(t, "app", s)
+		require.Equal(t, 1, n)
+		require.Equal(t, 2.0, f)
+		require.True(t, b)
+		require.Equal(t, int64(2), i)
+	})
+	require.NoError(t, err)
+	require.False(t, app.Has(2.0))
+	_, err = request.Get(uint(0))
+	require.EqualError(t, err, "unbound type uint")
+}
+
+type testSafeModule struct{ err error }
+
+func (m *testSafeModule) Configure(binder SafeBinder) error {
+	if err := binder.Bind("first"); err != nil {
+		return err
+	}
+	// Handle the conflict gracefully rather than panicking.
+	m.err = binder.Bind("second")
+	return nil
+}
+
+func TestSafeModule(t *testing.T) {
+	module := &testSafeModule{}
+	i := SafeNew()
+	require.NoError(t, i.Install(module))
+	require.EqualError(t, module.err, "string is already bound")
+	v, err := i.Get("")
+	require.NoError(t, err)
+	require.Equal(t, "first", v)
+}
+
+func TestImplementations(t *testing.T) {
+	built := false
+	i := SafeNew()
+	require.NoError(t, i.Bind(testHandlerA{}, 1))
+	c := i.Child()
+	require.NoError(t, c.Bind(func() *testHandlerB {
+		built = true
+		return &testHandlerB{}
+	}, Sequence([]testHandler{testHandlerA{}})))
+	require.NoError(t, c.Bind(testHandlerA{}, Name("named")))
+	require.Equal(t, []reflect.Type{reflect.TypeOf(testHandlerA{}), reflect.TypeOf(&testHandlerB{})},
+		c.Implementations((*testHandler)(nil)))
+	require.False(t, built)
+	require.Equal(t, []reflect.Type{reflect.TypeOf(testHandlerA{})}, i.Implementations((*testHandler)(nil)))
+	require.Nil(t, i.Implementations(0))
+}
+
+func TestBindFlagged(t *testing.T) {
+	enabled := int32(0)
+	flag := func() bool { return atomic.LoadInt32(&enabled) == 1 }
+	builtB := 0
+	i := SafeNew()
+	require.NoError(t, i.BindFlagged((*testHandler)(nil), flag, Singleton(func() *testHandlerB {
+		builtB++
+		return &testHandlerB{}
+	}), testHandlerA{}))
+
+	v, err := i.Get((*testHandler)(nil))
+	require.NoError(t, err)
+	require.Equal(t, "a", v.(testHandler).Handle())
+	require.Equal(t, 0, builtB)
+	atomic.StoreInt32(&enabled, 1)
+	for j := 0; j < 2; j++ {
+		v, err = i.Get((*testHandler)(nil))
+		require.NoError(t, err)
+		require.Equal(t, "b", v.(testHandler).Handle())
+	}
+	require.Equal(t, 1, builtB)
+
+	require.NoError(t, i.BindFlagged((*testHandler)(nil), flag, testHandlerA{}, &testHandlerB{}, Name("named")))
+	v, err = i.GetKey(Key{Type: reflect.TypeOf((*testHandler)(nil)).Elem(), Name: "named"})
+	require.NoError(t, err)
+	require.Equal(t, testHandlerA{}, v)
+
+	err = i.BindFlagged((*testHandler)(nil), flag, testHandlerA{}, 1, Name("invalid"))
+	require.EqualError(t, err, "flagged implementation int is not assignable to inject.testHandler")
+}
+
+// testHandlerProxy is as generated by injectproxy.
+type testHandlerProxy struct{ handler ProxyHandler }
+
+func (p testHandlerProxy) Handle() string {
+	out := p.handler("Handle")
+	r0, _ := out[0].(string)
+	return r0
+}
+
+func TestIntercept(t *testing.T) {
+	i := SafeNew()
+	passThrough := func(call CallInfo, next Invoke) []interface{} { return next(call.Args) }
+	err := i.Intercept((*testHandler)(nil), passThrough)
+	require.EqualError(t, err, "no proxy is registered for inject.testHandler; generate one with injectproxy")
+	require.EqualError(t, i.Intercept(0, passThrough), "Intercept() requires an interface but got int")
+
+	t.Cleanup(RegisterProxy((*testHandler)(nil), func(handler ProxyHandler) interface{} { return testHandlerProxy{handler} }))
+	require.EqualError(t, i.Intercept((*testHandler)(nil), nil), "Intercept() requires an interceptor for inject.testHandler")
+	calls := []string{}
+	require.NoError(t, i.Intercept((*testHandler)(nil), func(call CallInfo, next Invoke) []interface{} {
+		calls = append(calls, fmt.Sprintf("%s.%s(%v) on %T", call.Interface, call.Method, call.Args, call.Target))
+		out := next(call.Args)
+		return []interface{}{"outer " + out[0].(string)}
+	}))
+	c := i.Child()
+	require.NoError(t, c.Intercept((*testHandler)(nil), func(call CallInfo, next Invoke) []interface{} {
+		return []interface{}{"inner " + next(call.Args)[0].(string)}
+	}))
+	require.NoError(t, c.Bind(testHandlerA{}))
+
+	v, err := c.Get((*testHandler)(nil))
+	require.NoError(t, err)
+	require.Equal(t, "outer inner a", v.(testHandler).Handle())
+	require.Equal(t, []string{"inject.testHandler.Handle([]) on inject.testHandlerProxy"}, calls)
+	// Concrete types are not proxied.
+	v, err = c.Get(testHandlerA{})
+	require.NoError(t, err)
+	require.Equal(t, "a", v.(testHandler).Handle())
+}
+
+func TestGetAll(t *testing.T) {
+	i := SafeNew()
+	require.NoError(t, i.Bind(testHandlerA{}, 1))
+	require.NoError(t, i.Bind(&testHandlerB{}, Name("named")))
+	c := i.Child()
+	require.NoError(t, c.Bind(func() *testHandlerB { return &testHandlerB{} }))
+	require.NoError(t, c.Bind(testHandlerA{}, Name("other")))
+
+	values, err := c.GetAll((*testHandler)(nil))
+	require.NoError(t, err)
+	require.Equal(t, []interface{}{testHandlerA{}, testHandlerA{}, &testHandlerB{}, &testHandlerB{}}, values)
+	values, err = i.GetAll((*testHandler)(nil))
+	require.NoError(t, err)
+	require.Len(t, values, 2)
+	_, err = i.GetAll(0)
+	require.EqualError(t, err, "GetAll() requires an interface but got int")
+
+	require.NoError(t, c.Bind(func() (*testHandlerB, error) { return nil, fmt.Errorf("failed") }, Name("broken")))
+	_, err = c.GetAll((*testHandler)(nil))
+	require.Error(t, err)
+	require.Contains(t, err.Error(), "broken")
+}
+
+func TestDynamicInjection(t *testing.T) {
+	i := SafeNew()
+	called := 0
+	i.Bind(func() *string {
+		called++
+		s := new(string)
+		*s = fmt.Sprintf("hello:%d", called)
+		return s
+	})
+	p := new(string)
+	a, err := i.Get(p)
+	require.NoError(t, err)
+	b, err := i.Get(p)
+	require.NoError(t, err)
+	require.NotEqual(t, a, b)
+	require.Equal(t, 2, called)
+}
+
+func TestSequenceAnnotation(t *testing.T) {
+	i := SafeNew()
+	i.Bind(Sequence([]int{1}))
+	i.Bind(Sequence([]int{2}))
+	i.Bind(Sequence(Singleton(func() []int { return []int{3} })))
+	v, err := i.Get([]int{})
+	require.NoError(t, err)
+	require.Equal(t, []int{1, 2, 3}, v)
+}
+
+func TestMappingAnnotation(t *testing.T) {
+	i := SafeNew()
+	i.Bind(Mapping(map[string]int{"one": 1}))
+	i.Bind(Mapping(map[string]int{"two": 2}))
+	i.Bind(Mapping(func() map[string]int { return map[string]int{"three": 3} }))
+	v, err := i.Get(map[string]int{})
+	require.NoError(t, err)
+	require.Equal(t, map[string]int{"one": 1, "two": 2, "three": 3}, v)
+	called := false
+	i.Call(func(m map[string]int) {
+		called = true
+		require.Equal(t, map[string]int{"one": 1, "two": 2, "three": 3}, m)
+	})
+	require.True(t, called)
+}
+
+func TestLiteral(t *testing.T) {
+	i := SafeNew()
+	buf := bytes.Buffer{}
+	i.Bind(Literal(buf.WriteString))
+	i.Call(func(write func(string) (int, error)) {
+		write("hello world")
+	})
+	require.Equal(t, "hello world", buf.String())
+}
+
+type UserName string
+
+func TestPseudoBoundValues(t *testing.T) {
+	i := SafeNew()
+	i.Bind(UserName("bob"))
+	name := ""
+	i.Call(func(user UserName) {
+		name = string(user)
+	})
+	require.Equal(t, "bob", name)
+}
+
+type myModule struct{}
+
+func (m *myModule) ProvideString(i int) string { return fmt.Sprintf("hello:%d", i) }
+
+func TestModule(t *testing.T) {
+	i := SafeNew()
+	err := i.Bind(123)
+	require.NoError(t, err)
+	err = i.Install(&myModule{})
+	require.NoError(t, err)
+	actual, err := i.Get("")
+	require.NoError(t, err)
+	require.Equal(t, "hello:123", actual)
+}
+
+func TestCallError(t *testing.T) {
+	f := func() error {
+		return fmt.Errorf("failed")
+	}
+	i := SafeNew()
+	_, err := i.Call(f)
+	require.Error(t, err)
+}
+
+type notQuiteStringer int
+
+func (n notQuiteStringer) String() string { return fmt.Sprintf("%d", n) }
+
+type notQuiteAnotherStringer float32
+
+func (n notQuiteAnotherStringer) String() string { return fmt.Sprintf("%f", n) }
+
+func TestInterfaceConversion(t *testing.T) {
+	f := func(s fmt.Stringer) error {
+		return nil
+	}
+	i := SafeNew()
+	i.Bind(notQuiteStringer(10))
+	_, err := i.Call(f)
+	require.NoError(t, err)
+}
+
+func TestSliceInterfaceConversion(t *testing.T) {
+	expected := []fmt.Stringer{notQuiteStringer(10), notQuiteAnotherStringer(20)}
+	actual := []fmt.Stringer{}
+	f := func(s []fmt.Stringer) error {
+		actual = s
+		return nil
+	}
+	i := SafeNew()
+	i.Bind(Sequence([]notQuiteStringer{10}))
+	i.Bind(Sequence([]notQuiteAnotherStringer{20}))
+	_, err := i.Call(f)
+	require.NoError(t, err)
+	require.Equal(t, expected, actual)
+}
+
+func TestMapValueInterfaceConversion(t *testing.T) {
+	expected := map[string]fmt.Stringer{"a": notQuiteStringer(10), "b": notQuiteAnotherStringer(20)}
+	actual := map[string]fmt.Stringer{}
+	f := func(s map[string]fmt.Stringer) error {
+		actual = s
+		return nil
+	}
+	i := SafeNew()
+	i.Bind(Mapping(map[string]notQuiteStringer{"a": notQuiteStringer(10)}))
+	i.Bind(Mapping(map[string]notQuiteAnotherStringer{"b": notQuiteAnotherStringer(20)}))
+	_, err := i.Call(f)
+	require.NoError(t, err)
+	require.Equal(t, expected, actual)
+}
+
+func TestSliceIsNotImplicitlyProvided(t *testing.T) {
+	f := func(s []string) {}
+	i := SafeNew()
+	_, err := i.Call(f)
+	require.Error(t, err)
+}
+
+func TestMappingIsNotImplicitlyProvided(t *testing.T) {
+	f := func(s map[string]string) {}
+	i := SafeNew()
+	_, err := i.Call(f)
+	require.Error(t, err)
+}
+
+func TestIs(t *testing.T) {
+	require.True(t, Sequence([]int{1, 2}).Is(&sequenceType{}))
+}
+
+func TestDuplicateNamedBindErrors(t *testing.T) {
+	type Named string
+
+	i := SafeNew()
+	err := i.Bind(Named("alec"))
+	require.NoError(t, err)
+	err = i.Bind(Named("bob"))
+	require.Error(t, err)
+}
+
+func TestValidate(t *testing.T) {
+	i := SafeNew()
+
+	err := i.Validate(func(string) {})
+	require.Error(t, err)
+
+	i.Bind(func(int) string { return "hello" })
+	err = i.Validate(func(string) {})
+	require.Error(t, err)
+	i.Bind(10)
+
+	// Verify that Validate doesn't call anything.
+	var actual string
+	err = i.Validate(func(s string) { actual = s })
+	require.NoError(t, err)
+	require.Equal(t, "", actual)
+}
+
+type testModuleA struct {
+	param int
+}
+
+func (t *testModuleA) ProvideInt(string) int { return 0 }
+
+type testModuleB struct{}
+
+func (t *testModuleB) ProvideString(int) string { return "" }
+
+func TestProviderCycle(t *testing.T) {
+	i := SafeNew()
+	i.Install(&testModuleA{})
+	i.Install(&testModuleB{})
+	_, err := i.Get(int(0))
+	require.Error(t, err)
+}
+
+func TestInstallIdenticalDuplicateModule(t *testing.T) {
+	i := SafeNew()
+	err := i.Install(&testModuleA{})
+	require.NoError(t, err)
+	err = i.Install(&testModuleA{})
+	require.NoError(t, err)
+}
+
+func TestInstallDifferingDuplicateModule(t *testing.T) {
+	i := SafeNew()
+	err := i.Install(&testModuleA{param: 1})
+	require.NoError(t, err)
+	err = i.Install(&testModuleA{param: 2})
+	require.Error(t, err)
+}
+
+type testConfigurableModuleA struct{}
+
+func (t *testConfigurableModuleA) Configure(binder Binder) error {
+	binder.Bind(10)
+	return nil
+}
+
+type testConfigurableModuleB struct{}
+
+func (t *testConfigurableModuleB) Configure(binder Binder) error {
+	binder.Install(&testConfigurableModuleA{})
+	return nil
+}
+
+func TestInstallConfigurableModule(t *testing.T) {
+	i := SafeNew()
+	i.Install(&testConfigurableModuleB{})
+	v, err := i.Get(0)
+	require.NoError(t, err)
+	require.Equal(t, 10, v.(int))
+}
+
+type testModuleParam struct{ param int }
+
+func (t *testModuleParam) ProvideInt() int { return t.param }
+
+func TestInstallNewZeroModuleKeepsExisting(t *testing.T) {
+	i := SafeNew()
+	err := i.Install(&testModuleParam{param: 123})
+	require.NoError(t, err)
+	err = i.Install(&testModuleParam{})
+	require.NoError(t, err)
+	v, err := i.Get(0)
+	require.NoError(t, err)
+	require.Equal(t, 123, v)
+}
 
 func TestInstallNewNonZeroModuleOverwritesExisting(t *testing.T) {
 	i := SafeNew()
-	err := i.Install(&testModuleParam{})
+	err := i.Install(&testModuleParam{})
+	require.NoError(t, err)
+	err = i.Install(&testModuleParam{param: 123})
+	require.NoError(t, err)
+	v, err := i.Get(0)
+	require.NoError(t, err)
+	require.Equal(t, 123, v)
+}
+
+func TestWriteMermaid(t *testing.T) {
+	i := SafeNew()
+	i.Bind(func() int { return 123 })
+	i.Bind(func(n int) string { return fmt.Sprintf("hello:%d", n) })
+	w := &bytes.Buffer{}
+	err := i.WriteMermaid(w)
+	require.NoError(t, err)
+	require.Equal(t, `flowchart LR
+  n0["*inject.SafeInjector"]
+  n1["inject.SafeBinder"]
+  n2["int"]
+  n3["string"]
+  n3 --> n2
+`, w.String())
+}
+
+type testInjectedFieldModule struct {
+	Prefix string
+	Count  int `inject:""`
+}
+
+func (t *testInjectedFieldModule) ProvideString() string {
+	return fmt.Sprintf("%s:%d", t.Prefix, t.Count)
+}
+
+func TestInstallInjectsModuleFields(t *testing.T) {
+	i := SafeNew()
+	err := i.Bind(123)
+	require.NoError(t, err)
+	err = i.Install(&testInjectedFieldModule{Prefix: "count"})
+	require.NoError(t, err)
+	// Duplicates are compared without their injected fields.
+	err = i.Install(&testInjectedFieldModule{Prefix: "count"})
+	require.NoError(t, err)
+	v, err := i.Get("")
+	require.NoError(t, err)
+	require.Equal(t, "count:123", v)
+}
+
+func TestInstallInjectedFieldUnbound(t *testing.T) {
+	i := SafeNew()
+	err := i.Install(&testInjectedFieldModule{})
+	require.Error(t, err)
+}
+
+type testValueModule struct {
+	Count int `inject:""`
+	name  string
+}
+
+func (m testValueModule) ProvideName() string { return m.name }
+
+func (m *testValueModule) ProvideCount() float64 { return float64(m.Count) }
+
+func (m *testValueModule) Configure(binder Binder) error {
+	binder.Bind(int32(1))
+	return nil
+}
+
+func TestInstallValueModule(t *testing.T) {
+	i := SafeNew()
+	require.NoError(t, i.Bind(123))
+	require.NoError(t, i.Install(testValueModule{name: "value"}))
+	v, err := i.Get("")
+	require.NoError(t, err)
+	require.Equal(t, "value", v)
+	v, err = i.Get(1.0)
+	require.NoError(t, err)
+	require.Equal(t, 123.0, v)
+	v, err = i.Get(int32(0))
+	require.NoError(t, err)
+	require.Equal(t, int32(1), v)
+	require.NoError(t, i.Install(testValueModule{name: "value"}))
+	require.Error(t, i.Install(&testValueModule{name: "other"}))
+}
+
+func TestResetSingleton(t *testing.T) {
+	i := SafeNew()
+	calls := 0
+	i.Bind(Singleton(func() string {
+		calls++
+		return fmt.Sprintf("hello:%d", calls)
+	}))
+	i.Bind(Singleton(func() int { return 123 }))
+	v, err := i.Get("")
+	require.NoError(t, err)
+	require.Equal(t, "hello:1", v)
+	err = i.ResetSingleton("")
+	require.NoError(t, err)
+	v, err = i.Get("")
+	require.NoError(t, err)
+	require.Equal(t, "hello:2", v)
+	i.ResetAllSingletons()
+	v, err = i.Get("")
+	require.NoError(t, err)
+	require.Equal(t, "hello:3", v)
+	err = i.ResetSingleton(1.0)
+	require.Error(t, err)
+}
+
+func TestResetSingletonCloses(t *testing.T) {
+	closed := []string{}
+	builds := 0
+	i := SafeNew()
+	require.NoError(t, i.Bind(Singleton(func(cleanup Cleanup) *testCloser {
+		builds++
+		name := fmt.Sprintf("conn%d", builds)
+		cleanup(func() { closed = append(closed, "cleanup "+name) })
+		return &testCloser{name: name, closed: &closed}
+	})))
+	_, err := i.Get(&testCloser{})
+	require.NoError(t, err)
+	require.NoError(t, i.ResetSingleton(&testCloser{}))
+	require.Equal(t, []string{"conn1", "cleanup conn1"}, closed)
+
+	_, err = i.Get(&testCloser{})
+	require.NoError(t, err)
+	require.NoError(t, i.ResetAllSingletons())
+	_, err = i.Get(&testCloser{})
+	require.NoError(t, err)
+	require.NoError(t, i.Close())
+	require.Equal(t, []string{"conn1", "cleanup conn1", "conn2", "cleanup conn2", "conn3", "cleanup conn3"}, closed)
+}
+
+func TestInjectorResetAllSingletonsPanics(t *testing.T) {
+	closed := []string{}
+	i := New()
+	i.Bind(Singleton(func() *testCloser {
+		return &testCloser{name: "conn", closed: &closed, err: fmt.Errorf("failed")}
+	}))
+	i.Get(reflect.TypeOf(&testCloser{}))
+	require.PanicsWithError(t, "failed to close *inject.testCloser: failed", func() { i.ResetAllSingletons() })
+	require.Equal(t, []string{"conn"}, closed)
+}
+
+func TestCleanupBelongsToSingletonBuild(t *testing.T) {
+	closed := []string{}
+	var later Cleanup
+	i := SafeNew()
+	i.SetParallelism(4)
+	// Dependencies built concurrently register their cleanups with the singleton requiring them.
+	require.NoError(t, i.Bind(1.0, func(cleanup Cleanup) int {
+		cleanup(func() { closed = append(closed, "int") })
+		return 1
+	}))
+	require.NoError(t, i.Bind(Singleton(func(n int, f float64, cleanup Cleanup) string {
+		later = cleanup
+		return ""
+	})))
+	_, err := i.Get("")
+	require.NoError(t, err)
+	// A Cleanup used once the build has finished still belongs to the singleton it was built for.
+	done := make(chan struct{})
+	go func() {
+		later(func() { closed = append(closed, "later") })
+		close(done)
+	}()
+	<-done
+	require.NoError(t, i.ResetSingleton(""))
+	require.Equal(t, []string{"later", "int"}, closed)
+	require.NoError(t, i.Close())
+	require.Equal(t, []string{"later", "int"}, closed)
+}
+
+func TestSingletonErrorIsNotCached(t *testing.T) {
+	i := SafeNew()
+	calls := 0
+	i.Bind(Singleton(func() (string, error) {
+		calls++
+		if calls == 1 {
+			return "", fmt.Errorf("failed")
+		}
+		return "hello", nil
+	}))
+	_, err := i.Get("")
+	require.Error(t, err)
+	v, err := i.Get("")
+	require.NoError(t, err)
+	require.Equal(t, "hello", v)
+}
+
+func TestInstallFunctionModules(t *testing.T) {
+	i := SafeNew()
+	calls := 0
+	err := i.Install(
+		func() int {
+			calls++
+			return 123
+		},
+		Providers(
+			func(n int) string { return fmt.Sprintf("hello:%d", n) },
+			Sequence([]int{1, 2}),
+		),
+	)
+	require.NoError(t, err)
+	v, err := i.Get("")
+	require.NoError(t, err)
+	require.Equal(t, "hello:123", v)
+	_, err = i.Get(1)
+	require.NoError(t, err)
+	require.Equal(t, 1, calls)
+	v, err = i.Get([]int{})
+	require.NoError(t, err)
+	require.Equal(t, []int{1, 2}, v)
+}
+
+func TestSingletonConcurrentConstruction(t *testing.T) {
+	i := SafeNew()
+	calls := int32(0)
+	release := make(chan struct{})
+	i.Bind(Singleton(func() string {
+		atomic.AddInt32(&calls, 1)
+		<-release
+		return "hello"
+	}))
+	wg := sync.WaitGroup{}
+	errs := make(chan error, 10)
+	for j := 0; j < 10; j++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			v, err := i.Get("")
+			if err == nil && v != "hello" {
+				err = fmt.Errorf("unexpected value %v", v)
+			}
+			errs <- err
+		}()
+	}
+	time.Sleep(time.Millisecond * 10)
+	close(release)
+	wg.Wait()
+	close(errs)
+	for err := range errs {
+		require.NoError(t, err)
+	}
+	require.Equal(t, int32(1), atomic.LoadInt32(&calls))
+}
+
+func TestSingletonRuntimeRecursion(t *testing.T) {
+	i := SafeNew()
+	require.NoError(t, i.Bind(Singleton(func(s *SafeInjector) (string, error) {
+		_, err := s.Get("")
+		return "", err
+	})))
+	done := make(chan error)
+	go func() {
+		_, err := i.Get("")
+		done <- err
+	}()
+	select {
+	case err := <-done:
+		require.EqualError(t, err, "building string: provider github.com/alecthomas/inject.TestSingletonRuntimeRecursion.func1 failed: "+
+			"recursive binding string: it was requested again while being built")
+	case <-time.After(time.Second * 5):
+		t.Fatal("recursive singleton blocked")
+	}
+
+	// Arguments built concurrently are part of the same resolution.
+	i = SafeNew()
+	i.SetParallelism(4)
+	require.NoError(t, i.Bind(Singleton(func(n int, f float64) string { return "" })))
+	require.NoError(t, i.Bind(1.0, func(s *SafeInjector) (int, error) {
+		_, err := s.Get("")
+		return 0, err
+	}))
+	go func() {
+		_, err := i.Get("")
+		done <- err
+	}()
+	select {
+	case err := <-done:
+		require.Error(t, err)
+		require.EqualError(t, err, "building string -> int: provider github.com/alecthomas/inject.TestSingletonRuntimeRecursion.func4 failed: "+
+			"recursive binding string: it was requested again while being built")
+	case <-time.After(time.Second * 5):
+		t.Fatal("recursive singleton blocked")
+	}
+}
+
+func TestTransientRuntimeRecursion(t *testing.T) {
+	i := SafeNew()
+	require.NoError(t, i.Bind(func(s *SafeInjector) (int, error) {
+		_, err := s.Get(0)
+		return 0, err
+	}))
+	_, err := i.Get(0)
+	require.Error(t, err)
+	require.Contains(t, err.Error(), "recursive binding int: it was requested again while being built")
+}
+
+func TestResolvedRecursion(t *testing.T) {
+	i := SafeNew()
+	require.NoError(t, i.Bind(func(s string) int { return len(s) }))
+	// Cycles through bindings that are only resolved at runtime can't be found when binding.
+	binding, err := Provider(func(n int) string { return "" }).Build(i)
+	require.NoError(t, err)
+	i.AddResolver(func(key Key) (*Binding, error) {
+		if key.Type != reflect.TypeOf("") {
+			return nil, nil
+		}
+		return binding, nil
+	})
+	_, err = i.Get(0)
+	require.EqualError(t, err, "couldn't inject argument 1 of func(string) int: couldn't inject argument 1 of func(int) string: "+
+		"recursive binding int: it was requested again while being built")
+}
+
+func TestInjectedInjectorOutlivesBuild(t *testing.T) {
+	type holder struct{ injector *SafeInjector }
+	i := SafeNew()
+	require.NoError(t, i.Bind(Singleton(func(s *SafeInjector) *holder { return &holder{s} })))
+	v, err := i.Get(&holder{})
+	require.NoError(t, err)
+	// Requests made once the build has finished are not part of it.
+	again, err := v.(*holder).injector.Get(&holder{})
+	require.NoError(t, err)
+	require.Equal(t, v, again)
+}
+
+func TestMerge(t *testing.T) {
+	lib := SafeNew()
+	lib.Bind(func(n int) string { return fmt.Sprintf("hello:%d", n) })
+	lib.Bind(Sequence([]int{1}))
+	lib.Bind(1.5)
+
+	app := SafeNew()
+	app.Bind(123)
+	app.Bind(Sequence([]int{2}))
+	app.Bind(2.5)
+
+	err := app.Merge(lib, ConflictError)
+	require.Error(t, err)
+
+	app = SafeNew()
+	app.Bind(123)
+	app.Bind(Sequence([]int{2}))
+	app.Bind(2.5)
+	err = app.Merge(lib, ConflictKeep)
+	require.NoError(t, err)
+	v, err := app.Get("")
+	require.NoError(t, err)
+	require.Equal(t, "hello:123", v)
+	v, err = app.Get([]int{})
+	require.NoError(t, err)
+	require.Equal(t, []int{2, 1}, v)
+	v, err = app.Get(0.0)
+	require.NoError(t, err)
+	require.Equal(t, 2.5, v)
+
+	err = app.Merge(lib, ConflictReplace)
+	require.NoError(t, err)
+	v, err = app.Get(0.0)
+	require.NoError(t, err)
+	require.Equal(t, 1.5, v)
+}
+
+type requestID string
+
+func TestCallWith(t *testing.T) {
+	i := SafeNew()
+	i.Bind("hello")
+	i.Bind(requestID("bound"))
+	var actual []interface{}
+	_, err := i.CallWith(func(s string, id requestID, st fmt.Stringer) {
+		actual = []interface{}{s, id, st}
+	}, requestID("extra"), stringer("stringer"))
+	require.NoError(t, err)
+	require.Equal(t, []interface{}{"hello", requestID("extra"), stringer("stringer")}, actual)
+}
+
+type testNamedAnnotation struct {
+	name string
+	v    interface{}
+}
+
+func (n *testNamedAnnotation) Build(i *SafeInjector) (*Binding, error) {
+	binding, err := Annotate(n.v).Build(i)
+	if err != nil {
+		return nil, err
+	}
+	binding.Name = n.name
+	return binding, nil
+}
+
+func (n *testNamedAnnotation) Is(annotation Annotation) bool { return false }
+
+func TestGetKey(t *testing.T) {
+	i := SafeNew()
+	err := i.Bind("default", &testNamedAnnotation{"replica", "replica"})
+	require.NoError(t, err)
+	v, err := i.GetKey(Key{Type: reflect.TypeOf("")})
+	require.NoError(t, err)
+	require.Equal(t, "default", v)
+	v, err = i.GetKey(Key{Type: reflect.TypeOf(""), Name: "replica"})
+	require.NoError(t, err)
+	require.Equal(t, "replica", v)
+	_, err = i.GetKey(Key{Type: reflect.TypeOf(""), Name: "missing"})
+	require.Error(t, err)
+
+	actual := ""
+	_, err = i.CallKeyed(func(s string) { actual = s }, Key{Type: reflect.TypeOf(""), Name: "replica"})
+	require.NoError(t, err)
+	require.Equal(t, "replica", actual)
+}
+
+func TestNamedSequence(t *testing.T) {
+	i := SafeNew()
+	require.NoError(t, i.Bind(Sequence([]int{1}), Name("x")))
+	require.NoError(t, i.Bind(Sequence([]int{2}), Name("x")))
+	require.NoError(t, i.Provide("x", Sequence(func() []int { return []int{3} })))
+	require.NoError(t, i.Bind(Sequence([]int{4})))
+	require.NoError(t, i.Bind(Mapping(map[string]int{"a": 1}), Name("m")))
+	require.NoError(t, i.Bind(Mapping(map[string]int{"b": 2}), Name("m")))
+
+	v, err := i.GetKey(Key{Type: reflect.TypeOf([]int{}), Name: "x"})
+	require.NoError(t, err)
+	require.Equal(t, []int{1, 2, 3}, v)
+	v, err = i.Get([]int{})
+	require.NoError(t, err)
+	require.Equal(t, []int{4}, v)
+	v, err = i.GetKey(Key{Type: reflect.TypeOf(map[string]int{}), Name: "m"})
+	require.NoError(t, err)
+	require.Equal(t, map[string]int{"a": 1, "b": 2}, v)
+}
+
+func TestGetType(t *testing.T) {
+	i := SafeNew()
+	require.NoError(t, i.Bind("hello", testHandlerA{}))
+	v, err := i.GetType(reflect.TypeOf(""))
+	require.NoError(t, err)
+	require.Equal(t, "hello", v)
+	v, err = i.GetType(reflect.TypeOf((*testHandler)(nil)))
+	require.NoError(t, err)
+	require.Equal(t, testHandlerA{}, v)
+	_, err = i.GetType(reflect.TypeOf(0))
+	require.EqualError(t, err, "unbound type int")
+
+	u := New()
+	u.Bind("hello")
+	require.Equal(t, "hello", u.GetType(reflect.TypeOf("")))
+	require.Equal(t, "hello", u.Get(reflect.TypeOf("")))
+}
+
+func TestDescribe(t *testing.T) {
+	i := SafeNew()
+	err := i.Bind(Describe("the answer", func() (int, error) { return 0, fmt.Errorf("failed") }))
+	require.NoError(t, err)
+	bindings := i.Bindings()
+	require.Equal(t, "the answer", bindings[len(bindings)-1].Description)
+	_, err = i.Get(0)
+	require.EqualError(t, err, "building int (the answer): provider github.com/alecthomas/inject.TestDescribe.func1 failed: failed")
+	w := &bytes.Buffer{}
+	err = i.WriteMermaid(w)
+	require.NoError(t, err)
+	require.Contains(t, w.String(), `["int<br/>the answer"]`)
+
+	// Singleton() keeps the description of the binding it wraps.
+	i = SafeNew()
+	require.NoError(t, i.Bind(Singleton(Describe("the answer", func() int { return 42 }))))
+	bindings = i.Bindings()
+	require.Equal(t, "the answer", bindings[len(bindings)-1].Description)
+
+	// Errors of described bindings can be unwrapped.
+	i = SafeNew()
+	require.NoError(t, i.Bind(Describe("the answer", func() int { panic("no answer") })))
+	_, err = i.Get(0)
+	var perr *PanicError
+	require.True(t, errors.As(err, &perr))
+	require.Equal(t, "no answer", perr.Value)
+}
+
+func TestInstallDetectsProviderCycle(t *testing.T) {
+	i := SafeNew()
+	err := i.Install(&testModuleA{})
+	require.NoError(t, err)
+	err = i.Install(&testModuleB{})
+	require.EqualError(t, err, "module *inject.testModuleB: method ProvideString: recursive binding string -> int -> string")
+	_, err = i.Get("")
+	require.Error(t, err)
+}
+
+type testCloser struct {
+	name   string
+	closed *[]string
+	err    error
+}
+
+func (c *testCloser) Close() error {
+	*c.closed = append(*c.closed, c.name)
+	return c.err
+}
+
+type testCloserA struct{ *testCloser }
+type testCloserB struct{ *testCloser }
+
+func TestClose(t *testing.T) {
+	closed := []string{}
+	i := SafeNew()
+	i.Bind(Singleton(func() testCloserA {
+		return testCloserA{&testCloser{name: "a", closed: &closed, err: fmt.Errorf("failed")}}
+	}))
+	i.Bind(Singleton(func(testCloserA) testCloserB {
+		return testCloserB{&testCloser{name: "b", closed: &closed}}
+	}))
+	i.Bind(Singleton(func() *testCloser {
+		return &testCloser{name: "unused", closed: &closed}
+	}))
+	_, err := i.Get(testCloserB{})
+	require.NoError(t, err)
+	err = i.Close()
+	require.EqualError(t, err, "failed to close inject.testCloserA: failed")
+	require.Equal(t, []string{"b", "a"}, closed)
+	err = i.Close()
+	require.NoError(t, err)
+	require.Equal(t, []string{"b", "a"}, closed)
+}
+
+func TestFactoryMap(t *testing.T) {
+	built := []string{}
+	i := SafeNew()
+	require.NoError(t, i.Bind(Singleton(func() testHandler {
+		built = append(built, "a")
+		return testHandlerA{}
+	}), Name("a")))
+	c := i.Child()
+	require.NoError(t, c.Bind(func() (testHandler, error) {
+		built = append(built, "b")
+		return nil, fmt.Errorf("b is unavailable")
+	}, Name("b")))
+
+	v, err := c.Get(map[string]func() (testHandler, error){})
+	require.NoError(t, err)
+	factories := v.(map[string]func() (testHandler, error))
+	require.Len(t, factories, 2)
+	require.Empty(t, built)
+	h, err := factories["a"]()
+	require.NoError(t, err)
+	require.Equal(t, "a", h.Handle())
+	_, err = factories["a"]()
+	require.NoError(t, err)
+	require.Equal(t, []string{"a"}, built)
+	_, err = factories["b"]()
+	require.EqualError(t, err, `building inject.testHandler("b"): provider github.com/alecthomas/inject.TestFactoryMap.func2 failed: b is unavailable`)
+
+	v, err = i.Get(map[string]func() (testHandler, error){})
+	require.NoError(t, err)
+	require.Len(t, v, 1)
+	_, err = i.Get(map[string]func() (int, error){})
+	require.Error(t, err)
+}
+
+type testCopiedConfig struct {
+	Hosts   []string
+	Limits  map[string]int
+	Primary *string
+	Backup  *string
+	private []string
+}
+
+func TestBindCopy(t *testing.T) {
+	primary := "a"
+	config := &testCopiedConfig{
+		Hosts:   []string{"a", "b"},
+		Limits:  map[string]int{"rps": 10},
+		Primary: &primary,
+		Backup:  &primary,
+		private: []string{"shared"},
+	}
+	i := SafeNew()
+	require.NoError(t, i.BindCopy(config))
+	require.NoError(t, i.BindCopy([]int{1, 2}, Name("ports")))
+	config.Hosts[0] = "changed after binding"
+
+	v, err := i.Get(&testCopiedConfig{})
+	require.NoError(t, err)
+	first := v.(*testCopiedConfig)
+	require.Equal(t, []string{"a", "b"}, first.Hosts)
+	require.True(t, first.Primary == first.Backup)
+	first.Hosts[1] = "x"
+	first.Limits["rps"] = 0
+	*first.Primary = "x"
+
+	v, err = i.Get(&testCopiedConfig{})
+	require.NoError(t, err)
+	second := v.(*testCopiedConfig)
+	require.Equal(t, []string{"a", "b"}, second.Hosts)
+	require.Equal(t, map[string]int{"rps": 10}, second.Limits)
+	require.Equal(t, "a", *second.Primary)
+	require.Equal(t, "a", primary)
+	require.Equal(t, []string{"shared"}, second.private)
+
+	v, err = i.GetKey(Key{Type: reflect.TypeOf([]int{}), Name: "ports"})
+	require.NoError(t, err)
+	require.Equal(t, []int{1, 2}, v)
+	require.EqualError(t, i.BindCopy(nil), "can not bind a copy of nil")
+}
+
+func TestPooled(t *testing.T) {
+	built := 0
+	i := SafeNew()
+	require.NoError(t, i.Bind(Pooled(func() *bytes.Buffer {
+		built++
+		return &bytes.Buffer{}
+	})))
+	request := i.Child()
+	a, err := request.Get(&bytes.Buffer{})
+	require.NoError(t, err)
+	b, err := request.Get(&bytes.Buffer{})
+	require.NoError(t, err)
+	require.False(t, a == b)
+	require.Equal(t, 2, built)
+	a.(*bytes.Buffer).WriteString("dirty")
+	require.NoError(t, request.Close())
+	require.Equal(t, 0, a.(*bytes.Buffer).Len())
+
+	// The pool may drop values at any time, so reuse can't be asserted exactly.
+	request = i.Child()
+	_, err = request.Get(&bytes.Buffer{})
+	require.NoError(t, err)
+	require.True(t, built <= 3)
+
+	require.EqualError(t, i.Bind(Singleton(Pooled(func() int { return 0 }))), "Pooled() providers can not be singletons")
+	require.EqualError(t, i.Bind(Pooled(Singleton(func() int { return 0 }))), "Singleton() and Cached() providers can not be pooled")
+	require.EqualError(t, i.Bind(Pooled(1)), "only providers can be pooled")
+
+	// Values converted by BindTo() are still returned to the pool.
+	resets := 0
+	i = SafeNew()
+	require.NoError(t, i.BindTo(testConvertedResetCounter{}, Pooled(func() testResetCounter {
+		return testResetCounter{&resets}
+	})))
+	request = i.Child()
+	_, err = request.Get(testConvertedResetCounter{})
+	require.NoError(t, err)
+	require.NoError(t, request.Close())
+	require.Equal(t, 1, resets)
+}
+
+type testResetCounter struct{ resets *int }
+
+func (c testResetCounter) Reset() { *c.resets++ }
+
+type testConvertedResetCounter testResetCounter
+
+func (c testConvertedResetCounter) Reset() { *c.resets++ }
+
+func TestScoped(t *testing.T) {
+	closed := []string{}
+	i := SafeNew()
+	i.OnScope(func(key interface{}, scope *SafeInjector) error {
+		if key == "bad" {
+			return fmt.Errorf("unknown tenant %v", key)
+		}
+		return scope.Bind(Singleton(func() *testCloser {
+			return &testCloser{name: key.(string), closed: &closed}
+		}))
+	})
+	a, err := i.Scoped("a")
+	require.NoError(t, err)
+	again, err := i.Scoped("a")
+	require.NoError(t, err)
+	require.Equal(t, a, again)
+	b, err := i.Scoped("b")
+	require.NoError(t, err)
+	_, err = i.Scoped("bad")
+	require.EqualError(t, err, "unknown tenant bad")
+	require.Equal(t, []interface{}{"a", "b"}, i.Scopes())
+	for _, scope := range []*SafeInjector{a, b} {
+		_, err = scope.Get(&testCloser{})
+		require.NoError(t, err)
+	}
+
+	require.NoError(t, i.Evict("a"))
+	require.NoError(t, i.Evict("a"))
+	require.Equal(t, []string{"a"}, closed)
+	require.Equal(t, []interface{}{"b"}, i.Scopes())
+	a2, err := i.Scoped("a")
+	require.NoError(t, err)
+	require.NotEqual(t, a, a2)
+	_, err = a2.Get(&testCloser{})
+	require.NoError(t, err)
+
+	require.NoError(t, i.EvictAll())
+	require.Equal(t, []string{"a", "a", "b"}, closed)
+	require.Empty(t, i.Scopes())
+}
+
+func TestBindOptions(t *testing.T) {
+	i := SafeNew()
+	calls := 0
+	err := i.Bind(Singleton(func() stringer {
+		calls++
+		return stringer("replica")
+	}), Name("replica"), As((*fmt.Stringer)(nil)), Eager())
+	require.NoError(t, err)
+	require.Equal(t, 1, calls)
+	v, err := i.GetKey(Key{Type: reflect.TypeOf((*fmt.Stringer)(nil)), Name: "replica"})
+	require.NoError(t, err)
+	require.Equal(t, stringer("replica"), v)
+	_, err = i.GetKey(Key{Type: reflect.TypeOf((*fmt.Stringer)(nil))})
+	require.Error(t, err)
+
+	err = i.Bind(Singleton(func() int {
+		calls++
+		return calls
+	}), NoSingleton())
+	require.NoError(t, err)
+	a, _ := i.Get(0)
+	b, _ := i.Get(0)
+	require.NotEqual(t, a, b)
+
+	err = i.Bind(func() (string, error) { return "", fmt.Errorf("failed") }, Eager())
+	require.Error(t, err)
+}
+
+func testPanickingProvider() int { panic("boom") }
+
+func TestProviderPanicBecomesError(t *testing.T) {
+	i := SafeNew()
+	i.Bind(testPanickingProvider)
+	i.Bind(func(n int) string { return "unreachable" })
+	_, err := i.Call(func(s string) {})
+	require.Error(t, err)
+	require.Contains(t, err.Error(), "provider github.com/alecthomas/inject.testPanickingProvider of int panicked: boom")
+	_, err = i.Get(0)
+	perr, ok := err.(*PanicError)
+	require.True(t, ok)
+	require.Equal(t, "boom", perr.Value)
+	require.NotEmpty(t, perr.Stack)
+}
+
+type testPanickingModule struct{}
+
+func (testPanickingModule) Configure(binder Binder) error { panic("bad configuration") }
+
+type testConflictingModule struct{}
+
+func (testConflictingModule) Configure(binder Binder) error {
+	binder.Bind(1)
+	binder.Bind(2)
+	return nil
+}
+
+type testRegistrationPanicModule struct{}
+
+func (testRegistrationPanicModule) ProvideInt() int { return 1 }
+
+type testValidatedModule struct {
+	URI string
+}
+
+func (m *testValidatedModule) Validate() error {
+	if m.URI == "" {
+		return fmt.Errorf("URI is required")
+	}
+	return nil
+}
+
+func (m *testValidatedModule) ProvideURI() string { return m.URI }
+
+func TestValidatingModule(t *testing.T) {
+	i := SafeNew()
+	err := i.Install(testValidatedModule{})
+	require.EqualError(t, err, "module inject.testValidatedModule: Validate: URI is required")
+	require.False(t, i.Has(""))
+	require.Empty(t, i.Modules())
+
+	require.NoError(t, i.Install(&testValidatedModule{URI: "db://"}))
+	v, err := i.Get("")
+	require.NoError(t, err)
+	require.Equal(t, "db://", v)
+}
+
+var errTestBuild = errors.New("database unreachable")
+
+type testBuildErrorModule struct{}
+
+func (testBuildErrorModule) ProvideInt() (int, error) { return 0, errTestBuild }
+
+func TestBuildError(t *testing.T) {
+	i := SafeNew()
+	require.NoError(t, i.Install(testBuildErrorModule{}))
+	require.NoError(t, i.Bind(func(n int) float64 { return float64(n) }))
+	c := i.Child()
+	require.NoError(t, c.Bind(func(f float64) bool { return f > 0 }))
+
+	_, err := c.Call(func(b bool) {})
+	require.EqualError(t, err, "building bool -> float64 -> int: provider "+
+		"github.com/alecthomas/inject.testBuildErrorModule.ProvideInt failed: database unreachable")
+	require.True(t, errors.Is(err, errTestBuild))
+	var berr *BuildError
+	require.True(t, errors.As(err, &berr))
+	require.Equal(t, Key{Type: reflect.TypeOf(0)}, berr.Key)
+	require.Equal(t, "github.com/alecthomas/inject.testBuildErrorModule.ProvideInt", berr.Provider)
+	require.Equal(t, reflect.TypeOf(testBuildErrorModule{}), berr.Module)
+	require.Equal(t, []Key{{Type: reflect.TypeOf(true)}, {Type: reflect.TypeOf(0.0)}, {Type: reflect.TypeOf(0)}}, berr.Chain)
+
+	_, err = i.Get(0)
+	require.EqualError(t, err, "building int: provider "+
+		"github.com/alecthomas/inject.testBuildErrorModule.ProvideInt failed: database unreachable")
+
+	_, err = c.Call(func(injector *SafeInjector) error { return errTestBuild })
+	require.Equal(t, errTestBuild, err, "errors returned by called functions are not wrapped")
+}
+
+func TestInstallPanicBecomesError(t *testing.T) {
+	i := SafeNew()
+	err := i.Install(testPanickingModule{})
+	require.EqualError(t, err, "installing module inject.testPanickingModule panicked: bad configuration")
+	perr, ok := err.(*PanicError)
+	require.True(t, ok)
+	require.Equal(t, reflect.TypeOf(testPanickingModule{}), perr.Module)
+	require.NotEmpty(t, perr.Stack)
+
+	i.SetLogger(func(level, msg string, kv ...interface{}) {
+		if msg == "bound" {
+			panic(42)
+		}
+	})
+	err = i.Install(testRegistrationPanicModule{})
+	require.EqualError(t, err, "installing module inject.testRegistrationPanicModule panicked: 42")
+
+	// Errors panicked by the Injector passed to Configure() are returned unchanged.
+	err = SafeNew().Install(testConflictingModule{})
+	require.EqualError(t, err, "int is already bound")
+}
+
+func TestUniqueSequence(t *testing.T) {
+	i := SafeNew()
+	require.NoError(t, i.Bind(Sequence([]int{1, 2, 1})))
+	require.NoError(t, i.Bind(Unique(Sequence([]int{2, 3}))))
+	require.NoError(t, i.Bind(Sequence([]int{3, 4})))
+	v, err := i.Get([]int{})
+	require.NoError(t, err)
+	require.Equal(t, []int{1, 2, 3, 4}, v)
+
+	// Closures from the same factory share their code, so functions are never duplicates.
+	called := []string{}
+	prefix := func(p string) func() { return func() { called = append(called, p) } }
+	i = SafeNew()
+	require.NoError(t, i.Bind(Unique(Sequence([]func(){prefix("a")}))))
+	require.NoError(t, i.Bind(Sequence([]func(){prefix("b")})))
+	v, err = i.Get([]func(){})
+	require.NoError(t, err)
+	for _, f := range v.([]func()) {
+		f()
+	}
+	require.Equal(t, []string{"a", "b"}, called)
+
+	i = SafeNew()
+	require.NoError(t, i.Bind(UniqueBy(func(s string) int { return len(s) }, Sequence([]string{"a", "b", "cd"}))))
+	v, err = i.Get([]string{})
+	require.NoError(t, err)
+	require.Equal(t, []string{"a", "cd"}, v)
+
+	require.Error(t, SafeNew().Bind(Unique([]int{1})))
+	require.Error(t, SafeNew().Bind(UniqueBy(func(s int) int { return s }, Sequence([]string{"a"}))))
+}
+
+type auditInnerModule struct{}
+
+func (auditInnerModule) ProvideFloat() float64 { return 1.5 }
+
+type auditModule struct{}
+
+func (auditModule) Configure(binder Binder) error {
+	binder.Bind("configured")
+	binder.Install(auditInnerModule{})
+	return nil
+}
+
+func (auditModule) ProvideInt() int { return 1 }
+
+func TestModuleBindings(t *testing.T) {
+	i := New()
+	i.Install(&auditModule{})
+	provides := []reflect.Type{}
+	for _, binding := range i.ModuleBindings(auditModule{}) {
+		provides = append(provides, binding.Provides)
+	}
+	require.Equal(t, []reflect.Type{reflect.TypeOf(""), reflect.TypeOf(0)}, provides)
+	bindings := i.ModuleBindings(&auditInnerModule{})
+	require.Len(t, bindings, 1)
+	require.Equal(t, reflect.TypeOf(1.5), bindings[0].Provides)
+	_, err := i.Safe().ModuleBindings(struct{}{})
+	require.Error(t, err)
+}
+
+type testEnvConfig struct {
+	Port    int           `env:"INJECT_TEST_PORT" default:"8080" name:"port"`
+	Host    string        `env:"INJECT_TEST_HOST" default:"localhost"`
+	Debug   bool          `env:"INJECT_TEST_DEBUG"`
+	Timeout time.Duration `env:"INJECT_TEST_TIMEOUT" default:"5s"`
+	Ignored string
+}
+
+func TestEnvModule(t *testing.T) {
+	t.Setenv("INJECT_TEST_HOST", "example.com")
+	t.Setenv("INJECT_TEST_DEBUG", "true")
+	i := SafeNew()
+	require.NoError(t, i.Install(EnvModule(&testEnvConfig{Ignored: "kept"})))
+	v, err := i.Get(&testEnvConfig{})
+	require.NoError(t, err)
+	require.Equal(t, &testEnvConfig{
+		Port:    8080,
+		Host:    "example.com",
+		Debug:   true,
+		Timeout: 5 * time.Second,
+		Ignored: "kept",
+	}, v)
+	port, err := i.GetKey(Key{Type: reflect.TypeOf(0), Name: "port"})
+	require.NoError(t, err)
+	require.Equal(t, 8080, port)
+
+	t.Setenv("INJECT_TEST_PORT", "http")
+	err = SafeNew().Install(EnvModule(&testEnvConfig{}))
+	require.EqualError(t, err, `invalid value "http" for $INJECT_TEST_PORT (inject.testEnvConfig.Port): strconv.ParseInt: parsing "http": invalid syntax`)
+	require.Error(t, SafeNew().Install(EnvModule(testEnvConfig{})))
+}
+
+func TestInterfaceResolutionIsCachedUntilBind(t *testing.T) {
+	i := SafeNew()
+	require.NoError(t, i.Bind(stringer("x"), Name("a")))
+	_, err := i.GetKey(Key{Type: reflect.TypeOf((*fmt.Stringer)(nil)).Elem(), Name: "b"})
+	require.Error(t, err)
+	require.NoError(t, i.Bind(notQuiteStringer(1), Name("b")))
+	v, err := i.GetKey(Key{Type: reflect.TypeOf((*fmt.Stringer)(nil)).Elem(), Name: "b"})
+	require.NoError(t, err)
+	require.Equal(t, notQuiteStringer(1), v)
+}
+
+func TestOnBuild(t *testing.T) {
+	type event struct {
+		t   reflect.Type
+		v   interface{}
+		err error
+	}
+	events := []event{}
+	i := New()
+	i.OnBuild(func(t reflect.Type, v interface{}, err error) {
+		events = append(events, event{t, v, err})
+	})
+	i.Bind(Singleton(func() int { return 1 }))
+	i.Bind(func(n int) (string, error) { return "", fmt.Errorf("failed") })
+	child := i.Child()
+	child.Bind(func(n int) float64 { return float64(n) })
+
+	child.Get(reflect.TypeOf(1.0))
+	child.Get(reflect.TypeOf(1.0))
+	_, err := i.Safe().Get("")
+	require.EqualError(t, err, "building string: provider github.com/alecthomas/inject.TestOnBuild.func3 failed: failed")
+	require.Equal(t, []event{
+		{reflect.TypeOf(0), 1, nil},
+		{reflect.TypeOf(1.0), 1.0, nil},
+		{reflect.TypeOf(1.0), 1.0, nil},
+		{reflect.TypeOf(""), nil, fmt.Errorf("failed")},
+	}, events)
+}
+
+type testPrefixedHandler struct{ testHandler }
+
+func (h testPrefixedHandler) Handle() string { return "prefixed " + h.testHandler.Handle() }
+
+func TestAfterBuild(t *testing.T) {
+	i := SafeNew()
+	i.AfterBuild((*testHandler)(nil), func(v interface{}) (interface{}, error) {
+		return testPrefixedHandler{v.(testHandler)}, nil
+	})
+	i.AfterBuild(0, func(v interface{}) (interface{}, error) {
+		if v.(int) < 0 {
+			return nil, fmt.Errorf("%d is negative", v)
+		}
+		return v.(int) * 2, nil
+	})
+	require.NoError(t, i.Bind(func() testHandler { return testHandlerA{} }))
+	require.NoError(t, i.Bind(Singleton(func() testHandlerA { return testHandlerA{} })))
+	require.NoError(t, i.Bind(func() int { return 2 }))
+	require.NoError(t, i.Bind(int32(3)))
+
+	v, err := i.Get((*testHandler)(nil))
+	require.NoError(t, err)
+	require.Equal(t, "prefixed a", v.(testHandler).Handle())
+	// Processors must return a value assignable to the provided type.
+	_, err = i.Get(testHandlerA{})
+	require.EqualError(t, err, "post-processor returned inject.testPrefixedHandler for inject.testHandlerA")
+	v, err = i.Get(0)
+	require.NoError(t, err)
+	require.Equal(t, 4, v)
+	v, err = i.Get(int32(0))
+	require.NoError(t, err)
+	require.Equal(t, int32(3), v)
+
+	child := i.Child()
+	child.AfterBuild(0, func(v interface{}) (interface{}, error) { return v.(int) - 3, nil })
+	require.NoError(t, child.Override(func() int { return 1 }))
+	_, err = child.Get(0)
+	require.EqualError(t, err, "-2 is negative")
+	v, err = i.Get(0)
+	require.NoError(t, err)
+	require.Equal(t, 4, v)
+}
+
+type testObserver struct {
+	built []string
+	hits  []reflect.Type
+}
+
+func (o *testObserver) Built(t reflect.Type, duration time.Duration, err error) {
+	if err != nil {
+		o.built = append(o.built, t.String()+": "+err.Error())
+	} else {
+		o.built = append(o.built, t.String())
+	}
+}
+
+func (o *testObserver) CacheHit(t reflect.Type) { o.hits = append(o.hits, t) }
+
+func TestObserve(t *testing.T) {
+	observer := &testObserver{}
+	i := New()
+	i.Observe(observer)
+	i.Bind(Singleton(func() int { return 1 }))
+	i.Bind(func(n int) (string, error) { return "", fmt.Errorf("failed") })
+	child := i.Child()
+	child.Bind(func(n int) float64 { return float64(n) })
+
+	child.Get(reflect.TypeOf(1.0))
+	child.Get(reflect.TypeOf(1.0))
+	_, err := i.Safe().Get("")
+	require.Error(t, err)
+	require.Equal(t, []string{"int", "float64", "float64", "string: failed"}, observer.built)
+	require.Equal(t, []reflect.Type{reflect.TypeOf(0), reflect.TypeOf(0)}, observer.hits)
+}
+
+type testOutResult struct {
+	Out
+
+	Count   int
+	Label   string  `name:"label"`
+	Ratio   float64 `group:""`
+	private bool
+}
+
+func TestOutStructFieldsAreBound(t *testing.T) {
+	calls := 0
+	i := SafeNew()
+	require.NoError(t, i.Bind(Singleton(func() testOutResult {
+		calls++
+		return testOutResult{Count: 2, Label: "two", Ratio: 0.5}
+	})))
+	require.NoError(t, i.Bind(Sequence([]float64{1.5})))
+	_, err := i.Call(func(n int, ratios []float64, result testOutResult) {
+		require.Equal(t, 2, n)
+		require.Equal(t, []float64{0.5, 1.5}, ratios)
+		require.Equal(t, "two", result.Label)
+	})
+	require.NoError(t, err)
+	label, err := i.GetKey(Key{Type: reflect.TypeOf(""), Name: "label"})
+	require.NoError(t, err)
+	require.Equal(t, "two", label)
+	require.Equal(t, 1, calls)
+
+	i = SafeNew()
+	require.NoError(t, i.Bind(3))
+	require.EqualError(t, i.Bind(func() testOutResult { return testOutResult{} }),
+		"inject.testOutResult.Count: int is already bound")
+}
+
+type testInParams struct {
+	In
+
+	Count   int
+	Label   string  `name:"label"`
+	Ratio   float64 `optional:"true"`
+	Missing []int   `optional:"true"`
+}
+
+func TestInStructParameters(t *testing.T) {
+	i := SafeNew()
+	require.NoError(t, i.Bind(3))
+	require.NoError(t, i.Bind("two", Name("label")))
+	require.NoError(t, i.Bind(func(params testInParams) bool { return params.Count == 3 }))
+	_, err := i.Call(func(params testInParams, ok bool) {
+		require.Equal(t, testInParams{Count: 3, Label: "two"}, params)
+		require.True(t, ok)
+	})
+	require.NoError(t, err)
+	require.NoError(t, i.Validate(func(params testInParams) {}))
+
+	i = SafeNew()
+	require.Error(t, i.Validate(func(params testInParams) {}))
+	_, err = i.Call(func(params testInParams) {})
+	require.EqualError(t, err, "couldn't inject argument 1 of func(inject.testInParams): couldn't inject field inject.testInParams.Count: unbound type int")
+}
+
+func TestChildShareSingletons(t *testing.T) {
+	closed := []string{}
+	provider := func() *testCloser { return &testCloser{name: "shared", closed: &closed} }
+	parent := SafeNew()
+	calls := 0
+	require.NoError(t, parent.Bind(Singleton(func() int { calls++; return calls })))
+
+	a := parent.ChildWithOptions(ShareSingletons(true))
+	b := parent.ChildWithOptions(ShareSingletons(true))
+	c := parent.Child()
+	for _, child := range []*SafeInjector{a, b, c} {
+		require.NoError(t, child.Bind(Singleton(provider)))
+	}
+	av, err := a.Get(&testCloser{})
+	require.NoError(t, err)
+	bv, err := b.Get(&testCloser{})
+	require.NoError(t, err)
+	cv, err := c.Get(&testCloser{})
+	require.NoError(t, err)
+	require.True(t, av == bv)
+	require.False(t, av == cv)
+
+	// Parent singletons are always cached by the parent.
+	_, err = a.Get(0)
+	require.NoError(t, err)
+	_, err = c.Get(0)
+	require.NoError(t, err)
+	require.Equal(t, 1, calls)
+
+	// Shared singletons are owned by the parent.
+	require.NoError(t, a.Close())
+	require.Empty(t, closed)
+	require.NoError(t, parent.Close())
+	require.Equal(t, []string{"shared"}, closed)
+}
+
+type testSharedModule struct{ name string }
+
+func (m *testSharedModule) ProvideName() fmt.Stringer { return testYourString(m.name) }
+
+func TestChildShareSingletonsByModule(t *testing.T) {
+	parent := SafeNew()
+	shared := &testSharedModule{"shared"}
+	get := func(module *testSharedModule) fmt.Stringer {
+		child := parent.ChildWithOptions(ShareSingletons(true))
+		require.NoError(t, child.Install(module))
+		v, err := child.Get((*fmt.Stringer)(nil))
+		require.NoError(t, err)
+		return v.(fmt.Stringer)
+	}
+	require.Equal(t, "shared", get(shared).String())
+	// Methods of the same name on other modules are different providers.
+	require.Equal(t, "other", get(&testSharedModule{"other"}).String())
+	require.Equal(t, "shared", get(shared).String())
+}
+
+func testTraceProvider(n int) string { return fmt.Sprint(n) }
+
+func TestTracer(t *testing.T) {
+	tracer := NewTracer()
+	i := SafeNew()
+	require.NoError(t, i.Bind(Describe("the answer", Literal(42))))
+	require.NoError(t, i.Bind(testTraceProvider))
+	i.SetTracer(tracer)
+	child := i.Child()
+	_, err := child.CallWith(func(s string, n int, f float64) {}, 1.5)
 	require.NoError(t, err)
-	err = i.Install(&testModuleParam{param: 123})
+	_, err = i.Get(true)
+	require.Error(t, err)
+
+	traces := tracer.Traces()
+	require.Len(t, traces, 2)
+	require.Equal(t, `func(string, int, float64) <- github.com/alecthomas/inject.TestTracer.func1
+  string <- github.com/alecthomas/inject.testTraceProvider [parent]
+    int <- int (the answer)
+  int <- int (the answer) [parent] [repeated]
+  float64 <- extra argument
+`, traces[0].String())
+	require.Equal(t, "bool ERROR: unbound type bool\n", traces[1].String())
+
+	w := &bytes.Buffer{}
+	require.NoError(t, tracer.WriteJSON(w))
+	require.Contains(t, w.String(), `"binding": "github.com/alecthomas/inject.testTraceProvider"`)
+
+	tracer.Reset()
+	i.SetTracer(nil)
+	i.Get("")
+	require.Empty(t, tracer.Traces())
+}
+
+type testExplainModule struct{}
+
+func (testExplainModule) ProvideGreeting(n int) string { return fmt.Sprint(n) }
+
+func TestExplain(t *testing.T) {
+	i := New()
+	i.Bind(Describe("the answer", Literal(42)))
+	i.Install(testExplainModule{})
+	require.Equal(t, `string <- github.com/alecthomas/inject.testExplainModule.ProvideGreeting via Singleton() from module inject.testExplainModule
+  int <- int via Literal() (the answer)
+`, i.Explain(""))
+	i.Get(reflect.TypeOf(""))
+	require.Contains(t, i.Child().Explain(""), "from module inject.testExplainModule [parent] [cached]\n")
+	require.Equal(t, "bool ERROR: unbound type bool\n", i.Explain(true))
+}
+
+type testEvent struct{ n int }
+
+func TestChannelDirectionConversion(t *testing.T) {
+	i := SafeNew()
+	events := make(chan testEvent, 1)
+	require.NoError(t, i.Bind(events))
+	require.NoError(t, i.Bind(make(chan testEvent), Name("named")))
+	_, err := i.Call(func(send chan<- testEvent, recv <-chan testEvent) {
+		send <- testEvent{1}
+		require.Equal(t, testEvent{1}, <-recv)
+	})
+	require.NoError(t, err)
+	v, err := i.GetKey(Key{Type: reflect.TypeOf((<-chan testEvent)(nil)), Name: "named"})
+	require.NoError(t, err)
+	require.NotEqual(t, (<-chan testEvent)(events), v)
+
+	// Explicit directional bindings take precedence.
+	child := i.Child()
+	other := make(chan testEvent)
+	require.NoError(t, child.Bind((<-chan testEvent)(other)))
+	v, err = child.Get((<-chan testEvent)(nil))
+	require.NoError(t, err)
+	require.Equal(t, (<-chan testEvent)(other), v)
+	require.False(t, SafeNew().Has((<-chan testEvent)(nil)))
+}
+
+type testLifecycleDB struct{}
+
+type testDBModule struct{ events *[]string }
+
+func (m *testDBModule) ProvideDB() *testLifecycleDB { return &testLifecycleDB{} }
+
+func (m *testDBModule) Start(ctx context.Context) error {
+	*m.events = append(*m.events, "start db")
+	return nil
+}
+
+func (m *testDBModule) Stop(ctx context.Context) error {
+	*m.events = append(*m.events, "stop db")
+	return nil
+}
+
+type testAppModule struct {
+	events *[]string
+	fail   bool
+}
+
+func (m *testAppModule) Start(ctx context.Context, db *testLifecycleDB) error {
+	if m.fail {
+		return fmt.Errorf("failed")
+	}
+	*m.events = append(*m.events, "start app")
+	return nil
+}
+
+func (m *testAppModule) Stop(ctx context.Context) error {
+	*m.events = append(*m.events, "stop app")
+	return nil
+}
+
+func TestModuleStartStop(t *testing.T) {
+	events := []string{}
+	i := New()
+	i.Install(&testAppModule{events: &events}, &testDBModule{&events})
+	require.NoError(t, i.Start(context.Background()))
+	require.Error(t, i.Start(context.Background()))
+	require.NoError(t, i.Stop(context.Background()))
+	require.Equal(t, []string{"start db", "start app", "stop app", "stop db"}, events)
+
+	events = []string{}
+	i = New()
+	i.Install(&testAppModule{events: &events, fail: true}, &testDBModule{&events})
+	require.EqualError(t, i.Start(context.Background()), "module inject.testAppModule: failed to start: failed")
+	require.Equal(t, []string{"start db", "stop db"}, events)
+}
+
+func TestFallbackResolvers(t *testing.T) {
+	zero := func(key Key) (*Binding, error) {
+		return &Binding{
+			Provides: key.Type,
+			Build:    func() (interface{}, error) { return reflect.Zero(key.Type).Interface(), nil },
+		}, nil
+	}
+	calls := []string{}
+	i := SafeNew()
+	require.NoError(t, i.Bind("bound"))
+	i.AddResolver(func(key Key) (*Binding, error) {
+		calls = append(calls, key.String())
+		if key.Type == reflect.TypeOf(true) {
+			return nil, fmt.Errorf("registry unavailable")
+		}
+		return nil, nil
+	})
+	_, err := i.Get(0)
+	require.EqualError(t, err, "unbound type int")
+	_, err = i.Get(true)
+	require.EqualError(t, err, "resolving bool: registry unavailable")
+
+	child := i.Child()
+	child.AddResolver(zero)
+	r, err := child.Call(func(s string, n int, f float64) string { return fmt.Sprint(s, n, f) })
+	require.NoError(t, err)
+	require.Equal(t, []interface{}{"bound0 0"}, r)
+	// The parent's resolvers are consulted before the child's.
+	require.Equal(t, []string{"int", "bool"}, calls[:2])
+	require.Contains(t, calls[2:], "float64")
+	require.False(t, i.Has(0))
+
+	i.AddResolver(func(key Key) (*Binding, error) { return &Binding{Provides: reflect.TypeOf("")}, nil })
+	_, err = i.Get(0)
+	require.EqualError(t, err, "resolver returned a binding of string for int")
+}
+
+type testGroupParams struct {
+	In
+
+	Up    []string `group:"up"`
+	Down  []string `group:"down"`
+	Other []string `group:"other"`
+}
+
+func TestGroups(t *testing.T) {
+	i := SafeNew()
+	require.NoError(t, i.Bind(Group("up", "create"), Group("up", Singleton(func() string { return "index" }))))
+	require.NoError(t, i.Bind(Group("down", "drop")))
+	require.NoError(t, i.Bind(Sequence([]string{"unnamed"})))
+	require.NoError(t, i.Validate(func(testGroupParams) {}))
+	r, err := i.Call(func(p testGroupParams, all []string) string {
+		return fmt.Sprint(p.Up, p.Down, p.Other, all)
+	})
+	require.NoError(t, err)
+	require.Equal(t, []interface{}{"[create index] [drop] [] [unnamed]"}, r)
+	v, err := i.GetKey(Key{Type: reflect.TypeOf([]string{}), Name: "down"})
+	require.NoError(t, err)
+	require.Equal(t, []string{"drop"}, v)
+}
+
+type testPartialModule struct {
+	Host    string
+	Port    int
+	Verbose bool
+	secret  string
+}
+
+func TestInstallDuplicateModuleMergesFields(t *testing.T) {
+	i := SafeNew()
+	existing := &testPartialModule{Host: "localhost", secret: "s"}
+	require.NoError(t, i.Install(existing))
+	require.NoError(t, i.Install(&testPartialModule{Port: 8080, secret: "s"}))
+	require.NoError(t, i.Install(&testPartialModule{Host: "localhost", Verbose: true}))
+	require.Equal(t, &testPartialModule{Host: "localhost", Port: 8080, Verbose: true, secret: "s"}, existing)
+
+	err := i.Install(&testPartialModule{Host: "example.com", Port: 9090, secret: "t"})
+	require.EqualError(t, err, "duplicate module inject.testPartialModule has conflicting values for Host, Port, unexported fields")
+	require.Equal(t, 8080, existing.Port)
+}
+
+func TestPrewarm(t *testing.T) {
+	built := []string{}
+	i := New()
+	i.Bind(Singleton(func() int { built = append(built, "int"); return 1 }))
+	i.Bind(Singleton(func(int) string { built = append(built, "string"); return "" }))
+	i.Bind(Singleton(func() float64 { built = append(built, "float64"); return 0 }))
+	require.NoError(t, i.Prewarm(""))
+	require.Equal(t, []string{"int", "string"}, built)
+	i.Get(reflect.TypeOf(""))
+	require.Equal(t, []string{"int", "string"}, built)
+
+	err := i.Prewarm(true, Key{Type: reflect.TypeOf(0), Name: "missing"}, 1.0)
+	require.EqualError(t, err, `couldn't prewarm bool: unbound type bool; couldn't prewarm int("missing"): unbound key int("missing")`)
+	require.Equal(t, []string{"int", "string", "float64"}, built)
+}
+
+func TestDefaultBindings(t *testing.T) {
+	i := SafeNew()
+	require.NoError(t, i.Bind(Default("default")))
+	v, err := i.Get("")
+	require.NoError(t, err)
+	require.Equal(t, "default", v)
+	require.NoError(t, i.Bind("real"))
+	v, err = i.Get("")
+	require.NoError(t, err)
+	require.Equal(t, "real", v)
+	require.NoError(t, i.Bind(Default("ignored")))
+	v, err = i.Get("")
+	require.NoError(t, err)
+	require.Equal(t, "real", v)
+	require.Error(t, i.Bind("again"))
+
+	i = SafeNew()
+	require.NoError(t, i.BindTo((*fmt.Stringer)(nil), Default(stringer("default"))))
+	require.NoError(t, i.BindTo((*fmt.Stringer)(nil), stringer("real")))
+	v, err = i.Get((*fmt.Stringer)(nil))
+	require.NoError(t, err)
+	require.Equal(t, stringer("real"), v)
+
+	// A default wrapped in Singleton() is still a default.
+	i = SafeNew()
+	require.NoError(t, i.Bind("real"))
+	require.NoError(t, i.Bind(Singleton(Default(func() string { return "default" }))))
+	v, err = i.Get("")
+	require.NoError(t, err)
+	require.Equal(t, "real", v)
+}
+
+type testConditionalModule struct{}
+
+func (testConditionalModule) Configure(binder Binder) error {
+	if !binder.Has("") {
+		binder.Bind("fallback")
+	}
+	if binder.Has(0) {
+		binder.Override(2)
+	}
+	binder.Provide("greeting", func(n int) string { return fmt.Sprintf("hello %d", n) })
+	return nil
+}
+
+func TestBinderHasOverrideProvide(t *testing.T) {
+	i := New()
+	i.Bind(1)
+	i.Install(testConditionalModule{})
+	require.Equal(t, "fallback", i.Get(reflect.TypeOf("")))
+	require.Equal(t, 2, i.Get(reflect.TypeOf(0)))
+	require.Equal(t, "hello 2", i.GetKey(Key{Type: reflect.TypeOf(""), Name: "greeting"}))
+	require.True(t, i.Has(Key{Type: reflect.TypeOf(""), Name: "greeting"}))
+	require.True(t, i.Has((*Binder)(nil)))
+	require.False(t, i.Has(1.0))
+	require.True(t, i.Child().Has(0))
+
+	s := SafeNew()
+	require.NoError(t, s.Override(1))
+	require.NoError(t, s.Override(2, Name("n")))
+	require.NoError(t, s.Override(3, Name("n")))
+	v, err := s.GetKey(Key{Type: reflect.TypeOf(0), Name: "n"})
+	require.NoError(t, err)
+	require.Equal(t, 3, v)
+}
+
+func TestIf(t *testing.T) {
+	hasInt := func(i *Injector) bool { return i.Has(0) }
+	i := SafeNew()
+	require.NoError(t, i.Bind(If(hasInt, func(n int) string { return fmt.Sprint(n) })))
+	require.False(t, i.Has(""))
+	require.NoError(t, i.Bind(1))
+	require.NoError(t, i.Bind(If(hasInt, func(n int) string { return fmt.Sprint(n) })))
+	v, err := i.Get("")
+	require.NoError(t, err)
+	require.Equal(t, "1", v)
+
+	never := func(*Injector) bool { return false }
+	require.NoError(t, i.Bind(If(never, "conflict")))
+	require.NoError(t, i.Bind(Singleton(If(never, func() float64 { return 1 })), Eager()))
+	require.NoError(t, i.Bind(Sequence(If(never, []int{1}))))
+	require.NoError(t, i.BindTo((*fmt.Stringer)(nil), If(never, stringer("never"))))
+	require.False(t, i.Has(1.0))
+	require.False(t, i.Has([]int{}))
+	require.False(t, i.Has((*fmt.Stringer)(nil)))
+
+	// Conditions are re-evaluated against the injector being merged into.
+	other := SafeNew()
+	require.NoError(t, other.Bind(If(hasInt, func(n int) float64 { return float64(n) })))
+	require.False(t, other.Has(1.0))
+	require.NoError(t, i.Merge(other, ConflictReplace))
+	v, err = i.Get(1.0)
 	require.NoError(t, err)
+	require.Equal(t, 1.0, v)
+}
+
+func TestVariadic(t *testing.T) {
+	i := SafeNew()
+	require.NoError(t, i.Bind(func(names ...string) int { return len(names) }))
+	require.NoError(t, i.Validate(func(n int, flags ...bool) {}))
 	v, err := i.Get(0)
 	require.NoError(t, err)
-	require.Equal(t, 123, v)
+	require.Equal(t, 0, v)
+	r, err := i.Call(func(flags ...bool) int { return len(flags) })
+	require.NoError(t, err)
+	require.Equal(t, []interface{}{0}, r)
+
+	require.NoError(t, i.Bind(Sequence([]string{"a", "b"})))
+	require.NoError(t, i.Bind(Sequence(func() []string { return []string{"c"} })))
+	v, err = i.Get(0)
+	require.NoError(t, err)
+	require.Equal(t, 3, v)
+	r, err = i.Call(func(n int, names ...string) string { return fmt.Sprint(n, names) })
+	require.NoError(t, err)
+	require.Equal(t, []interface{}{"3 [a b c]"}, r)
+	r, err = i.CallWith(func(names ...string) int { return len(names) }, []string{"x"})
+	require.NoError(t, err)
+	require.Equal(t, []interface{}{1}, r)
+}
+
+type testPrivateConn struct{ dsn string }
+
+type testPrivateRepo struct{ conn *testPrivateConn }
+
+type testPrivateModule struct {
+	events *[]string
+}
+
+func (m *testPrivateModule) Exposes() []interface{} { return []interface{}{&testPrivateRepo{}} }
+
+func (m *testPrivateModule) ProvideConn(dsn string, cleanup Cleanup) *testPrivateConn {
+	cleanup(func() { *m.events = append(*m.events, "close conn") })
+	return &testPrivateConn{dsn}
+}
+
+func (m *testPrivateModule) ProvideRepo(conn *testPrivateConn) *testPrivateRepo {
+	return &testPrivateRepo{conn}
+}
+
+func TestPrivateModuleProviders(t *testing.T) {
+	events := []string{}
+	i := SafeNew()
+	require.NoError(t, i.Bind("dsn"))
+	require.NoError(t, i.Install(&testPrivateModule{&events}))
+	require.False(t, i.Has(&testPrivateConn{}))
+	require.NoError(t, i.Validate(func(*testPrivateRepo) {}))
+	repo, err := i.Get(&testPrivateRepo{})
+	require.NoError(t, err)
+	require.Equal(t, "dsn", repo.(*testPrivateRepo).conn.dsn)
+	again, err := i.Get(&testPrivateRepo{})
+	require.NoError(t, err)
+	require.Equal(t, repo, again)
+
+	bindings, err := i.ModuleBindings(&testPrivateModule{})
+	require.NoError(t, err)
+	require.Len(t, bindings, 1)
+	require.Equal(t, []reflect.Type{reflect.TypeOf(""), reflect.TypeOf(Cleanup(nil))}, bindings[0].Requires)
+
+	require.NoError(t, i.Close())
+	require.Equal(t, []string{"close conn"}, events)
+}
+
+type testPrivateKey struct{ id string }
+
+type testKeyModule struct{}
+
+func (testKeyModule) ProvidePrivateKey() *testPrivateKey { return &testPrivateKey{"key"} }
+
+func TestPrivateKeyProviderIsPublic(t *testing.T) {
+	i := SafeNew()
+	require.NoError(t, i.Install(testKeyModule{}))
+	key, err := i.Get(&testPrivateKey{})
+	require.NoError(t, err)
+	require.Equal(t, "key", key.(*testPrivateKey).id)
+}
+
+type testRoute struct {
+	MapEntry
+
+	Key   string
+	Value int
+}
+
+func TestMapEntries(t *testing.T) {
+	i := SafeNew()
+	require.NoError(t, i.Bind(Mapping(map[string]int{"c": 3, "a": 1})))
+	require.NoError(t, i.Bind(Mapping(map[string]int{"b": 2})))
+	require.NoError(t, i.Bind(map[string]int{"z": 26}, Name("named")))
+	for j := 0; j < 5; j++ {
+		v, err := i.Get([]testRoute{})
+		require.NoError(t, err)
+		require.Equal(t, []testRoute{{Key: "a", Value: 1}, {Key: "b", Value: 2}, {Key: "c", Value: 3}}, v)
+	}
+	v, err := i.GetKey(Key{Type: reflect.TypeOf([]testRoute{}), Name: "named"})
+	require.NoError(t, err)
+	require.Equal(t, []testRoute{{Key: "z", Value: 26}}, v)
+
+	_, err = SafeNew().Get([]testRoute{})
+	require.Error(t, err)
+
+	values := []reflect.Value{reflect.ValueOf(10), reflect.ValueOf(-1), reflect.ValueOf(2)}
+	sortValues(values)
+	require.Equal(t, []interface{}{-1, 2, 10}, []interface{}{values[0].Interface(), values[1].Interface(), values[2].Interface()})
+}
+
+type testRequestScoped string
+
+func TestCleanup(t *testing.T) {
+	events := []string{}
+	parent := SafeNew()
+	require.NoError(t, parent.Bind(Singleton(func(cleanup Cleanup) int {
+		cleanup(func() { events = append(events, "parent") })
+		return 1
+	})))
+	child := parent.Child()
+	require.NoError(t, child.Bind(func(n int, cleanup Cleanup) testRequestScoped {
+		cleanup(func() { events = append(events, "request") })
+		return "request"
+	}))
+	_, err := child.Get(testRequestScoped(""))
+	require.NoError(t, err)
+	_, err = child.Get(testRequestScoped(""))
+	require.NoError(t, err)
+
+	require.NoError(t, child.Close())
+	require.Equal(t, []string{"request", "request"}, events)
+	require.NoError(t, child.Close())
+	require.NoError(t, parent.Close())
+	require.Equal(t, []string{"request", "request", "parent"}, events)
+}
+
+type testLegacyModule struct{}
+
+func (testLegacyModule) GiveInt() int                { return 1 }
+func (testLegacyModule) GiveFreshString() string     { return "fresh" }
+func (testLegacyModule) GiveFloatList() []float64    { return []float64{1} }
+func (testLegacyModule) ProvideIgnored() bool        { return true }
+func (testLegacyModule) GiveLookupDict() map[int]int { return map[int]int{1: 1} }
+
+func TestInstallWithPrefixAndMarkers(t *testing.T) {
+	i := SafeNew()
+	require.NoError(t, i.Install(testLegacyModule{}, WithPrefix("Give"),
+		WithMarkers(Markers{Multi: "Fresh", Sequence: "List", Mapping: "Dict"})))
+	require.NoError(t, i.Bind(Sequence([]float64{2})))
+	require.NoError(t, i.Bind(Mapping(map[int]int{2: 2})))
+	_, err := i.Call(func(n int, s string, f []float64, m map[int]int) {
+		require.Equal(t, 1, n)
+		require.Equal(t, "fresh", s)
+		require.Equal(t, []float64{1, 2}, f)
+		require.Equal(t, map[int]int{1: 1, 2: 2}, m)
+	})
+	require.NoError(t, err)
+	require.False(t, i.Has(true))
+
+	err = SafeNew().Install(testLegacyModule{}, WithPrefix(""))
+	require.EqualError(t, err, "WithPrefix() requires a non-empty prefix")
+	// Changing the returned markers does not change the defaults.
+	markers := DefaultMarkers()
+	markers.Multi = "Fresh"
+	require.Equal(t, "Multi", DefaultMarkers().Multi)
+}
+
+type testFactoryModule struct{ built *int }
+
+func (m testFactoryModule) ProvideBuilder() *bytes.Buffer {
+	*m.built++
+	return &bytes.Buffer{}
+}
+
+func TestInstallWithDefaultScope(t *testing.T) {
+	built := 0
+	i := SafeNew()
+	require.NoError(t, i.Install(testFactoryModule{&built}, func() int {
+		built++
+		return built
+	}, WithDefaultScope(Transient)))
+	a, err := i.Get(&bytes.Buffer{})
+	require.NoError(t, err)
+	b, err := i.Get(&bytes.Buffer{})
+	require.NoError(t, err)
+	require.False(t, a == b)
+	_, err = i.Get(0)
+	require.NoError(t, err)
+	require.Equal(t, 3, built)
+
+	// Other Install() calls are unaffected.
+	require.NoError(t, i.Install(func() string {
+		built++
+		return "singleton"
+	}))
+	_, err = i.Get("")
+	require.NoError(t, err)
+	_, err = i.Get("")
+	require.NoError(t, err)
+	require.Equal(t, 4, built)
+}
+
+type testWorker struct{ name string }
+
+type testWorkerList []*testWorker
+
+type testHandlerMap map[string]*testWorker
+
+func TestResolveConcreteSequencesAndMappings(t *testing.T) {
+	i := SafeNew()
+	require.NoError(t, i.Bind(Sequence(testWorkerList{{"a"}})))
+	require.NoError(t, i.Bind(Sequence(testWorkerList{{"b"}})))
+	require.NoError(t, i.Bind(Mapping(testHandlerMap{"a": {"a"}})))
+	_, err := i.Call(func(workers []*testWorker, handlers map[string]*testWorker) {
+		require.Equal(t, []*testWorker{{"a"}, {"b"}}, workers)
+		require.Equal(t, map[string]*testWorker{"a": {"a"}}, handlers)
+	})
+	require.NoError(t, err)
+
+	// Interface sequences in children include those of the parent.
+	i.Bind(Sequence([]stringer{"parent"}))
+	v, err := i.Child().Get([]fmt.Stringer{})
+	require.NoError(t, err)
+	require.Equal(t, []fmt.Stringer{stringer("parent")}, v)
+
+	// Concrete types are still unbound if nothing provides them.
+	_, err = i.Get([]int{})
+	require.Error(t, err)
+}
+
+func TestTopoOrder(t *testing.T) {
+	i := SafeNew()
+	i.Bind(func(n int, f float64) string { return "" })
+	i.Bind(func(f float64) int { return 0 })
+	i.Bind(1.5)
+	bindings, err := i.TopoOrder()
+	require.NoError(t, err)
+	provides := []reflect.Type{}
+	for _, binding := range bindings {
+		provides = append(provides, binding.Provides)
+	}
+	require.Equal(t, []reflect.Type{
+		reflect.TypeOf(i),
+		reflect.TypeOf((*SafeBinder)(nil)).Elem(),
+		reflect.TypeOf(1.5),
+		reflect.TypeOf(0),
+		reflect.TypeOf(""),
+	}, provides)
+}
+
+type testGraphModule struct{}
+
+func (testGraphModule) ProvideUint(b bool) uint { return 0 }
+
+func TestGraph(t *testing.T) {
+	i := SafeNew()
+	require.NoError(t, i.Bind(Singleton(func(s string) int { return len(s) }), "config"))
+	c := i.Child()
+	require.NoError(t, c.Bind(func(n int) float64 { return float64(n) }, testHandlerA{}))
+	require.NoError(t, c.Bind(func(h testHandler, f float64) bool { return true }))
+	require.NoError(t, c.Install(testGraphModule{}))
+	require.NoError(t, c.Override("override"))
+
+	g := c.Graph()
+	keys := []string{}
+	for _, node := range g.Nodes() {
+		keys = append(keys, node.Key.String())
+	}
+	require.Equal(t, []string{"int", "*inject.SafeInjector", "inject.SafeBinder", "float64", "inject.testHandlerA", "bool", "uint", "string"}, keys)
+
+	key := func(v interface{}) Key { return Key{Type: reflect.TypeOf(v)} }
+	info, ok := g.Node(key(0))
+	require.True(t, ok)
+	require.Equal(t, ScopeSingleton, info.Scope)
+	require.Equal(t, i, info.Injector)
+	require.Equal(t, []reflect.Type{reflect.TypeOf("")}, info.Requires)
+	info, _ = g.Node(key(""))
+	require.Equal(t, ScopeLiteral, info.Scope)
+	require.Equal(t, c, info.Injector)
+	info, _ = g.Node(key(uint(0)))
+	require.Equal(t, reflect.TypeOf(testGraphModule{}), info.Module)
+	require.Equal(t, "github.com/alecthomas/inject.testGraphModule.ProvideUint", info.Provider)
+
+	names := func(infos []*BindingInfo) []string {
+		out := []string{}
+		for _, info := range infos {
+			out = append(out, info.Key.String())
+		}
+		return out
+	}
+	require.Equal(t, []string{"inject.testHandlerA", "float64"}, names(g.Dependencies(key(true))))
+	require.Equal(t, []string{"float64"}, names(g.Dependents(key(0))))
+
+	walked := []string{}
+	g.WalkDepthFirst(key(uint(0)), func(info *BindingInfo, depth int) bool {
+		walked = append(walked, fmt.Sprintf("%s:%d", info.Key, depth))
+		return info.Key != key(0)
+	})
+	require.Equal(t, []string{"uint:0", "bool:1", "inject.testHandlerA:2", "float64:2", "int:3"}, walked)
+	walked = nil
+	g.WalkBreadthFirst(key(uint(0)), func(info *BindingInfo, depth int) bool {
+		walked = append(walked, fmt.Sprintf("%s:%d", info.Key, depth))
+		return true
+	})
+	require.Equal(t, []string{"uint:0", "bool:1", "inject.testHandlerA:2", "float64:2", "int:3", "string:4"}, walked)
+
+	require.True(t, g.Reachable(key(uint(0)), key("")))
+	require.False(t, g.Reachable(key(""), key(0)))
+	require.False(t, g.Reachable(key(0), key(0)))
+}
+
+func TestPlan(t *testing.T) {
+	built := 0
+	i := SafeNew()
+	require.NoError(t, i.Bind(Singleton(func(s string) int { built++; return len(s) }), "config"))
+	c := i.Child()
+	require.NoError(t, c.Bind(func(n int) float64 { built++; return float64(n) }, testHandlerA{}))
+	require.NoError(t, c.Bind(func(h testHandler, f float64) bool { built++; return true }))
+	require.NoError(t, c.Install(testGraphModule{}))
+
+	keys := func(infos []*BindingInfo) []string {
+		out := []string{}
+		for _, info := range infos {
+			out = append(out, info.Key.String())
+		}
+		return out
+	}
+	plan, err := c.Plan(func(u uint, f float64) {})
+	require.NoError(t, err)
+	require.Equal(t, []string{"int", "float64", "bool", "uint"}, keys(plan))
+	require.Equal(t, 0, built)
+
+	plan, err = c.Plan(Key{Type: reflect.TypeOf(0.0)}, 0)
+	require.NoError(t, err)
+	require.Equal(t, []string{"int", "float64"}, keys(plan))
+
+	_, err = i.Get(0)
+	require.NoError(t, err)
+	plan, err = c.Plan(0.0)
+	require.NoError(t, err)
+	require.Equal(t, []string{"float64"}, keys(plan))
+
+	require.NoError(t, c.Bind(func(r rune) int8 { return 0 }))
+	_, err = c.Plan(func(n int8) {})
+	require.EqualError(t, err, "couldn't satisfy argument 0 of func(int8): no binding for int32 required by int8: unbound type int32")
+}
+
+func TestDiff(t *testing.T) {
+	base := func() *SafeInjector {
+		i := SafeNew()
+		require.NoError(t, i.Bind("config", func(s string) int { return len(s) }))
+		return i
+	}
+	a := base()
+	require.NoError(t, a.Bind(1.0))
+	b := base()
+	require.NoError(t, b.Override(Singleton(func(s string) int { return 0 })))
+	require.NoError(t, b.Install(testGraphModule{}))
+
+	require.Empty(t, Diff(base(), base().Child()))
+	changes := Diff(a, b)
+	lines := []string{}
+	for _, change := range changes {
+		lines = append(lines, change.String())
+	}
+	require.Equal(t, []string{
+		"changed int: annotation: Provider -> Singleton; scope: transient -> singleton; " +
+			"provider: github.com/alecthomas/inject.TestDiff.func1.1 -> github.com/alecthomas/inject.TestDiff.func2",
+		"removed float64 (Literal)",
+		"added uint (Singleton, provided by github.com/alecthomas/inject.testGraphModule.ProvideUint, from module inject.testGraphModule)",
+	}, lines)
+	require.Equal(t, BindingChanged, changes[0].Kind)
+	require.Nil(t, changes[1].After)
+	require.Nil(t, changes[2].Before)
+}
+
+type testBadProviderModule struct{}
+
+func (testBadProviderModule) ProvideNothing() {}
+
+type testBadProvidersModule struct{}
+
+func (*testBadProvidersModule) ProvideError() error { return nil }
+
+func (*testBadProvidersModule) ProvideGood() int { return 1 }
+
+func (*testBadProvidersModule) ProvideTwo() (int, string) { return 0, "" }
+
+func testErrorFirstProvider() (error, int) { return nil, 0 } // nolint: golint
+
+func TestProviderSignatureErrors(t *testing.T) {
+	err := SafeNew().Install(testBadProviderModule{})
+	require.EqualError(t, err, "module inject.testBadProviderModule: method ProvideNothing: invalid provider "+
+		"github.com/alecthomas/inject.testBadProviderModule.ProvideNothing func(): it returns nothing, but must return (<type>[, error])")
+	err = SafeNew().Install(&testBadProvidersModule{})
+	require.EqualError(t, err, "module *inject.testBadProvidersModule: method ProvideError: invalid provider "+
+		"github.com/alecthomas/inject.(*testBadProvidersModule).ProvideError func() error: it returns only an error, but must return (<type>, error); "+
+		"module *inject.testBadProvidersModule: method ProvideTwo: invalid provider "+
+		"github.com/alecthomas/inject.(*testBadProvidersModule).ProvideTwo func() (int, string): its second return value is string, but must be error")
+	errs, ok := err.(Errors)
+	require.True(t, ok)
+	require.Len(t, errs, 2)
+	require.Equal(t, "ProvideTwo", errs[1].(*ProviderError).Method)
+	err = SafeNew().Bind(testErrorFirstProvider)
+	require.EqualError(t, err, "invalid provider github.com/alecthomas/inject.testErrorFirstProvider func() (error, int): "+
+		"it returns the error first, but must return (<type>, error); to bind the function itself, use Literal() or AsLiteral()")
+	for _, provider := range []interface{}{
+		func() error { return nil },
+		func() (int, string, error) { return 0, "", nil },
+		func() (int, string) { return 0, "" },
+	} {
+		require.Error(t, SafeNew().Bind(provider))
+	}
 }
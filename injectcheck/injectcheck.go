@@ -0,0 +1,508 @@
+// Package injectcheck implements a static analyzer reporting common mistakes when wiring
+// applications with github.com/alecthomas/inject.
+//
+// The following are reported:
+//
+//   - Providers, whether module methods or functions passed to Bind() or Install(), with
+//     signatures that inject will reject at runtime. Module types are those passed to Install()
+//     in the package being analyzed, and those following the module convention of having a
+//     Configure() method or a name ending in "Module".
+//   - Module methods whose names look like misspellings of the "Provide" prefix, and so will
+//     silently not be bound. Modules installed with inject.WithPrefix() can be checked by passing
+//     their prefix with the -prefix flag.
+//   - Parameters of functions passed to Call() that are not provided by any binding, where the
+//     injector is created, configured and called within a single function.
+package injectcheck
+
+import (
+	"fmt"
+	"go/ast"
+	"go/types"
+	"strings"
+	"unicode"
+
+	"golang.org/x/tools/go/analysis"
+	"golang.org/x/tools/go/types/typeutil"
+)
+
+const injectPath = "github.com/alecthomas/inject"
+
+// Analyzer reports inject wiring mistakes.
+var Analyzer = &analysis.Analyzer{
+	Name: "injectcheck",
+	Doc:  "report mistakes in github.com/alecthomas/inject providers and wiring",
+	Run:  run,
+}
+
+// prefix identifies provider methods of modules, as with inject.WithPrefix().
+var prefix string
+
+func init() {
+	Analyzer.Flags.StringVar(&prefix, "prefix", "Provide", "method name prefix identifying providers of modules")
+}
+
+func run(pass *analysis.Pass) (interface{}, error) {
+	if prefix == "" {
+		return nil, fmt.Errorf("-prefix must not be empty")
+	}
+	modules := map[*types.TypeName]bool{}
+	for _, file := range pass.Files {
+		ast.Inspect(file, func(node ast.Node) bool {
+			if call, ok := node.(*ast.CallExpr); ok {
+				checkCall(pass, call, modules)
+			}
+			return true
+		})
+	}
+	for _, file := range pass.Files {
+		for _, decl := range file.Decls {
+			fn, ok := decl.(*ast.FuncDecl)
+			if !ok {
+				continue
+			}
+			if fn.Recv != nil {
+				checkMethod(pass, fn, modules)
+			} else if fn.Body != nil {
+				checkEntryPoints(pass, fn.Body)
+			}
+		}
+	}
+	return nil, nil
+}
+
+// checkCall checks the arguments of calls to Bind() and Install(), and records the types of
+// installed modules.
+func checkCall(pass *analysis.Pass, call *ast.CallExpr, modules map[*types.TypeName]bool) {
+	switch injectMethod(pass, call) {
+	case "Bind":
+		for _, arg := range call.Args {
+			checkProviderArg(pass, arg)
+		}
+	case "Install":
+		for _, arg := range call.Args {
+			if isFunc(pass.TypesInfo.TypeOf(arg)) {
+				checkProviderArg(pass, arg)
+			} else if name := namedType(pass.TypesInfo.TypeOf(arg)); name != nil {
+				modules[name] = true
+			}
+		}
+	}
+}
+
+// checkProviderArg checks the signature of a function passed to Bind(), possibly wrapped in
+// annotations.
+func checkProviderArg(pass *analysis.Pass, arg ast.Expr) {
+	if call, ok := arg.(*ast.CallExpr); ok {
+		switch injectFunc(pass, call) {
+		case "Literal":
+			return
+		case "Singleton", "Provider", "Sequence", "Mapping":
+			if len(call.Args) == 1 {
+				checkProviderArg(pass, call.Args[0])
+			}
+			return
+		case "Describe":
+			if len(call.Args) == 2 {
+				checkProviderArg(pass, call.Args[1])
+			}
+			return
+		case "Providers":
+			for _, arg := range call.Args {
+				checkProviderArg(pass, arg)
+			}
+			return
+		}
+	}
+	sig, ok := pass.TypesInfo.TypeOf(arg).Underlying().(*types.Signature)
+	if !ok {
+		return
+	}
+	if problem := providerProblem(sig); problem != "" {
+		pass.Reportf(arg.Pos(), "invalid provider: %s; to bind the function itself, use inject.Literal() or inject.AsLiteral()", problem)
+	}
+}
+
+// checkMethod checks methods of module types.
+func checkMethod(pass *analysis.Pass, fn *ast.FuncDecl, modules map[*types.TypeName]bool) {
+	obj, ok := pass.TypesInfo.Defs[fn.Name].(*types.Func)
+	if !ok {
+		return
+	}
+	sig := obj.Type().(*types.Signature)
+	recv := namedType(sig.Recv().Type())
+	if recv == nil || !(modules[recv] || isModule(recv)) {
+		return
+	}
+	name := fn.Name.Name
+	if strings.HasPrefix(name, prefix) {
+		if problem := providerProblem(sig); problem != "" {
+			pass.Reportf(fn.Name.Pos(), "invalid provider method %s: %s", name, problem)
+		}
+		return
+	}
+	word := leadingWord(name)
+	if word == strings.ToLower(prefix) {
+		pass.Reportf(fn.Name.Pos(), "method %s is unexported so will not be bound as a provider", name)
+	} else if d := distance(strings.ToLower(word), strings.ToLower(prefix)); d > 0 && d <= 2 {
+		pass.Reportf(fn.Name.Pos(), "method %s looks like a provider but does not start with %q", name, prefix)
+	}
+}
+
+// providerProblem describes what is wrong with a provider signature, if anything, in the words of
+// inject's own checkProviderSignature().
+func providerProblem(sig *types.Signature) string {
+	results := sig.Results()
+	switch n := results.Len(); {
+	case n == 0:
+		return "it returns nothing, but must return (<type>[, error])"
+	case isError(results.At(0).Type()) && n == 1:
+		return "it returns only an error, but must return (<type>, error)"
+	case isError(results.At(0).Type()):
+		return "it returns the error first, but must return (<type>, error)"
+	case n > 2:
+		return fmt.Sprintf("it returns %d values, but must return (<type>[, error]); use a struct embedding Out to provide multiple values", n)
+	case n == 2 && !isError(results.At(1).Type()):
+		return fmt.Sprintf("its second return value is %s, but must be error",
+			types.TypeString(results.At(1).Type(), func(p *types.Package) string { return p.Name() }))
+	}
+	return ""
+}
+
+// isModule returns true if the type follows the module convention of having a Configure method or
+// a name ending in "Module".
+func isModule(name *types.TypeName) bool {
+	if strings.HasSuffix(name.Name(), "Module") {
+		return true
+	}
+	mset := types.NewMethodSet(types.NewPointer(name.Type()))
+	return mset.Lookup(name.Pkg(), "Configure") != nil
+}
+
+// injectorState tracks bindings made to an injector created within a function.
+type injectorState struct {
+	provided []types.Type
+	// Set if bindings could not be determined statically.
+	incomplete bool
+}
+
+// checkEntryPoints checks that the parameters of functions passed to Call() are provided, for
+// injectors created with New() or SafeNew() within body.
+func checkEntryPoints(pass *analysis.Pass, body *ast.BlockStmt) {
+	injectors := map[types.Object]*injectorState{}
+	receivers := map[*ast.Ident]bool{}
+	ast.Inspect(body, func(node ast.Node) bool {
+		assign, ok := node.(*ast.AssignStmt)
+		if !ok || len(assign.Lhs) != len(assign.Rhs) {
+			return true
+		}
+		for j, rhs := range assign.Rhs {
+			call, ok := rhs.(*ast.CallExpr)
+			if !ok {
+				continue
+			}
+			if name := injectFunc(pass, call); name != "New" && name != "SafeNew" {
+				continue
+			}
+			if ident, ok := assign.Lhs[j].(*ast.Ident); ok {
+				if obj := objectOf(pass, ident); obj != nil {
+					injectors[obj] = &injectorState{provided: builtinTypes(pass, obj.Type())}
+				}
+			}
+		}
+		return true
+	})
+	if len(injectors) == 0 {
+		return
+	}
+	type entryPoint struct {
+		state *injectorState
+		arg   ast.Expr
+	}
+	entryPoints := []entryPoint{}
+	ast.Inspect(body, func(node ast.Node) bool {
+		call, ok := node.(*ast.CallExpr)
+		if !ok {
+			return true
+		}
+		method := injectMethod(pass, call)
+		if method == "" {
+			return true
+		}
+		root := receiverRoot(pass, call.Fun.(*ast.SelectorExpr).X)
+		if root == nil {
+			return true
+		}
+		state := injectors[objectOf(pass, root)]
+		if state == nil {
+			return true
+		}
+		receivers[root] = true
+		switch method {
+		case "Bind":
+			for _, arg := range call.Args {
+				state.bind(pass, arg)
+			}
+		case "BindTo":
+			if len(call.Args) == 2 {
+				t := pass.TypesInfo.TypeOf(call.Args[0])
+				if ptr, ok := t.(*types.Pointer); ok && types.IsInterface(ptr.Elem()) {
+					t = ptr.Elem()
+				}
+				state.provided = append(state.provided, t)
+			}
+		case "Install":
+			for _, arg := range call.Args {
+				state.install(pass, arg)
+			}
+		case "Call":
+			if len(call.Args) == 1 {
+				entryPoints = append(entryPoints, entryPoint{state, call.Args[0]})
+			}
+		default:
+			state.incomplete = true
+		}
+		return true
+	})
+	// Any other use of the injector may add bindings we can't see.
+	ast.Inspect(body, func(node ast.Node) bool {
+		ident, ok := node.(*ast.Ident)
+		if !ok || receivers[ident] {
+			return true
+		}
+		if state := injectors[pass.TypesInfo.Uses[ident]]; state != nil {
+			state.incomplete = true
+		}
+		return true
+	})
+	for _, entry := range entryPoints {
+		if entry.state.incomplete {
+			continue
+		}
+		sig, ok := pass.TypesInfo.TypeOf(entry.arg).Underlying().(*types.Signature)
+		if !ok {
+			continue
+		}
+		for j := 0; j < sig.Params().Len(); j++ {
+			param := sig.Params().At(j).Type()
+			if !entry.state.provides(param) {
+				pass.Reportf(entry.arg.Pos(), "argument %d (%s) of function passed to Call is not provided by any binding",
+					j+1, types.TypeString(param, types.RelativeTo(pass.Pkg)))
+			}
+		}
+	}
+}
+
+// bind records the type provided by an argument to Bind().
+func (s *injectorState) bind(pass *analysis.Pass, arg ast.Expr) {
+	t := pass.TypesInfo.TypeOf(arg)
+	if call, ok := arg.(*ast.CallExpr); ok {
+		switch injectFunc(pass, call) {
+		case "Singleton", "Provider", "Sequence", "Mapping":
+			if len(call.Args) == 1 {
+				s.bind(pass, call.Args[0])
+				return
+			}
+		case "Describe":
+			if len(call.Args) == 2 {
+				s.bind(pass, call.Args[1])
+				return
+			}
+		case "Literal":
+			if len(call.Args) == 1 {
+				s.provided = append(s.provided, pass.TypesInfo.TypeOf(call.Args[0]))
+				return
+			}
+		case "Eager", "NoSingleton":
+			return
+		}
+	}
+	if sig, ok := t.Underlying().(*types.Signature); ok {
+		if sig.Results().Len() > 0 {
+			s.provided = append(s.provided, sig.Results().At(0).Type())
+		}
+		return
+	}
+	if types.IsInterface(t) {
+		// Annotations and options we don't understand.
+		s.incomplete = true
+		return
+	}
+	s.provided = append(s.provided, t)
+}
+
+// install records the types provided by a module passed to Install().
+func (s *injectorState) install(pass *analysis.Pass, arg ast.Expr) {
+	if call, ok := arg.(*ast.CallExpr); ok && injectFunc(pass, call) == "Providers" {
+		for _, arg := range call.Args {
+			s.bind(pass, arg)
+		}
+		return
+	}
+	t := pass.TypesInfo.TypeOf(arg)
+	if isFunc(t) {
+		s.bind(pass, arg)
+		return
+	}
+	if _, ok := t.Underlying().(*types.Slice); ok {
+		s.incomplete = true
+		return
+	}
+	mset := types.NewMethodSet(t)
+	for j := 0; j < mset.Len(); j++ {
+		method := mset.At(j).Obj()
+		if method.Name() == "Configure" {
+			s.incomplete = true
+		}
+		if !strings.HasPrefix(method.Name(), prefix) {
+			continue
+		}
+		sig := method.Type().(*types.Signature)
+		if sig.Results().Len() > 0 {
+			s.provided = append(s.provided, sig.Results().At(0).Type())
+		}
+	}
+}
+
+// provides returns true if t is satisfied by a recorded binding.
+func (s *injectorState) provides(t types.Type) bool {
+	switch t.Underlying().(type) {
+	case *types.Slice, *types.Map:
+		// Sequences and mappings may be assembled from bindings of other types.
+		return true
+	}
+	iface, isInterface := t.Underlying().(*types.Interface)
+	for _, p := range s.provided {
+		if types.Identical(p, t) || (isInterface && types.Implements(p, iface)) {
+			return true
+		}
+	}
+	return false
+}
+
+// builtinTypes returns the types an injector of type t binds to itself.
+func builtinTypes(pass *analysis.Pass, t types.Type) []types.Type {
+	out := []types.Type{t}
+	named := namedType(t)
+	if named == nil {
+		return out
+	}
+	for _, name := range []string{"Injector", "SafeInjector", "Binder", "SafeBinder"} {
+		if obj, ok := named.Pkg().Scope().Lookup(name).(*types.TypeName); ok {
+			if types.IsInterface(obj.Type()) {
+				out = append(out, obj.Type())
+			} else {
+				out = append(out, types.NewPointer(obj.Type()))
+			}
+		}
+	}
+	return out
+}
+
+// receiverRoot returns the identifier at the root of a chain of Bind(), BindTo() and Install()
+// calls, or nil.
+func receiverRoot(pass *analysis.Pass, expr ast.Expr) *ast.Ident {
+	switch expr := expr.(type) {
+	case *ast.Ident:
+		return expr
+	case *ast.CallExpr:
+		switch injectMethod(pass, expr) {
+		case "Bind", "BindTo", "Install":
+			return receiverRoot(pass, expr.Fun.(*ast.SelectorExpr).X)
+		}
+	}
+	return nil
+}
+
+// injectMethod returns the name of the inject method called by call, or "".
+func injectMethod(pass *analysis.Pass, call *ast.CallExpr) string {
+	fn, ok := typeutil.Callee(pass.TypesInfo, call).(*types.Func)
+	if !ok || fn.Pkg() == nil || fn.Pkg().Path() != injectPath {
+		return ""
+	}
+	if fn.Type().(*types.Signature).Recv() == nil {
+		return ""
+	}
+	return fn.Name()
+}
+
+// injectFunc returns the name of the inject package-level function called by call, or "".
+func injectFunc(pass *analysis.Pass, call *ast.CallExpr) string {
+	fn, ok := typeutil.Callee(pass.TypesInfo, call).(*types.Func)
+	if !ok || fn.Pkg() == nil || fn.Pkg().Path() != injectPath {
+		return ""
+	}
+	if fn.Type().(*types.Signature).Recv() != nil {
+		return ""
+	}
+	return fn.Name()
+}
+
+func objectOf(pass *analysis.Pass, ident *ast.Ident) types.Object {
+	if obj := pass.TypesInfo.Defs[ident]; obj != nil {
+		return obj
+	}
+	return pass.TypesInfo.Uses[ident]
+}
+
+func namedType(t types.Type) *types.TypeName {
+	if ptr, ok := t.(*types.Pointer); ok {
+		t = ptr.Elem()
+	}
+	if named, ok := t.(*types.Named); ok {
+		return named.Obj()
+	}
+	return nil
+}
+
+func isFunc(t types.Type) bool {
+	if t == nil {
+		return false
+	}
+	_, ok := t.Underlying().(*types.Signature)
+	return ok
+}
+
+func isError(t types.Type) bool {
+	return types.Identical(t, types.Universe.Lookup("error").Type())
+}
+
+// leadingWord returns the leading word of a camel-case identifier.
+func leadingWord(name string) string {
+	for j, r := range name {
+		if j > 0 && unicode.IsUpper(r) {
+			return name[:j]
+		}
+	}
+	return name
+}
+
+// distance returns the Levenshtein distance between a and b.
+func distance(a, b string) int {
+	prev := make([]int, len(b)+1)
+	for j := range prev {
+		prev[j] = j
+	}
+	for i := 1; i <= len(a); i++ {
+		cur := make([]int, len(b)+1)
+		cur[0] = i
+		for j := 1; j <= len(b); j++ {
+			cost := 1
+			if a[i-1] == b[j-1] {
+				cost = 0
+			}
+			cur[j] = min3(prev[j]+1, cur[j-1]+1, prev[j-1]+cost)
+		}
+		prev = cur
+	}
+	return prev[len(b)]
+}
+
+func min3(a, b, c int) int {
+	if b < a {
+		a = b
+	}
+	if c < a {
+		a = c
+	}
+	return a
+}
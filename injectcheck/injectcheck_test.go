@@ -0,0 +1,18 @@
+package injectcheck
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+	"golang.org/x/tools/go/analysis/analysistest"
+)
+
+func TestAnalyzer(t *testing.T) {
+	analysistest.Run(t, analysistest.TestData(), Analyzer, "a")
+}
+
+func TestAnalyzerPrefix(t *testing.T) {
+	require.NoError(t, Analyzer.Flags.Set("prefix", "Give"))
+	t.Cleanup(func() { _ = Analyzer.Flags.Set("prefix", "Provide") })
+	analysistest.Run(t, analysistest.TestData(), Analyzer, "b")
+}
@@ -0,0 +1,64 @@
+package a
+
+import (
+	"fmt"
+
+	"github.com/alecthomas/inject"
+)
+
+type Config struct{}
+
+type Module struct{}
+
+func (m *Module) ProvideConfig() *Config { return &Config{} }
+
+func (m *Module) ProvideNothing() {} // want `invalid provider method ProvideNothing: it returns nothing, but must return \(<type>\[, error\]\)`
+
+func (m *Module) ProvideBackwards() (error, string) { return nil, "" } // want `invalid provider method ProvideBackwards: it returns the error first, but must return \(<type>, error\)`
+
+func (m *Module) ProvideCount() (uint, bool) { return 0, false } // want `invalid provider method ProvideCount: its second return value is bool, but must be error`
+
+func (m *Module) ProvdeInt() int { return 1 } // want `method ProvdeInt looks like a provider but does not start with "Provide"`
+
+func (m *Module) provideFloat() float64 { return 1 } // want `method provideFloat is unexported so will not be bound as a provider`
+
+func (m *Module) Proverb() string { return "" }
+
+// Types that aren't installed and don't follow the module convention aren't checked.
+type Feedback struct{}
+
+func (f *Feedback) ProvideFeedback(message string) {}
+
+func (f *Feedback) ProvdeRating() int { return 1 }
+
+type StorageModule struct{}
+
+func (m StorageModule) ProvideNothing() {} // want `invalid provider method ProvideNothing: it returns nothing, but must return \(<type>\[, error\]\)`
+
+type configured struct{}
+
+func (c *configured) Configure(binder inject.Binder) error { return nil }
+
+func (c *configured) ProvdeInt() int { return 1 } // want `method ProvdeInt looks like a provider but does not start with "Provide"`
+
+type stringer string
+
+func (s stringer) String() string { return string(s) }
+
+func main() {
+	i := inject.New()
+	i.Install(&Module{})
+	i.Bind(func() {}) // want `invalid provider: it returns nothing, but must return \(<type>\[, error\]\); to bind the function itself, use inject.Literal\(\) or inject.AsLiteral\(\)`
+	i.Bind(inject.Literal(func() {}))
+	i.Bind(inject.Singleton(func() stringer { return "" }))
+	i.Call(func(c *Config, s fmt.Stringer, b inject.Binder) {})
+	i.Call(func(n int) {}) // want `argument 1 \(int\) of function passed to Call is not provided by any binding`
+}
+
+func escapes() {
+	i := inject.New()
+	configure(i)
+	i.Call(func(n int) {})
+}
+
+func configure(i *inject.Injector) {}
@@ -0,0 +1,12 @@
+package b
+
+// Modules installed with inject.WithPrefix("Give").
+type LegacyModule struct{}
+
+func (m *LegacyModule) GiveName() string { return "" }
+
+func (m *LegacyModule) GiveNothing() {} // want `invalid provider method GiveNothing: it returns nothing, but must return \(<type>\[, error\]\)`
+
+func (m *LegacyModule) GvieInt() int { return 1 } // want `method GvieInt looks like a provider but does not start with "Give"`
+
+func (m *LegacyModule) ProvideFloat() float64 { return 1 }
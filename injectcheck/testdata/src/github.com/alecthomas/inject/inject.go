@@ -0,0 +1,29 @@
+// Package inject is a minimal stub of github.com/alecthomas/inject for analyzer tests.
+package inject
+
+type Annotation interface{}
+
+type BindOption interface{}
+
+type Binder interface {
+	Bind(things ...interface{}) Binder
+	BindTo(to interface{}, impl interface{}) Binder
+	Install(module ...interface{}) Binder
+}
+
+type SafeBinder interface{}
+
+type Injector struct{}
+
+type SafeInjector struct{}
+
+func New() *Injector { return &Injector{} }
+
+func (i *Injector) Bind(things ...interface{}) Binder              { return i }
+func (i *Injector) BindTo(to interface{}, impl interface{}) Binder { return i }
+func (i *Injector) Install(modules ...interface{}) Binder          { return i }
+func (i *Injector) Call(f interface{}) []interface{}               { return nil }
+
+func Singleton(v interface{}) Annotation       { return nil }
+func Literal(v interface{}) Annotation         { return nil }
+func Providers(v ...interface{}) []interface{} { return v }
@@ -0,0 +1,169 @@
+// Package injectcron runs jobs bound in an injector on a schedule.
+//
+// Jobs implement ScheduledJob and have a Run method of the form Run(ctx context.Context, deps...)
+// error, whose remaining parameters are injected each time the job runs. Jobs are bound in a
+// Sequence() of ScheduledJob, and the Module contributes a Scheduler to the injector's workers, so
+// jobs run for as long as inject.SafeInjector.Run():
+//
+//	type ReportJob struct{}
+//
+//	func (ReportJob) Schedule() string { return "0 6 * * 1-5" }
+//	func (ReportJob) Run(ctx context.Context, db *sql.DB, mailer *Mailer) error { ... }
+//
+//	injector.Install(&injectcron.Module{})
+//	injector.Bind(inject.Sequence([]injectcron.ScheduledJob{ReportJob{}}))
+//	err := injector.Run(ctx)
+package injectcron
+
+import (
+	"context"
+	"fmt"
+	"reflect"
+	"sync"
+	"time"
+
+	"github.com/alecthomas/inject"
+)
+
+// A ScheduledJob is run by a Scheduler according to its schedule. It must also have a Run method
+// of the form Run(ctx context.Context, deps...) error.
+type ScheduledJob interface {
+	// Schedule of the job, as accepted by Parse() or the Module's Parse function.
+	Schedule() string
+}
+
+// Module provides a Scheduler of the bound ScheduledJobs, and adds it to the Sequence() of
+// inject.Worker run by the injector.
+type Module struct {
+	// Parse schedules. Defaults to Parse().
+	Parse func(spec string) (Schedule, error)
+	// OnError, if not nil, is called with errors returned by jobs.
+	OnError func(job ScheduledJob, err error)
+}
+
+// ProvideScheduler builds a Scheduler for the bound jobs.
+func (m *Module) ProvideScheduler(injector *inject.SafeInjector, jobs ...ScheduledJob) (*Scheduler, error) {
+	scheduler, err := NewScheduler(injector, m.Parse, jobs...)
+	if err != nil {
+		return nil, err
+	}
+	scheduler.OnError = m.OnError
+	return scheduler, nil
+}
+
+// ProvideWorkersSequence adds the Scheduler to the injector's workers.
+func (m *Module) ProvideWorkersSequence(scheduler *Scheduler) []inject.Worker {
+	return []inject.Worker{scheduler}
+}
+
+// A Scheduler runs jobs according to their schedules. It is an inject.Worker.
+type Scheduler struct {
+	// OnError, if not nil, is called with errors returned by jobs, from the job's goroutine.
+	OnError func(job ScheduledJob, err error)
+
+	injector *inject.SafeInjector
+	jobs     []*scheduledJob
+}
+
+type scheduledJob struct {
+	job      ScheduledJob
+	schedule Schedule
+	run      reflect.Value
+}
+
+var (
+	contextType = reflect.TypeOf((*context.Context)(nil)).Elem()
+	errorType   = reflect.TypeOf((*error)(nil)).Elem()
+)
+
+// NewScheduler creates a Scheduler of jobs, injecting the parameters of their Run methods from
+// injector. Schedules are parsed with parse, or Parse() if it is nil.
+//
+// An error is returned if a schedule is invalid, or a Run method is missing, has the wrong form or
+// requires a type that the injector can not provide.
+func NewScheduler(injector *inject.SafeInjector, parse func(spec string) (Schedule, error), jobs ...ScheduledJob) (*Scheduler, error) {
+	if parse == nil {
+		parse = Parse
+	}
+	s := &Scheduler{injector: injector}
+	for _, job := range jobs {
+		schedule, err := parse(job.Schedule())
+		if err != nil {
+			return nil, fmt.Errorf("job %T: %s", job, err)
+		}
+		run := reflect.ValueOf(job).MethodByName("Run")
+		if !run.IsValid() {
+			return nil, fmt.Errorf("job %T: no Run method", job)
+		}
+		rt := run.Type()
+		if rt.NumIn() == 0 || rt.In(0) != contextType || rt.NumOut() != 1 || rt.Out(0) != errorType {
+			return nil, fmt.Errorf("job %T: Run method must be of the form Run(ctx context.Context, ...) error but got %s", job, rt)
+		}
+		for j := 1; j < rt.NumIn(); j++ {
+			if !injector.Has(inject.Key{Type: rt.In(j)}) {
+				return nil, fmt.Errorf("job %T: Run method requires unbound %s", job, rt.In(j))
+			}
+		}
+		s.jobs = append(s.jobs, &scheduledJob{job: job, schedule: schedule, run: run})
+	}
+	return s, nil
+}
+
+// Run the jobs according to their schedules until ctx is cancelled, then wait for running jobs to
+// return.
+//
+// Each job runs in its own goroutine, so a job that runs for longer than the interval between its
+// scheduled times may run concurrently with itself.
+func (s *Scheduler) Run(ctx context.Context) error {
+	wg := sync.WaitGroup{}
+	defer wg.Wait()
+	now := time.Now()
+	next := make([]time.Time, len(s.jobs))
+	for j, job := range s.jobs {
+		next[j] = job.schedule.Next(now)
+	}
+	for {
+		earliest := time.Time{}
+		for _, t := range next {
+			if !t.IsZero() && (earliest.IsZero() || t.Before(earliest)) {
+				earliest = t
+			}
+		}
+		if earliest.IsZero() {
+			<-ctx.Done()
+			return nil
+		}
+		timer := time.NewTimer(time.Until(earliest))
+		select {
+		case <-ctx.Done():
+			timer.Stop()
+			return nil
+		case now = <-timer.C:
+		}
+		for j, job := range s.jobs {
+			if next[j].IsZero() || next[j].After(now) {
+				continue
+			}
+			next[j] = job.schedule.Next(now)
+			wg.Add(1)
+			go func(job *scheduledJob) {
+				defer wg.Done()
+				s.runJob(ctx, job)
+			}(job)
+		}
+	}
+}
+
+// runJob runs job once, reporting any error or panic to OnError.
+func (s *Scheduler) runJob(ctx context.Context, job *scheduledJob) {
+	var err error
+	defer func() {
+		if p := recover(); p != nil {
+			err = fmt.Errorf("panic: %v", p)
+		}
+		if err != nil && s.OnError != nil {
+			s.OnError(job.job, err)
+		}
+	}()
+	_, err = s.injector.CallWith(job.run.Interface(), ctx)
+}
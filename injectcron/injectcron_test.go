@@ -0,0 +1,98 @@
+package injectcron
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/alecthomas/inject"
+)
+
+type counter struct{ n int32 }
+
+type countingJob struct{}
+
+func (countingJob) Schedule() string { return "@every 10ms" }
+
+func (countingJob) Run(ctx context.Context, c *counter) error {
+	atomic.AddInt32(&c.n, 1)
+	return nil
+}
+
+type failingJob struct{}
+
+func (failingJob) Schedule() string { return "@every 10ms" }
+
+func (failingJob) Run(ctx context.Context) error { return fmt.Errorf("failed") }
+
+func TestModule(t *testing.T) {
+	lock := sync.Mutex{}
+	errs := []error{}
+	c := &counter{}
+	injector := inject.SafeNew()
+	require.NoError(t, injector.Install(&Module{OnError: func(job ScheduledJob, err error) {
+		lock.Lock()
+		defer lock.Unlock()
+		errs = append(errs, err)
+	}}))
+	require.NoError(t, injector.Bind(c, inject.Sequence([]ScheduledJob{countingJob{}, failingJob{}})))
+
+	ctx, cancel := context.WithTimeout(context.Background(), 100*time.Millisecond)
+	defer cancel()
+	require.NoError(t, injector.Run(ctx))
+	require.True(t, atomic.LoadInt32(&c.n) >= 3, "ran %d times", c.n)
+	lock.Lock()
+	defer lock.Unlock()
+	require.NotEmpty(t, errs)
+	require.EqualError(t, errs[0], "failed")
+}
+
+type invalidJob struct{ schedule string }
+
+func (i invalidJob) Schedule() string { return i.schedule }
+
+func (invalidJob) Run(ctx context.Context, c *counter) {}
+
+func TestNewSchedulerErrors(t *testing.T) {
+	injector := inject.SafeNew()
+	_, err := NewScheduler(injector, nil, invalidJob{"* * *"})
+	require.EqualError(t, err, `job injectcron.invalidJob: invalid schedule "* * *": expected 5 fields but got 3`)
+	_, err = NewScheduler(injector, nil, invalidJob{"@daily"})
+	require.EqualError(t, err, "job injectcron.invalidJob: Run method must be of the form Run(ctx context.Context, ...) error but got func(context.Context, *injectcron.counter)")
+	_, err = NewScheduler(injector, nil, countingJob{})
+	require.EqualError(t, err, "job injectcron.countingJob: Run method requires unbound *injectcron.counter")
+}
+
+func TestParse(t *testing.T) {
+	// Wednesday.
+	now := time.Date(2024, 1, 10, 12, 34, 56, 0, time.UTC)
+	tests := []struct {
+		spec string
+		next time.Time
+	}{
+		{"* * * * *", time.Date(2024, 1, 10, 12, 35, 0, 0, time.UTC)},
+		{"*/15 * * * *", time.Date(2024, 1, 10, 12, 45, 0, 0, time.UTC)},
+		{"0 6 * * 1-5", time.Date(2024, 1, 11, 6, 0, 0, 0, time.UTC)},
+		{"30 9 * * 7", time.Date(2024, 1, 14, 9, 30, 0, 0, time.UTC)},
+		{"0 0 1,15 * *", time.Date(2024, 1, 15, 0, 0, 0, 0, time.UTC)},
+		{"0 0 1 * 5", time.Date(2024, 1, 12, 0, 0, 0, 0, time.UTC)},
+		{"0 0 29 2 *", time.Date(2024, 2, 29, 0, 0, 0, 0, time.UTC)},
+		{"@monthly", time.Date(2024, 2, 1, 0, 0, 0, 0, time.UTC)},
+		{"@every 90s", now.Add(90 * time.Second)},
+		{"0 0 30 2 *", time.Time{}},
+	}
+	for _, test := range tests {
+		schedule, err := Parse(test.spec)
+		require.NoError(t, err, test.spec)
+		require.Equal(t, test.next, schedule.Next(now), test.spec)
+	}
+	for _, spec := range []string{"60 * * * *", "* * * * * *", "*/0 * * * *", "5-1 * * * *", "@every -1s", "@often"} {
+		_, err := Parse(spec)
+		require.Error(t, err, spec)
+	}
+}
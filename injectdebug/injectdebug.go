@@ -0,0 +1,182 @@
+// Package injectdebug serves the live wiring of an injector over HTTP, in the manner of expvar and
+// net/http/pprof.
+//
+//	http.Handle("/debug/inject", injectdebug.NewHandler(injector.Safe()))
+//
+// The handler renders an HTML page by default, or JSON if the request has a "format=json" query
+// parameter or accepts only application/json.
+package injectdebug
+
+import (
+	"encoding/json"
+	"html/template"
+	"net/http"
+	"reflect"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/alecthomas/inject"
+)
+
+// Stats are the provider calls made for a type since the Handler was created.
+type Stats struct {
+	Builds    uint64        `json:"builds"`
+	Failures  uint64        `json:"failures"`
+	CacheHits uint64        `json:"cache_hits"`
+	Duration  time.Duration `json:"duration_ns"` // Total duration of all builds.
+}
+
+// Binding describes a single binding of the injector.
+type Binding struct {
+	Key         string   `json:"key"`
+	Provider    string   `json:"provider,omitempty"`
+	Annotation  string   `json:"annotation,omitempty"`
+	Description string   `json:"description,omitempty"`
+	Module      string   `json:"module,omitempty"`
+	Requires    []string `json:"requires,omitempty"`
+	Cached      bool     `json:"cached,omitempty"`
+	Stats       Stats    `json:"stats"`
+}
+
+// Snapshot is the state of an injector at a point in time.
+type Snapshot struct {
+	Bindings []Binding `json:"bindings"`
+	// Graph is a Mermaid flowchart of the bindings and their dependencies.
+	Graph string `json:"graph"`
+}
+
+// Handler is an http.Handler serving a Snapshot of an injector.
+type Handler struct {
+	injector *inject.SafeInjector
+	lock     sync.Mutex
+	stats    map[reflect.Type]*Stats
+}
+
+var _ http.Handler = &Handler{}
+
+// NewHandler creates a Handler for injector, recording the provider calls of the injector and its
+// children from now on.
+func NewHandler(injector *inject.SafeInjector) *Handler {
+	h := &Handler{injector: injector, stats: map[reflect.Type]*Stats{}}
+	injector.Observe(observer{h})
+	return h
+}
+
+// Snapshot returns the current state of the injector.
+func (h *Handler) Snapshot() (*Snapshot, error) {
+	modules := map[*inject.Binding]string{}
+	for _, module := range h.injector.Modules() {
+		bindings, err := h.injector.ModuleBindings(module)
+		if err != nil {
+			return nil, err
+		}
+		for _, binding := range bindings {
+			modules[binding] = reflect.TypeOf(module).String()
+		}
+	}
+	graph := &strings.Builder{}
+	if err := h.injector.WriteMermaid(graph); err != nil {
+		return nil, err
+	}
+	h.lock.Lock()
+	defer h.lock.Unlock()
+	snapshot := &Snapshot{Graph: graph.String()}
+	for _, binding := range h.injector.Bindings() {
+		requires := []string{}
+		for _, req := range binding.Requires {
+			requires = append(requires, req.String())
+		}
+		info := Binding{
+			Key:         inject.Key{Type: binding.Provides, Name: binding.Name}.String(),
+			Provider:    binding.Provider(),
+			Annotation:  binding.Annotation(),
+			Description: binding.Description,
+			Module:      modules[binding],
+			Requires:    requires,
+			Cached:      binding.Cached(),
+		}
+		if stats, ok := h.stats[binding.Provides]; ok {
+			info.Stats = *stats
+		}
+		snapshot.Bindings = append(snapshot.Bindings, info)
+	}
+	sort.SliceStable(snapshot.Bindings, func(a, b int) bool {
+		return snapshot.Bindings[a].Key < snapshot.Bindings[b].Key
+	})
+	return snapshot, nil
+}
+
+// ServeHTTP implements http.Handler.
+func (h *Handler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	snapshot, err := h.Snapshot()
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	if r.URL.Query().Get("format") == "json" || r.Header.Get("Accept") == "application/json" {
+		w.Header().Set("Content-Type", "application/json")
+		enc := json.NewEncoder(w)
+		enc.SetIndent("", "  ")
+		_ = enc.Encode(snapshot)
+		return
+	}
+	w.Header().Set("Content-Type", "text/html; charset=utf-8")
+	_ = page.Execute(w, snapshot)
+}
+
+// observer records provider calls in a Handler.
+type observer struct{ h *Handler }
+
+func (o observer) Built(t reflect.Type, duration time.Duration, err error) {
+	o.h.lock.Lock()
+	defer o.h.lock.Unlock()
+	stats := o.h.statsFor(t)
+	stats.Builds++
+	stats.Duration += duration
+	if err != nil {
+		stats.Failures++
+	}
+}
+
+func (o observer) CacheHit(t reflect.Type) {
+	o.h.lock.Lock()
+	defer o.h.lock.Unlock()
+	o.h.statsFor(t).CacheHits++
+}
+
+func (h *Handler) statsFor(t reflect.Type) *Stats {
+	stats, ok := h.stats[t]
+	if !ok {
+		stats = &Stats{}
+		h.stats[t] = stats
+	}
+	return stats
+}
+
+var page = template.Must(template.New("page").Parse(`<!DOCTYPE html>
+<html>
+<head><title>Injector bindings</title></head>
+<body>
+<h1>Bindings</h1>
+<table border="1" cellspacing="0" cellpadding="4">
+<tr><th>Key</th><th>Provider</th><th>Annotation</th><th>Module</th><th>Requires</th><th>Cached</th><th>Builds</th><th>Failures</th><th>Cache hits</th><th>Build time</th></tr>
+{{range .Bindings}}<tr>
+<td>{{.Key}}{{if .Description}}<br><small>{{.Description}}</small>{{end}}</td>
+<td>{{.Provider}}</td>
+<td>{{.Annotation}}</td>
+<td>{{.Module}}</td>
+<td>{{range .Requires}}{{.}}<br>{{end}}</td>
+<td>{{if .Cached}}yes{{end}}</td>
+<td>{{.Stats.Builds}}</td>
+<td>{{.Stats.Failures}}</td>
+<td>{{.Stats.CacheHits}}</td>
+<td>{{.Stats.Duration}}</td>
+</tr>
+{{end}}</table>
+<h1>Graph</h1>
+<pre>{{.Graph}}</pre>
+</body>
+</html>
+`))
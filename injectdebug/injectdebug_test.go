@@ -0,0 +1,51 @@
+package injectdebug
+
+import (
+	"encoding/json"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/alecthomas/inject"
+)
+
+type testModule struct{}
+
+func (testModule) ProvideGreeting(n int) string { return "hello" }
+
+func TestHandler(t *testing.T) {
+	injector := inject.New()
+	injector.Bind(inject.Describe("the answer", inject.Literal(42)))
+	injector.Install(testModule{})
+	handler := NewHandler(injector.Safe())
+	for j := 0; j < 3; j++ {
+		_, err := injector.Safe().Get("")
+		require.NoError(t, err)
+	}
+
+	w := httptest.NewRecorder()
+	handler.ServeHTTP(w, httptest.NewRequest("GET", "/debug/inject?format=json", nil))
+	require.Equal(t, "application/json", w.Header().Get("Content-Type"))
+	snapshot := &Snapshot{}
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), snapshot))
+	var greeting *Binding
+	for j, binding := range snapshot.Bindings {
+		if binding.Key == "string" {
+			greeting = &snapshot.Bindings[j]
+		}
+	}
+	require.NotNil(t, greeting)
+	require.Equal(t, "Singleton", greeting.Annotation)
+	require.Equal(t, "*injectdebug.testModule", greeting.Module)
+	require.Equal(t, []string{"int"}, greeting.Requires)
+	require.True(t, greeting.Cached)
+	require.Equal(t, uint64(1), greeting.Stats.Builds)
+	require.Equal(t, uint64(2), greeting.Stats.CacheHits)
+	require.Contains(t, snapshot.Graph, "flowchart")
+
+	w = httptest.NewRecorder()
+	handler.ServeHTTP(w, httptest.NewRequest("GET", "/debug/inject", nil))
+	require.Contains(t, w.Body.String(), "the answer")
+	require.Contains(t, w.Body.String(), "injectdebug.testModule.ProvideGreeting")
+}
@@ -0,0 +1,307 @@
+// Package injectdig adapts constructors written for go.uber.org/dig to inject, and exports inject
+// bindings to a dig container.
+//
+// This is intended to ease migration between the two: modules written in either style can be
+// used from the other without being rewritten.
+//
+// Provide() does not support named values. Value groups are mapped to inject Sequences of the
+// group's element type, so the group name itself is ignored. Export() provides named bindings as
+// dig named values, and named Sequences as value groups of that name.
+package injectdig
+
+import (
+	"fmt"
+	"reflect"
+	"strings"
+	"sync"
+
+	"go.uber.org/dig"
+
+	"github.com/alecthomas/inject"
+)
+
+var (
+	errorType   = reflect.TypeOf((*error)(nil)).Elem()
+	inType      = reflect.TypeOf(dig.In{})
+	outType     = reflect.TypeOf(dig.Out{})
+	callResults = reflect.TypeOf(constructorResults{})
+)
+
+// Provide binds dig-style constructors to the injector.
+//
+// A constructor is a function accepting any number of dependencies, including structs embedding
+// dig.In, and returning one or more values, including structs embedding dig.Out, optionally
+// followed by an error. As with dig, each constructor is called at most once.
+func Provide(binder inject.SafeBinder, constructors ...interface{}) error {
+	for _, ctor := range constructors {
+		annotations, err := annotate(ctor)
+		if err != nil {
+			return err
+		}
+		if err := binder.Bind(annotations...); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// Export provides every binding of the injector to the dig container.
+//
+// Named bindings are provided with dig.Name(), except for named Sequences, whose elements are
+// provided to the value group of that name. Values are built by the injector when the container
+// first requests them.
+func Export(injector *inject.SafeInjector, container *dig.Container) error {
+	for _, binding := range injector.Bindings() {
+		binding := binding
+		key := inject.Key{Type: binding.Provides, Name: binding.Name}
+		ft := reflect.FuncOf(nil, []reflect.Type{binding.Provides, errorType}, false)
+		fn := reflect.MakeFunc(ft, func([]reflect.Value) []reflect.Value {
+			// Resolve through the injector, so that its middleware and resolution checks apply.
+			v, err := injector.GetKey(key)
+			if err != nil {
+				return []reflect.Value{reflect.Zero(binding.Provides), reflect.ValueOf(&err).Elem()}
+			}
+			rv := reflect.Zero(binding.Provides)
+			if v != nil {
+				rv = reflect.ValueOf(v)
+			}
+			return []reflect.Value{rv, reflect.Zero(errorType)}
+		})
+		options := []dig.ProvideOption{}
+		switch {
+		case binding.Name != "" && binding.Annotation() == "Sequence":
+			options = append(options, dig.Group(binding.Name+",flatten"))
+		case binding.Name != "":
+			options = append(options, dig.Name(binding.Name))
+		}
+		if err := container.Provide(fn.Interface(), options...); err != nil {
+			return fmt.Errorf("could not export %s: %s", key, err)
+		}
+	}
+	return nil
+}
+
+type field struct {
+	index    int
+	t        reflect.Type
+	optional bool
+}
+
+type param struct {
+	t      reflect.Type
+	fields []field // Set if the parameter is a dig.In struct.
+}
+
+// constructor calls a dig-style constructor at most once, caching its results in a Singleton(), so
+// that a constructor requested again while resolving its own dependencies is reported as a
+// recursive binding rather than deadlocking.
+type constructor struct {
+	fn       reflect.Value
+	params   []param
+	requires []reflect.Type
+	hasError bool
+
+	lock sync.Mutex
+	once *inject.Binding // Singleton() calling the constructor, built on first use.
+}
+
+// constructorResults are the values returned by a constructor.
+type constructorResults []reflect.Value
+
+func annotate(ctor interface{}) ([]interface{}, error) {
+	fn := reflect.ValueOf(ctor)
+	ft := fn.Type()
+	if ft.Kind() != reflect.Func {
+		return nil, fmt.Errorf("dig constructor must be a function but got %s", ft)
+	}
+	c := &constructor{fn: fn}
+	for j := 0; j < ft.NumIn(); j++ {
+		p, err := c.parseParam(ft.In(j))
+		if err != nil {
+			return nil, fmt.Errorf("invalid parameter %d of %s: %s", j+1, ft, err)
+		}
+		c.params = append(c.params, p)
+	}
+	outs := ft.NumOut()
+	if outs > 0 && ft.Out(outs-1) == errorType {
+		c.hasError = true
+		outs--
+	}
+	if outs == 0 {
+		return nil, fmt.Errorf("dig constructor %s must return at least one value", ft)
+	}
+	annotations := []interface{}{}
+	for j := 0; j < outs; j++ {
+		rt := ft.Out(j)
+		if !dig.IsOut(rt) {
+			annotations = append(annotations, &result{c: c, t: rt, index: j, field: -1})
+			continue
+		}
+		for k := 0; k < rt.NumField(); k++ {
+			f := rt.Field(k)
+			if f.Type == outType {
+				continue
+			}
+			if f.Tag.Get("name") != "" {
+				return nil, fmt.Errorf("%s.%s: named values are not supported", rt, f.Name)
+			}
+			r := &result{c: c, t: f.Type, index: j, field: k}
+			group := f.Tag.Get("group")
+			switch {
+			case group == "":
+				annotations = append(annotations, r)
+			case strings.HasSuffix(group, ",flatten"):
+				if f.Type.Kind() != reflect.Slice {
+					return nil, fmt.Errorf("%s.%s: flattened group must be a slice", rt, f.Name)
+				}
+				annotations = append(annotations, inject.Sequence(r))
+			default:
+				r.t = reflect.SliceOf(f.Type)
+				r.group = true
+				annotations = append(annotations, inject.Sequence(r))
+			}
+		}
+	}
+	return annotations, nil
+}
+
+func (c *constructor) parseParam(t reflect.Type) (param, error) {
+	if !dig.IsIn(t) {
+		c.requires = append(c.requires, t)
+		return param{t: t}, nil
+	}
+	p := param{t: t, fields: []field{}}
+	for k := 0; k < t.NumField(); k++ {
+		f := t.Field(k)
+		if f.Type == inType {
+			continue
+		}
+		if f.PkgPath != "" {
+			return p, fmt.Errorf("%s.%s: dig.In fields must be exported", t, f.Name)
+		}
+		if f.Tag.Get("name") != "" {
+			return p, fmt.Errorf("%s.%s: named values are not supported", t, f.Name)
+		}
+		// Empty groups are valid in dig, so groups are always optional.
+		group := f.Tag.Get("group") != ""
+		if group && f.Type.Kind() != reflect.Slice {
+			return p, fmt.Errorf("%s.%s: group must be a slice", t, f.Name)
+		}
+		optional := group || f.Tag.Get("optional") == "true"
+		if !optional {
+			c.requires = append(c.requires, f.Type)
+		}
+		p.fields = append(p.fields, field{index: k, t: f.Type, optional: optional})
+	}
+	return p, nil
+}
+
+// singleton returns the Singleton() binding calling the constructor with dependencies from i, the
+// injector its first result is bound to.
+func (c *constructor) singleton(i *inject.SafeInjector) (*inject.Binding, error) {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+	if c.once == nil {
+		once, err := inject.Singleton(func(i *inject.SafeInjector) (constructorResults, error) { return c.call(i) }).Build(i)
+		if err != nil {
+			return nil, err
+		}
+		c.once = once
+	}
+	return c.once, nil
+}
+
+func (c *constructor) call(i *inject.SafeInjector) (constructorResults, error) {
+	args := []reflect.Value{}
+	for _, p := range c.params {
+		if p.fields == nil {
+			v, err := get(i, p.t)
+			if err != nil {
+				return nil, err
+			}
+			args = append(args, v)
+			continue
+		}
+		in := reflect.New(p.t).Elem()
+		for _, f := range p.fields {
+			// Optional fields are left unset if unbound, but any other error fails the constructor.
+			if f.optional && !i.Has(inject.Key{Type: f.t}) {
+				continue
+			}
+			v, err := get(i, f.t)
+			if err != nil {
+				return nil, err
+			}
+			in.Field(f.index).Set(v)
+		}
+		args = append(args, in)
+	}
+	results := c.fn.Call(args)
+	if c.hasError {
+		last := results[len(results)-1]
+		if !last.IsNil() {
+			return nil, last.Interface().(error)
+		}
+	}
+	return results, nil
+}
+
+// get a value of type t from the injector.
+func get(i *inject.SafeInjector, t reflect.Type) (reflect.Value, error) {
+	var v interface{}
+	var err error
+	if t.Kind() == reflect.Interface {
+		v, err = i.Get(reflect.New(t).Interface())
+	} else {
+		v, err = i.Get(reflect.Zero(t).Interface())
+	}
+	if err != nil {
+		return reflect.Value{}, err
+	}
+	if v == nil {
+		return reflect.Zero(t), nil
+	}
+	return reflect.ValueOf(v), nil
+}
+
+// result binds a single value produced by a constructor.
+type result struct {
+	c     *constructor
+	t     reflect.Type
+	index int
+	field int
+	group bool
+}
+
+func (r *result) Build(i *inject.SafeInjector) (*inject.Binding, error) {
+	once, err := r.c.singleton(i)
+	if err != nil {
+		return &inject.Binding{}, err
+	}
+	// The result is built by the constructor's Singleton(), as part of the request for the result.
+	binding := *once
+	binding.Provides = r.t
+	binding.Requires = r.c.requires
+	binding.Wrap(func(build func() (interface{}, error)) (interface{}, error) {
+		results, err := build()
+		if berr, ok := err.(*inject.BuildError); ok && berr.Key.Type == callResults {
+			// Report errors from the constructor or its dependencies as-is.
+			return nil, berr.Err
+		} else if err != nil {
+			return nil, err
+		}
+		v := results.(constructorResults)[r.index]
+		if r.field >= 0 {
+			v = v.Field(r.field)
+		}
+		if r.group {
+			v = reflect.Append(reflect.MakeSlice(r.t, 0, 1), v)
+		}
+		return v.Interface(), nil
+	})
+	return &binding, nil
+}
+
+func (r *result) Is(annotation inject.Annotation) bool {
+	return reflect.TypeOf(annotation) == reflect.TypeOf(r)
+}
@@ -0,0 +1,171 @@
+package injectdig
+
+import (
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+	"go.uber.org/dig"
+
+	"github.com/alecthomas/inject"
+)
+
+type config struct{ name string }
+
+type params struct {
+	dig.In
+
+	Config   *config
+	Count    int      `optional:"true"`
+	Handlers []string `group:"handlers"`
+}
+
+type results struct {
+	dig.Out
+
+	Greeting fmt.Stringer
+	Handler  string   `group:"handlers"`
+	Handlers []string `group:"handlers,flatten"`
+}
+
+type greeting string
+
+func (g greeting) String() string { return string(g) }
+
+func TestProvide(t *testing.T) {
+	i := inject.SafeNew()
+	calls := 0
+	err := Provide(i,
+		func() *config { return &config{name: "bob"} },
+		func(c *config) results {
+			calls++
+			return results{
+				Greeting: greeting("hello " + c.name),
+				Handler:  "a",
+				Handlers: []string{"b", "c"},
+			}
+		},
+	)
+	require.NoError(t, err)
+	_, err = i.Call(func(g fmt.Stringer, s []string) {
+		require.Equal(t, "hello bob", g.String())
+		require.Equal(t, []string{"a", "b", "c"}, s)
+	})
+	require.NoError(t, err)
+	require.Equal(t, 1, calls)
+}
+
+func TestProvideRequiresResult(t *testing.T) {
+	err := Provide(inject.SafeNew(), func() error { return nil })
+	require.Error(t, err)
+}
+
+func TestProvideParams(t *testing.T) {
+	i := inject.SafeNew()
+	err := Provide(i,
+		func() *config { return &config{name: "bob"} },
+		func(p params) greeting {
+			require.Equal(t, 0, p.Count)
+			require.Empty(t, p.Handlers)
+			return greeting(p.Config.name)
+		},
+	)
+	require.NoError(t, err)
+	v, err := i.Get(greeting(""))
+	require.NoError(t, err)
+	require.Equal(t, greeting("bob"), v)
+}
+
+func TestProvideOptionalFailure(t *testing.T) {
+	i := inject.SafeNew()
+	require.NoError(t, i.Bind(func() (int, error) { return 0, fmt.Errorf("failed") }))
+	err := Provide(i, func(p params) greeting { return greeting("") })
+	require.NoError(t, err)
+	require.NoError(t, i.Bind(&config{}))
+	_, err = i.Get(greeting(""))
+	require.Error(t, err)
+	require.Contains(t, err.Error(), "failed")
+}
+
+func TestExportMiddleware(t *testing.T) {
+	i := inject.SafeNew()
+	require.NoError(t, i.Bind(&config{name: "bob"}))
+	i.Use(func(req inject.Request, next inject.Next) (interface{}, error) {
+		v, err := next()
+		if c, ok := v.(*config); ok {
+			return &config{name: "wrapped " + c.name}, err
+		}
+		return v, err
+	})
+	c := dig.New()
+	require.NoError(t, Export(i, c))
+	err := c.Invoke(func(cfg *config) {
+		require.Equal(t, "wrapped bob", cfg.name)
+	})
+	require.NoError(t, err)
+}
+
+func TestProvideNamedUnsupported(t *testing.T) {
+	type named struct {
+		dig.In
+		Config *config `name:"primary"`
+	}
+	err := Provide(inject.SafeNew(), func(named) int { return 1 })
+	require.Error(t, err)
+}
+
+func TestExport(t *testing.T) {
+	i := inject.SafeNew()
+	err := i.Bind(func() *config { return &config{name: "bob"} })
+	require.NoError(t, err)
+	c := dig.New()
+	err = Export(i, c)
+	require.NoError(t, err)
+	err = c.Invoke(func(cfg *config) {
+		require.Equal(t, "bob", cfg.name)
+	})
+	require.NoError(t, err)
+}
+
+func TestExportNamed(t *testing.T) {
+	i := inject.SafeNew()
+	require.NoError(t, i.Bind(&config{name: "primary"}, inject.Name("primary")))
+	require.NoError(t, i.Bind(inject.Sequence([]string{"a", "b"}), inject.Name("handlers")))
+	c := dig.New()
+	require.NoError(t, Export(i, c))
+	type in struct {
+		dig.In
+
+		Config   *config  `name:"primary"`
+		Handlers []string `group:"handlers"`
+	}
+	err := c.Invoke(func(p in) {
+		require.Equal(t, "primary", p.Config.name)
+		require.ElementsMatch(t, []string{"a", "b"}, p.Handlers)
+	})
+	require.NoError(t, err)
+}
+
+func TestProvideReentrant(t *testing.T) {
+	i := inject.SafeNew()
+	// The *config provider requests the other result of the constructor that requires it.
+	require.NoError(t, i.Bind(func(i *inject.SafeInjector) (*config, error) {
+		if _, err := i.Get(greeting("")); err != nil {
+			return nil, err
+		}
+		return &config{}, nil
+	}))
+	require.NoError(t, Provide(i, func(*config) (fmt.Stringer, greeting) { return greeting(""), greeting("") }))
+	done := make(chan error, 1)
+	go func() {
+		_, err := i.Get((*fmt.Stringer)(nil))
+		done <- err
+	}()
+	select {
+	case err := <-done:
+		require.Contains(t, err.Error(), "recursive binding injectdig.constructorResults")
+	case <-time.After(5 * time.Second):
+		t.Fatal("resolving the constructor's dependencies deadlocked")
+	}
+}
@@ -0,0 +1,106 @@
+// Package injecthealth aggregates the health checks of installed modules and bound components into
+// a single injectable Registry.
+//
+// Any installed module with a CheckHealth(context.Context) error method is checked, as is every
+// value bound in a Sequence() of a type implementing Checker:
+//
+//	injector.Install(&injecthealth.Module{}, &DatabaseModule{})
+//	injector.Call(func(health *injecthealth.Registry) {
+//		http.Handle("/healthz", health)
+//	})
+package injecthealth
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+
+	"github.com/alecthomas/inject"
+)
+
+// A Checker reports the health of a component.
+type Checker interface {
+	CheckHealth(ctx context.Context) error
+}
+
+// Module provides a *Registry of the health checks in the injector it is installed in.
+type Module struct{}
+
+// ProvideRegistry builds the Registry from installed modules and bound Checkers.
+func (m *Module) ProvideRegistry(injector *inject.SafeInjector, checkers []Checker) *Registry {
+	registry := &Registry{}
+	for _, module := range injector.Modules() {
+		if checker, ok := module.(Checker); ok {
+			registry.Register(fmt.Sprintf("%T", module), checker)
+		}
+	}
+	for _, checker := range checkers {
+		registry.Register(fmt.Sprintf("%T", checker), checker)
+	}
+	return registry
+}
+
+type namedChecker struct {
+	name    string
+	checker Checker
+}
+
+// A Registry of health checks.
+type Registry struct {
+	checkers []namedChecker
+}
+
+// Register adds a named health check to the registry.
+func (r *Registry) Register(name string, checker Checker) {
+	r.checkers = append(r.checkers, namedChecker{name, checker})
+}
+
+// Result is the outcome of a single health check.
+type Result struct {
+	Name string
+	Err  error
+}
+
+// Check runs all health checks in the order they were registered.
+func (r *Registry) Check(ctx context.Context) []Result {
+	results := make([]Result, 0, len(r.checkers))
+	for _, c := range r.checkers {
+		results = append(results, Result{Name: c.name, Err: c.checker.CheckHealth(ctx)})
+	}
+	return results
+}
+
+// CheckHealth runs all health checks, returning an inject.Errors of any failures.
+//
+// A Registry is itself a Checker, so registries may be nested.
+func (r *Registry) CheckHealth(ctx context.Context) error {
+	errs := inject.Errors{}
+	for _, result := range r.Check(ctx) {
+		if result.Err != nil {
+			errs = append(errs, fmt.Errorf("%s: %s", result.Name, result.Err))
+		}
+	}
+	if len(errs) > 0 {
+		return errs
+	}
+	return nil
+}
+
+// ServeHTTP reports the result of each health check as a JSON object mapping check names to "ok"
+// or an error message. The response status is 503 if any check fails.
+func (r *Registry) ServeHTTP(w http.ResponseWriter, req *http.Request) {
+	status := http.StatusOK
+	body := map[string]string{}
+	for _, result := range r.Check(req.Context()) {
+		if result.Err != nil {
+			status = http.StatusServiceUnavailable
+			body[result.Name] = result.Err.Error()
+		} else {
+			body[result.Name] = "ok"
+		}
+	}
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	_ = json.NewEncoder(w).Encode(body)
+}
@@ -0,0 +1,42 @@
+package injecthealth
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/alecthomas/inject"
+)
+
+type databaseModule struct{ err error }
+
+func (d *databaseModule) CheckHealth(ctx context.Context) error { return d.err }
+
+type cache struct{}
+
+func (cache) CheckHealth(ctx context.Context) error { return fmt.Errorf("cache unreachable") }
+
+func TestRegistry(t *testing.T) {
+	injector := inject.SafeNew()
+	db := &databaseModule{}
+	require.NoError(t, injector.Install(&Module{}, db))
+	require.NoError(t, injector.Bind(inject.Sequence([]cache{{}})))
+
+	v, err := injector.Get(&Registry{})
+	require.NoError(t, err)
+	registry := v.(*Registry)
+	require.Equal(t, []Result{
+		{Name: "*injecthealth.databaseModule"},
+		{Name: "injecthealth.cache", Err: fmt.Errorf("cache unreachable")},
+	}, registry.Check(context.Background()))
+	require.EqualError(t, registry.CheckHealth(context.Background()), "injecthealth.cache: cache unreachable")
+
+	w := httptest.NewRecorder()
+	registry.ServeHTTP(w, httptest.NewRequest("GET", "/healthz", nil))
+	require.Equal(t, http.StatusServiceUnavailable, w.Code)
+	require.JSONEq(t, `{"*injecthealth.databaseModule": "ok", "injecthealth.cache": "cache unreachable"}`, w.Body.String())
+}
@@ -0,0 +1,128 @@
+// Package injecthttp serves the HTTP routes contributed by modules.
+//
+// Modules contribute routes with a Sequence() of Route, such as from a provider method whose name
+// contains "Sequence", and the Module provides a *http.ServeMux serving all of them:
+//
+//	func (u *UsersModule) ProvideRoutesSequence() []injecthttp.Route {
+//		return []injecthttp.Route{
+//			{Method: "GET", Path: "/users/", Handler: func(w http.ResponseWriter, r *http.Request, users *UserStore) error { ... }},
+//		}
+//	}
+//
+//	injector.Install(&injecthttp.Module{}, &UsersModule{})
+//	injector.Call(func(mux *http.ServeMux) error {
+//		return http.ListenAndServe(":8080", mux)
+//	})
+package injecthttp
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"reflect"
+	"sort"
+	"strings"
+
+	"github.com/alecthomas/inject"
+)
+
+// A Route is an HTTP handler for a method and path.
+//
+// Handler is either an http.Handler, a func(http.ResponseWriter, *http.Request), or a function
+// returning nothing or an error whose parameters are injected for each request. Such functions may
+// also accept the http.ResponseWriter, *http.Request and the request's context.Context. An error
+// returned by the function is reported to the client as an internal server error.
+type Route struct {
+	Method  string // Empty to match any method.
+	Path    string // Pattern as for http.ServeMux.
+	Handler interface{}
+}
+
+// Module provides a *http.ServeMux serving the routes bound in the injector it is installed in.
+type Module struct{}
+
+// ProvideMux builds a *http.ServeMux from the bound routes.
+func (m *Module) ProvideMux(injector *inject.SafeInjector, routes ...Route) (*http.ServeMux, error) {
+	return NewMux(injector, routes...)
+}
+
+// NewMux returns a *http.ServeMux serving routes, injecting the parameters of handler functions
+// from injector. Requests with a method that no route of the path matches are rejected with
+// "405 Method Not Allowed".
+func NewMux(injector *inject.SafeInjector, routes ...Route) (*http.ServeMux, error) {
+	paths := []string{}
+	methods := map[string]methodHandler{}
+	for _, route := range routes {
+		handler, err := handlerFor(injector, route.Handler)
+		if err != nil {
+			return nil, fmt.Errorf("route %s: %s", route, err)
+		}
+		if _, ok := methods[route.Path]; !ok {
+			paths = append(paths, route.Path)
+			methods[route.Path] = methodHandler{}
+		}
+		method := strings.ToUpper(route.Method)
+		if _, ok := methods[route.Path][method]; ok {
+			return nil, fmt.Errorf("route %s is already registered", route)
+		}
+		methods[route.Path][method] = handler
+	}
+	mux := http.NewServeMux()
+	for _, path := range paths {
+		mux.Handle(path, methods[path])
+	}
+	return mux, nil
+}
+
+func (r Route) String() string {
+	if r.Method == "" {
+		return r.Path
+	}
+	return strings.ToUpper(r.Method) + " " + r.Path
+}
+
+// methodHandler dispatches requests for a path by method, with "" matching any method.
+type methodHandler map[string]http.Handler
+
+func (m methodHandler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	handler, ok := m[r.Method]
+	if !ok {
+		handler, ok = m[""]
+	}
+	if !ok {
+		allowed := []string{}
+		for method := range m {
+			allowed = append(allowed, method)
+		}
+		sort.Strings(allowed)
+		w.Header().Set("Allow", strings.Join(allowed, ", "))
+		http.Error(w, http.StatusText(http.StatusMethodNotAllowed), http.StatusMethodNotAllowed)
+		return
+	}
+	handler.ServeHTTP(w, r)
+}
+
+var errorType = reflect.TypeOf((*error)(nil)).Elem()
+
+// handlerFor returns an http.Handler for handler, which is described by Route.
+func handlerFor(injector *inject.SafeInjector, handler interface{}) (http.Handler, error) {
+	switch handler := handler.(type) {
+	case http.Handler:
+		return handler, nil
+	case func(http.ResponseWriter, *http.Request):
+		return http.HandlerFunc(handler), nil
+	}
+	ft := reflect.TypeOf(handler)
+	if ft == nil || ft.Kind() != reflect.Func {
+		return nil, fmt.Errorf("handler must be an http.Handler or a function but got %T", handler)
+	}
+	if ft.NumOut() > 1 || (ft.NumOut() == 1 && ft.Out(0) != errorType) {
+		return nil, fmt.Errorf("handler function must return nothing or an error but got %s", ft)
+	}
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var ctx context.Context = r.Context()
+		if _, err := injector.CallWith(handler, w, r, ctx); err != nil {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+		}
+	}), nil
+}
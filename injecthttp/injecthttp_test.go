@@ -0,0 +1,81 @@
+package injecthttp
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/alecthomas/inject"
+)
+
+type userStore struct{ users map[string]string }
+
+type usersModule struct{}
+
+func (usersModule) ProvideUsers() *userStore {
+	return &userStore{users: map[string]string{"/users/alice": "Alice"}}
+}
+
+func (usersModule) ProvideRoutesSequence() []Route {
+	return []Route{
+		{Method: "GET", Path: "/users/", Handler: func(w http.ResponseWriter, r *http.Request, ctx context.Context, users *userStore) error {
+			name, ok := users.users[r.URL.Path]
+			if !ok {
+				return fmt.Errorf("no user %s", r.URL.Path)
+			}
+			fmt.Fprint(w, name)
+			return nil
+		}},
+		{Method: "delete", Path: "/users/", Handler: http.NotFoundHandler()},
+	}
+}
+
+type statusModule struct{}
+
+func (statusModule) ProvideRoutesSequence() []Route {
+	return []Route{
+		{Path: "/status", Handler: func(w http.ResponseWriter, r *http.Request) { fmt.Fprint(w, "ok") }},
+	}
+}
+
+func serve(mux *http.ServeMux, method, path string) *httptest.ResponseRecorder {
+	w := httptest.NewRecorder()
+	mux.ServeHTTP(w, httptest.NewRequest(method, path, nil))
+	return w
+}
+
+func TestModule(t *testing.T) {
+	injector := inject.SafeNew()
+	require.NoError(t, injector.Install(&Module{}, usersModule{}, statusModule{}))
+	v, err := injector.Get(&http.ServeMux{})
+	require.NoError(t, err)
+	mux := v.(*http.ServeMux)
+
+	w := serve(mux, "GET", "/users/alice")
+	require.Equal(t, http.StatusOK, w.Code)
+	require.Equal(t, "Alice", w.Body.String())
+	w = serve(mux, "GET", "/users/bob")
+	require.Equal(t, http.StatusInternalServerError, w.Code)
+	require.Equal(t, "no user /users/bob\n", w.Body.String())
+	require.Equal(t, http.StatusNotFound, serve(mux, "DELETE", "/users/alice").Code)
+	w = serve(mux, "POST", "/users/alice")
+	require.Equal(t, http.StatusMethodNotAllowed, w.Code)
+	require.Equal(t, "DELETE, GET", w.Header().Get("Allow"))
+	require.Equal(t, "ok", serve(mux, "POST", "/status").Body.String())
+}
+
+func TestNewMuxErrors(t *testing.T) {
+	injector := inject.SafeNew()
+	_, err := NewMux(injector, Route{Method: "GET", Path: "/", Handler: "index"})
+	require.EqualError(t, err, "route GET /: handler must be an http.Handler or a function but got string")
+	_, err = NewMux(injector, Route{Path: "/", Handler: func() int { return 0 }})
+	require.EqualError(t, err, "route /: handler function must return nothing or an error but got func() int")
+	_, err = NewMux(injector,
+		Route{Method: "GET", Path: "/", Handler: http.NotFoundHandler()},
+		Route{Method: "get", Path: "/", Handler: http.NotFoundHandler()})
+	require.EqualError(t, err, "route GET / is already registered")
+}
@@ -0,0 +1,62 @@
+// Package injectkong binds command-line configuration parsed by github.com/alecthomas/kong into
+// an injector, so that flags flow into modules without manual glue.
+//
+//	var cli struct {
+//		Port  int  `help:"Port to listen on." default:"8080"`
+//		Debug bool `help:"Enable debug logging."`
+//	}
+//	ctx := kong.Parse(&cli)
+//	injector := inject.SafeNew()
+//	if err := injectkong.Bind(injector, ctx); err != nil {
+//		ctx.FatalIfErrorf(err)
+//	}
+//	port, err := injector.GetKey(inject.Key{Type: reflect.TypeOf(0), Name: "port"})
+package injectkong
+
+import (
+	"fmt"
+	"reflect"
+
+	"github.com/alecthomas/kong"
+
+	"github.com/alecthomas/inject"
+)
+
+// Bind binds the CLI struct parsed by ctx, and the *kong.Context itself, into binder.
+//
+// Each flag and positional argument of the selected command is also bound as a named value of its
+// field type, using its kong name (eg. "log-level"). Flags without a target, such as --help, are
+// skipped.
+func Bind(binder inject.SafeBinder, ctx *kong.Context) error {
+	if err := binder.Bind(ctx); err != nil {
+		return err
+	}
+	target := ctx.Model.Target
+	if target.CanAddr() {
+		target = target.Addr()
+	}
+	if err := binder.Bind(target.Interface()); err != nil {
+		return err
+	}
+	values := []*kong.Value{}
+	for _, flag := range ctx.Flags() {
+		values = append(values, flag.Value)
+	}
+	if selected := ctx.Selected(); selected != nil {
+		values = append(values, selected.Positional...)
+	} else {
+		values = append(values, ctx.Model.Positional...)
+	}
+	for _, value := range values {
+		if !value.Target.IsValid() || value.Name == "help" {
+			continue
+		}
+		if value.Target.Kind() == reflect.Interface && value.Target.IsNil() {
+			continue
+		}
+		if err := binder.Bind(inject.Literal(value.Target.Interface()), inject.Name(value.Name)); err != nil {
+			return fmt.Errorf("couldn't bind %q: %s", value.Name, err)
+		}
+	}
+	return nil
+}
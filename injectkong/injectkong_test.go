@@ -0,0 +1,47 @@
+package injectkong
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/alecthomas/kong"
+	"github.com/stretchr/testify/require"
+
+	"github.com/alecthomas/inject"
+)
+
+type serveCmd struct {
+	Addr string `arg:"" help:"Address to listen on."`
+}
+
+type cli struct {
+	LogLevel string   `help:"Log level." default:"info"`
+	Verbose  bool     `short:"v"`
+	Serve    serveCmd `cmd:""`
+}
+
+func TestBind(t *testing.T) {
+	var c cli
+	parser, err := kong.New(&c, kong.Exit(func(int) { t.Fatal("exited") }))
+	require.NoError(t, err)
+	ctx, err := parser.Parse([]string{"-v", "serve", ":8080"})
+	require.NoError(t, err)
+
+	injector := inject.SafeNew()
+	require.NoError(t, Bind(injector, ctx))
+
+	v, err := injector.Get(&cli{})
+	require.NoError(t, err)
+	require.True(t, v.(*cli) == &c)
+	_, err = injector.Get(&kong.Context{})
+	require.NoError(t, err)
+
+	_, err = injector.CallKeyed(func(level string, verbose bool) {
+		require.Equal(t, "info", level)
+		require.True(t, verbose)
+	}, inject.Key{Type: reflect.TypeOf(""), Name: "log-level"}, inject.Key{Type: reflect.TypeOf(true), Name: "verbose"})
+	require.NoError(t, err)
+	addr, err := injector.GetKey(inject.Key{Type: reflect.TypeOf(""), Name: "addr"})
+	require.NoError(t, err)
+	require.Equal(t, ":8080", addr)
+}
@@ -0,0 +1,168 @@
+// Package injectplugin composes an injector from plugins, each of which contributes modules.
+//
+// Plugins are either registered in-process, typically from the init() function of an extension
+// package, or loaded from Go plugins built with -buildmode=plugin:
+//
+//	loader := injectplugin.NewLoader()
+//	loader.Register("metrics", &MetricsModule{})
+//	if err := loader.Open("plugins/auth.so"); err != nil { ... }
+//	if err := loader.Install(injector.Safe()); err != nil { ... }
+//
+// Each plugin is installed in isolation before being merged into the injector, so a plugin that
+// binds a key already bound by another plugin or by the injector is reported by name. Sequence()
+// and Mapping() bindings are merged across plugins as usual.
+package injectplugin
+
+import (
+	"fmt"
+	"path/filepath"
+	"plugin"
+	"reflect"
+	"strings"
+
+	"github.com/alecthomas/inject"
+)
+
+// ModulesSymbol is the function a Go plugin must export to contribute modules. It must have the
+// signature func() []interface{}.
+const ModulesSymbol = "InjectModules"
+
+// NameSymbol is an optional string variable a Go plugin may export to name itself. Plugins are
+// otherwise named after their file, without extension.
+const NameSymbol = "InjectName"
+
+// A Plugin is a named set of modules.
+type Plugin struct {
+	Name    string
+	Modules []interface{}
+}
+
+// A Loader collects plugins and installs them into an injector.
+type Loader struct {
+	plugins   []*Plugin
+	installed map[string]bool
+	owners    map[inject.Key]string
+}
+
+// NewLoader creates a new Loader.
+func NewLoader() *Loader {
+	return &Loader{installed: map[string]bool{}, owners: map[inject.Key]string{}}
+}
+
+// Default is the Loader used by the package-level Register() function.
+var Default = NewLoader()
+
+// Register a plugin with the Default Loader. Panics if the name is already registered.
+func Register(name string, modules ...interface{}) {
+	if err := Default.Register(name, modules...); err != nil {
+		panic(err)
+	}
+}
+
+// Register a plugin contributing modules. Plugin names must be unique.
+func (l *Loader) Register(name string, modules ...interface{}) error {
+	if l.Plugin(name) != nil {
+		return fmt.Errorf("plugin %q is already registered", name)
+	}
+	l.plugins = append(l.plugins, &Plugin{Name: name, Modules: modules})
+	return nil
+}
+
+// Open loads a Go plugin from path and registers it. See ModulesSymbol and NameSymbol.
+func (l *Loader) Open(path string) error {
+	p, err := plugin.Open(path)
+	if err != nil {
+		return fmt.Errorf("couldn't open plugin %s: %s", path, err)
+	}
+	name := strings.TrimSuffix(filepath.Base(path), filepath.Ext(path))
+	if sym, err := p.Lookup(NameSymbol); err == nil {
+		ptr, ok := sym.(*string)
+		if !ok {
+			return fmt.Errorf("plugin %s: %s must be a string but is %T", path, NameSymbol, sym)
+		}
+		name = *ptr
+	}
+	sym, err := p.Lookup(ModulesSymbol)
+	if err != nil {
+		return fmt.Errorf("plugin %s: %s", path, err)
+	}
+	modules, ok := sym.(func() []interface{})
+	if !ok {
+		return fmt.Errorf("plugin %s: %s must be a func() []interface{} but is %T", path, ModulesSymbol, sym)
+	}
+	return l.Register(name, modules()...)
+}
+
+// Plugin returns the registered plugin with the given name, or nil.
+func (l *Loader) Plugin(name string) *Plugin {
+	for _, p := range l.plugins {
+		if p.Name == name {
+			return p
+		}
+	}
+	return nil
+}
+
+// Plugins returns the registered plugins, in the order they were registered.
+func (l *Loader) Plugins() []*Plugin {
+	return append([]*Plugin{}, l.plugins...)
+}
+
+// Owner returns the name of the plugin that bound key, if any.
+func (l *Loader) Owner(key inject.Key) (string, bool) {
+	name, ok := l.owners[key]
+	return name, ok
+}
+
+// Install the registered plugins that have not yet been installed into injector, in the order
+// they were registered.
+//
+// Installation stops at the first plugin that fails, leaving any earlier plugins installed.
+func (l *Loader) Install(injector *inject.SafeInjector) error {
+	for _, p := range l.plugins {
+		if l.installed[p.Name] {
+			continue
+		}
+		if err := l.install(injector, p); err != nil {
+			return fmt.Errorf("plugin %s: %s", p.Name, err)
+		}
+		l.installed[p.Name] = true
+	}
+	return nil
+}
+
+var (
+	safeInjectorType = reflect.TypeOf(&inject.SafeInjector{})
+	safeBinderType   = reflect.TypeOf((*inject.SafeBinder)(nil)).Elem()
+)
+
+func (l *Loader) install(injector *inject.SafeInjector, p *Plugin) error {
+	scratch := injector.Child()
+	if err := scratch.Install(p.Modules...); err != nil {
+		return err
+	}
+	keys := []inject.Key{}
+	for _, binding := range scratch.Bindings() {
+		key := inject.Key{Type: binding.Provides, Name: binding.Name}
+		// Every injector binds itself.
+		if key.Type == safeInjectorType || key.Type == safeBinderType {
+			continue
+		}
+		// Sequences and mappings are merged rather than conflicting.
+		if kind := key.Type.Kind(); kind != reflect.Slice && kind != reflect.Map {
+			if owner, ok := l.owners[key]; ok {
+				return fmt.Errorf("%s is already bound by plugin %s", key, owner)
+			}
+		}
+		keys = append(keys, key)
+	}
+	if err := injector.Merge(scratch, inject.ConflictError); err != nil {
+		return err
+	}
+	for _, key := range keys {
+		if _, ok := l.owners[key]; !ok {
+			l.owners[key] = p.Name
+		}
+	}
+	return nil
+}
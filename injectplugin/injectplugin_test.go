@@ -0,0 +1,60 @@
+package injectplugin
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/alecthomas/inject"
+)
+
+type greeting string
+
+type greetingModule struct{}
+
+func (greetingModule) ProvideGreeting() greeting { return "hello" }
+
+func (greetingModule) ProvideNamesSequence() []string { return []string{"greeting"} }
+
+type farewellModule struct{}
+
+func (farewellModule) ProvideFarewell(g greeting) string { return string(g) + ", goodbye" }
+
+func (farewellModule) ProvideNamesSequence() []string { return []string{"farewell"} }
+
+func TestLoader(t *testing.T) {
+	loader := NewLoader()
+	require.NoError(t, loader.Register("greeting", greetingModule{}))
+	require.NoError(t, loader.Register("farewell", farewellModule{}))
+	require.Error(t, loader.Register("greeting", greetingModule{}))
+
+	injector := inject.SafeNew()
+	require.NoError(t, loader.Install(injector))
+	v, err := injector.Get("")
+	require.NoError(t, err)
+	require.Equal(t, "hello, goodbye", v)
+	v, err = injector.Get([]string{})
+	require.NoError(t, err)
+	require.Equal(t, []string{"greeting", "farewell"}, v)
+
+	owner, ok := loader.Owner(inject.Key{Type: reflect.TypeOf(greeting(""))})
+	require.True(t, ok)
+	require.Equal(t, "greeting", owner)
+
+	// Installing again only installs new plugins.
+	require.NoError(t, loader.Register("duplicate", func() greeting { return "hi" }))
+	require.EqualError(t, loader.Install(injector), "plugin duplicate: injectplugin.greeting is already bound by plugin greeting")
+}
+
+func TestLoaderConflictsWithInjector(t *testing.T) {
+	loader := NewLoader()
+	require.NoError(t, loader.Register("greeting", greetingModule{}))
+	injector := inject.SafeNew()
+	require.NoError(t, injector.Bind(greeting("hi")))
+	require.EqualError(t, loader.Install(injector), "plugin greeting: injectplugin.greeting is already bound")
+}
+
+func TestOpenMissingPlugin(t *testing.T) {
+	require.Error(t, NewLoader().Open("missing.so"))
+}
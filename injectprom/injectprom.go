@@ -0,0 +1,133 @@
+// Package injectprom exposes metrics about an injector as Prometheus collectors.
+//
+//	injector.Install(&injectprom.Module{Namespace: "myapp"})
+//
+// The following metrics are exported, labelled by the type of the value built where relevant:
+//
+//	inject_bindings                       Number of bindings in the injector.
+//	inject_builds_total{type}             Number of provider calls.
+//	inject_build_failures_total{type}     Number of provider calls that failed.
+//	inject_build_duration_seconds{type}   Duration of provider calls, including dependencies.
+//	inject_singleton_cache_hits_total{type} Number of singleton values retrieved from cache.
+package injectprom
+
+import (
+	"fmt"
+	"reflect"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+
+	"github.com/alecthomas/inject"
+)
+
+// Module registers a Collector for the injector it is installed in, and binds the *Collector.
+type Module struct {
+	// Namespace prefixes metric names, if set.
+	Namespace string
+	// Registerer to register the Collector with. Defaults to prometheus.DefaultRegisterer.
+	Registerer prometheus.Registerer
+}
+
+// Configure registers the Collector.
+func (m *Module) Configure(binder inject.Binder) error {
+	injector, ok := binder.(*inject.Injector)
+	if !ok {
+		return fmt.Errorf("injectprom.Module must be installed in an *inject.Injector, not %T", binder)
+	}
+	collector := NewCollector(injector.Safe(), m.Namespace)
+	registerer := m.Registerer
+	if registerer == nil {
+		registerer = prometheus.DefaultRegisterer
+	}
+	if err := registerer.Register(collector); err != nil {
+		return err
+	}
+	binder.Bind(collector)
+	return nil
+}
+
+// Collector is a prometheus.Collector of injector metrics.
+type Collector struct {
+	bindings  prometheus.GaugeFunc
+	builds    *prometheus.CounterVec
+	failures  *prometheus.CounterVec
+	durations *prometheus.HistogramVec
+	hits      *prometheus.CounterVec
+}
+
+var _ prometheus.Collector = &Collector{}
+
+// NewCollector creates a Collector observing injector and its children.
+func NewCollector(injector *inject.SafeInjector, namespace string) *Collector {
+	c := &Collector{
+		bindings: prometheus.NewGaugeFunc(prometheus.GaugeOpts{
+			Namespace: namespace,
+			Subsystem: "inject",
+			Name:      "bindings",
+			Help:      "Number of bindings in the injector.",
+		}, func() float64 { return float64(len(injector.Bindings())) }),
+		builds: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Namespace: namespace,
+			Subsystem: "inject",
+			Name:      "builds_total",
+			Help:      "Number of provider calls.",
+		}, []string{"type"}),
+		failures: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Namespace: namespace,
+			Subsystem: "inject",
+			Name:      "build_failures_total",
+			Help:      "Number of provider calls that failed.",
+		}, []string{"type"}),
+		durations: prometheus.NewHistogramVec(prometheus.HistogramOpts{
+			Namespace: namespace,
+			Subsystem: "inject",
+			Name:      "build_duration_seconds",
+			Help:      "Duration of provider calls, including building their dependencies.",
+			Buckets:   prometheus.DefBuckets,
+		}, []string{"type"}),
+		hits: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Namespace: namespace,
+			Subsystem: "inject",
+			Name:      "singleton_cache_hits_total",
+			Help:      "Number of singleton values retrieved from cache.",
+		}, []string{"type"}),
+	}
+	injector.Observe(observer{c})
+	return c
+}
+
+// Describe implements prometheus.Collector.
+func (c *Collector) Describe(ch chan<- *prometheus.Desc) {
+	c.bindings.Describe(ch)
+	c.builds.Describe(ch)
+	c.failures.Describe(ch)
+	c.durations.Describe(ch)
+	c.hits.Describe(ch)
+}
+
+// Collect implements prometheus.Collector.
+func (c *Collector) Collect(ch chan<- prometheus.Metric) {
+	c.bindings.Collect(ch)
+	c.builds.Collect(ch)
+	c.failures.Collect(ch)
+	c.durations.Collect(ch)
+	c.hits.Collect(ch)
+}
+
+// observer records injector activity in a Collector. It is kept separate so that the Observer
+// methods are not part of the Collector's API.
+type observer struct{ c *Collector }
+
+func (o observer) Built(t reflect.Type, duration time.Duration, err error) {
+	label := t.String()
+	o.c.builds.WithLabelValues(label).Inc()
+	o.c.durations.WithLabelValues(label).Observe(duration.Seconds())
+	if err != nil {
+		o.c.failures.WithLabelValues(label).Inc()
+	}
+}
+
+func (o observer) CacheHit(t reflect.Type) {
+	o.c.hits.WithLabelValues(t.String()).Inc()
+}
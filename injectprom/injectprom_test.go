@@ -0,0 +1,51 @@
+package injectprom
+
+import (
+	"fmt"
+	"strings"
+	"testing"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	"github.com/stretchr/testify/require"
+
+	"github.com/alecthomas/inject"
+)
+
+func TestModule(t *testing.T) {
+	registry := prometheus.NewRegistry()
+	injector := inject.New()
+	injector.Install(&Module{Namespace: "test", Registerer: registry})
+	injector.Bind(inject.Singleton(func() int { return 1 }))
+	injector.Bind(func(n int) (string, error) { return "", fmt.Errorf("failed") })
+
+	safe := injector.Safe()
+	for j := 0; j < 3; j++ {
+		_, err := safe.Get(0)
+		require.NoError(t, err)
+	}
+	_, err := safe.Get("")
+	require.Error(t, err)
+
+	expected := `
+# HELP test_inject_build_failures_total Number of provider calls that failed.
+# TYPE test_inject_build_failures_total counter
+test_inject_build_failures_total{type="string"} 1
+# HELP test_inject_builds_total Number of provider calls.
+# TYPE test_inject_builds_total counter
+test_inject_builds_total{type="int"} 1
+test_inject_builds_total{type="string"} 1
+# HELP test_inject_singleton_cache_hits_total Number of singleton values retrieved from cache.
+# TYPE test_inject_singleton_cache_hits_total counter
+test_inject_singleton_cache_hits_total{type="int"} 3
+`
+	require.NoError(t, testutil.GatherAndCompare(registry, strings.NewReader(expected),
+		"test_inject_builds_total", "test_inject_build_failures_total", "test_inject_singleton_cache_hits_total"))
+	count, err := testutil.GatherAndCount(registry, "test_inject_bindings")
+	require.NoError(t, err)
+	require.Equal(t, 1, count)
+
+	v, err := safe.Get(&Collector{})
+	require.NoError(t, err)
+	require.NotNil(t, v)
+}
@@ -0,0 +1,246 @@
+// Package injectproxy generates the proxies used by inject.SafeInjector.Intercept() to wrap the
+// method calls of interfaces.
+//
+// Go can not implement interfaces at runtime, so for each intercepted interface a proxy type is
+// generated that forwards its methods to an inject.ProxyHandler, and is registered with
+// inject.RegisterProxy() from an init() function. The injectproxy command writes the generated
+// code for interfaces in the current package:
+//
+//	//go:generate injectproxy -type Store,Mailer
+package injectproxy
+
+import (
+	"bytes"
+	"fmt"
+	"go/ast"
+	"go/format"
+	"go/parser"
+	"go/token"
+	"os"
+	"path"
+	"sort"
+	"strconv"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+)
+
+// Generate returns the source of a file registering proxies for the named interfaces, declared in
+// the non-test Go files of the package in dir. Files named output are ignored, so that a previously
+// generated file is not parsed.
+func Generate(dir string, output string, types ...string) ([]byte, error) {
+	fset := token.NewFileSet()
+	pkgs, err := parser.ParseDir(fset, dir, func(info os.FileInfo) bool {
+		return !strings.HasSuffix(info.Name(), "_test.go") && info.Name() != output
+	}, 0)
+	if err != nil {
+		return nil, err
+	}
+	if len(pkgs) != 1 {
+		return nil, fmt.Errorf("expected a single package in %s but found %d", dir, len(pkgs))
+	}
+	var pkg *ast.Package
+	for _, p := range pkgs {
+		pkg = p
+	}
+	g := &generator{fset: fset, imports: map[string]string{}}
+	for _, name := range types {
+		file, iface, err := findInterface(pkg, name)
+		if err != nil {
+			return nil, err
+		}
+		if err := g.proxy(file, name, iface); err != nil {
+			return nil, err
+		}
+	}
+	return g.source(pkg.Name)
+}
+
+// findInterface returns the declaration of the interface type name, and the file declaring it.
+func findInterface(pkg *ast.Package, name string) (*ast.File, *ast.InterfaceType, error) {
+	for _, file := range pkg.Files {
+		for _, decl := range file.Decls {
+			gen, ok := decl.(*ast.GenDecl)
+			if !ok || gen.Tok != token.TYPE {
+				continue
+			}
+			for _, spec := range gen.Specs {
+				ts := spec.(*ast.TypeSpec)
+				if ts.Name.Name != name {
+					continue
+				}
+				iface, ok := ts.Type.(*ast.InterfaceType)
+				if !ok {
+					return nil, nil, fmt.Errorf("%s is not an interface", name)
+				}
+				if ts.TypeParams != nil {
+					return nil, nil, fmt.Errorf("%s: generic interfaces are not supported", name)
+				}
+				return file, iface, nil
+			}
+		}
+	}
+	return nil, nil, fmt.Errorf("interface %s not found", name)
+}
+
+type generator struct {
+	fset    *token.FileSet
+	imports map[string]string // Import path to the name it is referred to by.
+	inits   []string
+	decls   bytes.Buffer
+}
+
+// proxy generates the proxy type for the interface name, declared in file.
+func (g *generator) proxy(file *ast.File, name string, iface *ast.InterfaceType) error {
+	r, size := utf8.DecodeRuneInString(name)
+	proxy := string(unicode.ToLower(r)) + name[size:] + "Proxy"
+	g.inits = append(g.inits, fmt.Sprintf(
+		"inject.RegisterProxy((*%s)(nil), func(handler inject.ProxyHandler) interface{} { return %s{handler} })", name, proxy))
+	fmt.Fprintf(&g.decls, "\n// %s forwards the methods of %s to an inject.ProxyHandler.\n", proxy, name)
+	fmt.Fprintf(&g.decls, "type %s struct{ handler inject.ProxyHandler }\n", proxy)
+	for _, field := range iface.Methods.List {
+		ft, ok := field.Type.(*ast.FuncType)
+		if !ok || len(field.Names) == 0 {
+			return fmt.Errorf("%s: embedded interfaces are not supported", name)
+		}
+		method := field.Names[0].Name
+		if !ast.IsExported(method) {
+			return fmt.Errorf("%s: unexported method %s can not be proxied", name, method)
+		}
+		if err := g.addImports(file, ft); err != nil {
+			return fmt.Errorf("%s.%s: %s", name, method, err)
+		}
+		params, args := []string{}, []string{}
+		for _, param := range fieldTypes(ft.Params) {
+			arg := fmt.Sprintf("a%d", len(args))
+			params = append(params, arg+" "+g.expr(param))
+			args = append(args, arg)
+		}
+		results := []string{}
+		for _, result := range fieldTypes(ft.Results) {
+			results = append(results, g.expr(result))
+		}
+		signature := strings.Join(results, ", ")
+		if len(results) > 1 {
+			signature = "(" + signature + ")"
+		}
+		fmt.Fprintf(&g.decls, "\nfunc (p %s) %s(%s) %s {\n", proxy, method, strings.Join(params, ", "), signature)
+		call := fmt.Sprintf("p.handler(%s)", strings.Join(append([]string{strconv.Quote(method)}, args...), ", "))
+		if len(results) == 0 {
+			fmt.Fprintf(&g.decls, "\t%s\n}\n", call)
+			continue
+		}
+		fmt.Fprintf(&g.decls, "\tout := %s\n", call)
+		names := []string{}
+		for j, result := range results {
+			fmt.Fprintf(&g.decls, "\tr%d, _ := out[%d].(%s)\n", j, j, result)
+			names = append(names, fmt.Sprintf("r%d", j))
+		}
+		fmt.Fprintf(&g.decls, "\treturn %s\n}\n", strings.Join(names, ", "))
+	}
+	return nil
+}
+
+// fieldTypes returns the type of each parameter or result in fields, repeating shared types.
+func fieldTypes(fields *ast.FieldList) []ast.Expr {
+	if fields == nil {
+		return nil
+	}
+	types := []ast.Expr{}
+	for _, field := range fields.List {
+		n := len(field.Names)
+		if n == 0 {
+			n = 1
+		}
+		for j := 0; j < n; j++ {
+			types = append(types, field.Type)
+		}
+	}
+	return types
+}
+
+// addImports records the imports of file referred to by the types of ft.
+func (g *generator) addImports(file *ast.File, ft *ast.FuncType) error {
+	var err error
+	ast.Inspect(ft, func(n ast.Node) bool {
+		sel, ok := n.(*ast.SelectorExpr)
+		if !ok {
+			return true
+		}
+		ident, ok := sel.X.(*ast.Ident)
+		if !ok {
+			return true
+		}
+		importPath, ok := findImport(file, ident.Name)
+		if !ok {
+			err = fmt.Errorf("can not find the import of %s", ident.Name)
+			return false
+		}
+		g.imports[importPath] = ident.Name
+		return false
+	})
+	return err
+}
+
+// findImport returns the path of the import of file referred to by name.
+func findImport(file *ast.File, name string) (string, bool) {
+	for _, spec := range file.Imports {
+		importPath, _ := strconv.Unquote(spec.Path.Value)
+		if spec.Name != nil {
+			if spec.Name.Name == name {
+				return importPath, true
+			}
+			continue
+		}
+		if importName(importPath) == name {
+			return importPath, true
+		}
+	}
+	return "", false
+}
+
+// importName guesses the package name of an import path from its last element, ignoring major
+// version suffixes such as "v2" and "yaml.v3".
+func importName(importPath string) string {
+	base := path.Base(importPath)
+	if len(base) > 1 && base[0] == 'v' && strings.Trim(base[1:], "0123456789") == "" {
+		base = path.Base(path.Dir(importPath))
+	}
+	if j := strings.Index(base, ".v"); j > 0 {
+		base = base[:j]
+	}
+	return strings.TrimPrefix(strings.Replace(base, "-", "_", -1), "go_")
+}
+
+// expr formats a type expression.
+func (g *generator) expr(e ast.Expr) string {
+	buf := &bytes.Buffer{}
+	_ = format.Node(buf, g.fset, e)
+	return buf.String()
+}
+
+// source returns the formatted source of the generated file.
+func (g *generator) source(pkg string) ([]byte, error) {
+	buf := &bytes.Buffer{}
+	fmt.Fprintf(buf, "// Code generated by injectproxy. DO NOT EDIT.\n\npackage %s\n\nimport (\n", pkg)
+	g.imports["github.com/alecthomas/inject"] = "inject"
+	paths := []string{}
+	for importPath := range g.imports {
+		paths = append(paths, importPath)
+	}
+	sort.Strings(paths)
+	for _, importPath := range paths {
+		if name := g.imports[importPath]; name != path.Base(importPath) {
+			fmt.Fprintf(buf, "\t%s %q\n", name, importPath)
+		} else {
+			fmt.Fprintf(buf, "\t%q\n", importPath)
+		}
+	}
+	fmt.Fprintf(buf, ")\n\nfunc init() {\n")
+	for _, init := range g.inits {
+		fmt.Fprintf(buf, "\t%s\n", init)
+	}
+	fmt.Fprintf(buf, "}\n")
+	buf.Write(g.decls.Bytes())
+	return format.Source(buf.Bytes())
+}
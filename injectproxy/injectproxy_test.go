@@ -0,0 +1,60 @@
+package injectproxy
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestGenerate(t *testing.T) {
+	source, err := Generate("testdata/store", "inject_proxy.go", "Store")
+	require.NoError(t, err)
+	require.Equal(t, `// Code generated by injectproxy. DO NOT EDIT.
+
+package store
+
+import (
+	"context"
+	"github.com/alecthomas/inject"
+	yaml "gopkg.in/yaml.v3"
+	"io"
+)
+
+func init() {
+	inject.RegisterProxy((*Store)(nil), func(handler inject.ProxyHandler) interface{} { return storeProxy{handler} })
+}
+
+// storeProxy forwards the methods of Store to an inject.ProxyHandler.
+type storeProxy struct{ handler inject.ProxyHandler }
+
+func (p storeProxy) Get(a0 context.Context, a1 string) (*User, error) {
+	out := p.handler("Get", a0, a1)
+	r0, _ := out[0].(*User)
+	r1, _ := out[1].(error)
+	return r0, r1
+}
+
+func (p storeProxy) Put(a0 context.Context, a1 ...*User) error {
+	out := p.handler("Put", a0, a1)
+	r0, _ := out[0].(error)
+	return r0
+}
+
+func (p storeProxy) Export(a0 io.Writer, a1 *yaml.Node) {
+	p.handler("Export", a0, a1)
+}
+
+func (p storeProxy) Count() int {
+	out := p.handler("Count")
+	r0, _ := out[0].(int)
+	return r0
+}
+`, string(source))
+
+	_, err = Generate("testdata/store", "inject_proxy.go", "NotInterface")
+	require.EqualError(t, err, "NotInterface is not an interface")
+	_, err = Generate("testdata/store", "inject_proxy.go", "Embedding")
+	require.EqualError(t, err, "Embedding: embedded interfaces are not supported")
+	_, err = Generate("testdata/store", "inject_proxy.go", "Missing")
+	require.EqualError(t, err, "interface Missing not found")
+}
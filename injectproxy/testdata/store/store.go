@@ -0,0 +1,22 @@
+package store
+
+import (
+	"context"
+	yaml "gopkg.in/yaml.v3"
+	"io"
+)
+
+type User struct{ Name string }
+
+type Store interface {
+	Get(ctx context.Context, id string) (*User, error)
+	Put(ctx context.Context, users ...*User) error
+	Export(w io.Writer, node *yaml.Node)
+	Count() int
+}
+
+type NotInterface struct{}
+
+type Embedding interface {
+	io.Reader
+}
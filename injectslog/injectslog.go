@@ -0,0 +1,108 @@
+//go:build go1.21
+// +build go1.21
+
+// Package injectslog provides a *slog.Logger configured from the fields of its Module.
+//
+// Records are written to each of the Module's outputs, and to any slog.Handler contributed by
+// other modules in a Sequence() of slog.Handler, such as from a provider method whose name
+// contains "Sequence":
+//
+//	func (t *TracingModule) ProvideHandlersSequence(exporter *Exporter) []slog.Handler {
+//		return []slog.Handler{exporter.Handler()}
+//	}
+//
+//	injector.Install(&injectslog.Module{Level: slog.LevelDebug, Format: "json"}, &TracingModule{})
+//	injector.Call(func(logger *slog.Logger) { logger.Info("started") })
+package injectslog
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"os"
+)
+
+// Module provides a *slog.Logger.
+type Module struct {
+	// Level is the minimum level written to Outputs. Defaults to slog.LevelInfo.
+	Level slog.Level
+	// Format of records written to Outputs, either "text" or "json". Defaults to "text".
+	Format string
+	// Outputs that records are written to. Defaults to os.Stderr.
+	Outputs []io.Writer
+	// AddSource includes the source file and line of the log call in records written to Outputs.
+	AddSource bool
+}
+
+// ProvideLogger builds a *slog.Logger writing to the Module's outputs and to handlers.
+func (m *Module) ProvideLogger(handlers ...slog.Handler) (*slog.Logger, error) {
+	outputs := m.Outputs
+	if len(outputs) == 0 {
+		outputs = []io.Writer{os.Stderr}
+	}
+	options := &slog.HandlerOptions{Level: m.Level, AddSource: m.AddSource}
+	all := make([]slog.Handler, 0, len(outputs)+len(handlers))
+	for _, output := range outputs {
+		switch m.Format {
+		case "", "text":
+			all = append(all, slog.NewTextHandler(output, options))
+		case "json":
+			all = append(all, slog.NewJSONHandler(output, options))
+		default:
+			return nil, fmt.Errorf("unknown log format %q, expected \"text\" or \"json\"", m.Format)
+		}
+	}
+	all = append(all, handlers...)
+	return slog.New(Fanout(all...)), nil
+}
+
+// Fanout returns a slog.Handler that passes each record to every one of handlers that is enabled
+// for its level.
+func Fanout(handlers ...slog.Handler) slog.Handler {
+	if len(handlers) == 1 {
+		return handlers[0]
+	}
+	return fanout(handlers)
+}
+
+type fanout []slog.Handler
+
+func (f fanout) Enabled(ctx context.Context, level slog.Level) bool {
+	for _, handler := range f {
+		if handler.Enabled(ctx, level) {
+			return true
+		}
+	}
+	return false
+}
+
+func (f fanout) Handle(ctx context.Context, record slog.Record) error {
+	var errs []error
+	for _, handler := range f {
+		if !handler.Enabled(ctx, record.Level) {
+			continue
+		}
+		if err := handler.Handle(ctx, record.Clone()); err != nil {
+			errs = append(errs, err)
+		}
+	}
+	return errors.Join(errs...)
+}
+
+func (f fanout) WithAttrs(attrs []slog.Attr) slog.Handler {
+	out := make(fanout, len(f))
+	for j, handler := range f {
+		out[j] = handler.WithAttrs(attrs)
+	}
+	return out
+}
+
+func (f fanout) WithGroup(name string) slog.Handler {
+	out := make(fanout, len(f))
+	for j, handler := range f {
+		out[j] = handler.WithGroup(name)
+	}
+	return out
+}
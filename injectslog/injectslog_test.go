@@ -0,0 +1,80 @@
+//go:build go1.21
+// +build go1.21
+
+package injectslog
+
+import (
+	"bytes"
+	"context"
+	"io"
+	"log/slog"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/alecthomas/inject"
+)
+
+type recordingHandler struct {
+	records *[]string
+	attrs   []slog.Attr
+}
+
+func (r recordingHandler) Enabled(ctx context.Context, level slog.Level) bool { return true }
+
+func (r recordingHandler) Handle(ctx context.Context, record slog.Record) error {
+	msg := record.Level.String() + " " + record.Message
+	for _, attr := range r.attrs {
+		msg += " " + attr.String()
+	}
+	*r.records = append(*r.records, msg)
+	return nil
+}
+
+func (r recordingHandler) WithAttrs(attrs []slog.Attr) slog.Handler {
+	return recordingHandler{records: r.records, attrs: append(r.attrs[:len(r.attrs):len(r.attrs)], attrs...)}
+}
+
+func (r recordingHandler) WithGroup(name string) slog.Handler { return r }
+
+type recordingModule struct {
+	records []string
+}
+
+func (m *recordingModule) ProvideHandlersSequence() []slog.Handler {
+	return []slog.Handler{recordingHandler{records: &m.records}}
+}
+
+func TestModule(t *testing.T) {
+	w := &bytes.Buffer{}
+	recording := &recordingModule{}
+	injector := inject.SafeNew()
+	require.NoError(t, injector.Install(&Module{Level: slog.LevelWarn, Format: "json", Outputs: []io.Writer{w}}, recording))
+	v, err := injector.Get(&slog.Logger{})
+	require.NoError(t, err)
+	logger := v.(*slog.Logger).With("service", "users")
+	logger.Info("started")
+	logger.Warn("slow", "ms", 250)
+
+	require.Equal(t, []string{"INFO started service=users", "WARN slow service=users"}, recording.records)
+	require.Regexp(t, `^\{"time":"[^"]+","level":"WARN","msg":"slow","service":"users","ms":250\}\n$`, w.String())
+}
+
+func TestModuleText(t *testing.T) {
+	w := &bytes.Buffer{}
+	injector := inject.SafeNew()
+	require.NoError(t, injector.Install(&Module{Outputs: []io.Writer{w}}))
+	v, err := injector.Get(&slog.Logger{})
+	require.NoError(t, err)
+	v.(*slog.Logger).Debug("hidden")
+	v.(*slog.Logger).Info("shown", "n", 1)
+	require.Regexp(t, `^time=\S+ level=INFO msg=shown n=1\n$`, w.String())
+}
+
+func TestModuleUnknownFormat(t *testing.T) {
+	injector := inject.SafeNew()
+	require.NoError(t, injector.Install(&Module{Format: "xml"}))
+	_, err := injector.Get(&slog.Logger{})
+	require.EqualError(t, err, "building *slog.Logger: provider github.com/alecthomas/inject/injectslog.(*Module).ProvideLogger failed: "+
+		`unknown log format "xml", expected "text" or "json"`)
+}
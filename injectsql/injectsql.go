@@ -0,0 +1,230 @@
+// Package injectsql provides request-scoped database transactions for inject.
+//
+// A TxScope is a child injector in which *sql.Tx is bound to a transaction begun from the parent's
+// *sql.DB. The transaction is committed or rolled back when the scope ends.
+package injectsql
+
+import (
+	"bytes"
+	"context"
+	"database/sql"
+	"log"
+	"net/http"
+	"sync"
+
+	"github.com/alecthomas/inject"
+)
+
+// TxScope is a child injector with a *sql.Tx bound.
+type TxScope struct {
+	injector *inject.SafeInjector
+	lock     sync.Mutex
+	tx       *sql.Tx
+}
+
+// NewTxScope creates a child of parent in which *sql.Tx is bound to a transaction begun from the
+// *sql.DB bound in parent.
+//
+// The transaction is only begun when a *sql.Tx is first injected.
+func NewTxScope(ctx context.Context, parent *inject.SafeInjector, opts *sql.TxOptions) (*TxScope, error) {
+	t := &TxScope{injector: parent.Child()}
+	err := t.injector.Bind(func(db *sql.DB) (*sql.Tx, error) {
+		t.lock.Lock()
+		defer t.lock.Unlock()
+		if t.tx != nil {
+			return t.tx, nil
+		}
+		tx, err := db.BeginTx(ctx, opts)
+		if err != nil {
+			return nil, err
+		}
+		t.tx = tx
+		return tx, nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return t, nil
+}
+
+// Injector returns the child injector of the scope.
+func (t *TxScope) Injector() *inject.SafeInjector {
+	return t.injector
+}
+
+// End the scope, committing the transaction if err is nil or rolling it back otherwise, then
+// closing the child injector.
+//
+// Any error from committing, rolling back or closing is returned.
+func (t *TxScope) End(err error) error {
+	t.lock.Lock()
+	tx := t.tx
+	t.tx = nil
+	t.lock.Unlock()
+	if tx != nil {
+		var txErr error
+		if err == nil {
+			txErr = tx.Commit()
+		} else {
+			txErr = tx.Rollback()
+		}
+		if txErr != nil {
+			_ = t.injector.Close()
+			return txErr
+		}
+	}
+	return t.injector.Close()
+}
+
+type contextKey struct{}
+
+// FromContext returns the TxScope of a request handled by Middleware(), or nil.
+func FromContext(ctx context.Context) *TxScope {
+	scope, _ := ctx.Value(contextKey{}).(*TxScope)
+	return scope
+}
+
+// A MiddlewareOption configures Middleware().
+type MiddlewareOption func(options *middlewareOptions)
+
+type middlewareOptions struct {
+	onError    func(r *http.Request, err error)
+	unbuffered bool
+}
+
+// OnError sets the function called with errors beginning or ending the transaction of a request. By
+// default they are logged with the standard logger.
+func OnError(onError func(r *http.Request, err error)) MiddlewareOption {
+	return func(options *middlewareOptions) { options.onError = onError }
+}
+
+// Unbuffered streams responses to the client as they are written, rather than holding them back
+// until the transaction ends. The http.ResponseWriter passed to handlers then implements
+// http.Flusher, flushing the underlying one if it can, but a failure to commit can only be reported
+// to the OnError() function, as the response has already been sent.
+func Unbuffered() MiddlewareOption {
+	return func(options *middlewareOptions) { options.unbuffered = true }
+}
+
+// Middleware returns HTTP middleware creating a TxScope for each request, available to handlers
+// via FromContext().
+//
+// The transaction is committed if the handler responds with a status below 500, and rolled back
+// otherwise, including if the handler panics. Errors beginning or ending the transaction are passed
+// to the OnError() function, while the client only receives a generic server error.
+//
+// By default the response is buffered in memory until the transaction ends, so that a failure to
+// commit is reported as a server error rather than being lost. Buffered responses can't be streamed:
+// the http.ResponseWriter passed to handlers does not implement http.Flusher. Use Unbuffered() for
+// handlers that stream their responses.
+func Middleware(parent *inject.SafeInjector, opts *sql.TxOptions, options ...MiddlewareOption) func(http.Handler) http.Handler {
+	mo := &middlewareOptions{onError: func(r *http.Request, err error) {
+		log.Printf("injectsql: %s %s: %s", r.Method, r.URL.Path, err)
+	}}
+	for _, option := range options {
+		option(mo)
+	}
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			scope, err := NewTxScope(r.Context(), parent, opts)
+			if err != nil {
+				mo.onError(r, err)
+				http.Error(w, http.StatusText(http.StatusInternalServerError), http.StatusInternalServerError)
+				return
+			}
+			var rw statusWriter
+			if mo.unbuffered {
+				rw = &streamingWriter{ResponseWriter: w, status: http.StatusOK}
+			} else {
+				rw = &bufferedWriter{ResponseWriter: w, status: http.StatusOK}
+			}
+			defer func() {
+				if p := recover(); p != nil {
+					_ = scope.End(errPanic)
+					panic(p)
+				}
+			}()
+			next.ServeHTTP(rw, r.WithContext(context.WithValue(r.Context(), contextKey{}, scope)))
+			if rw.Status() >= 500 {
+				err = errServer
+			}
+			if err := scope.End(err); err != nil {
+				mo.onError(r, err)
+				if !mo.unbuffered {
+					http.Error(w, http.StatusText(http.StatusInternalServerError), http.StatusInternalServerError)
+				}
+				return
+			}
+			if buffered, ok := rw.(*bufferedWriter); ok {
+				w.WriteHeader(buffered.status)
+				_, _ = w.Write(buffered.body.Bytes())
+			}
+		})
+	}
+}
+
+type scopeError string
+
+func (s scopeError) Error() string { return string(s) }
+
+const (
+	errPanic  scopeError = "handler panicked"
+	errServer scopeError = "handler responded with a server error"
+)
+
+// statusWriter is an http.ResponseWriter recording the status of the response.
+type statusWriter interface {
+	http.ResponseWriter
+	Status() int
+}
+
+// bufferedWriter holds back the status and body of a response until the transaction has ended.
+type bufferedWriter struct {
+	http.ResponseWriter
+	status      int
+	wroteHeader bool
+	body        bytes.Buffer
+}
+
+func (b *bufferedWriter) Status() int { return b.status }
+
+func (b *bufferedWriter) WriteHeader(status int) {
+	if !b.wroteHeader {
+		b.status = status
+		b.wroteHeader = true
+	}
+}
+
+func (b *bufferedWriter) Write(data []byte) (int, error) {
+	b.wroteHeader = true
+	return b.body.Write(data)
+}
+
+// streamingWriter passes the response through as it is written.
+type streamingWriter struct {
+	http.ResponseWriter
+	status      int
+	wroteHeader bool
+}
+
+func (s *streamingWriter) Status() int { return s.status }
+
+func (s *streamingWriter) WriteHeader(status int) {
+	if !s.wroteHeader {
+		s.status = status
+		s.wroteHeader = true
+	}
+	s.ResponseWriter.WriteHeader(status)
+}
+
+func (s *streamingWriter) Write(data []byte) (int, error) {
+	s.wroteHeader = true
+	return s.ResponseWriter.Write(data)
+}
+
+func (s *streamingWriter) Flush() {
+	if flusher, ok := s.ResponseWriter.(http.Flusher); ok {
+		s.wroteHeader = true
+		flusher.Flush()
+	}
+}
@@ -0,0 +1,150 @@
+package injectsql
+
+import (
+	"context"
+	"database/sql"
+	"database/sql/driver"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/alecthomas/inject"
+)
+
+// A fake driver recording transaction outcomes.
+type fakeDriver struct {
+	lock       sync.Mutex
+	events     []string
+	failCommit bool
+}
+
+func (f *fakeDriver) record(event string) {
+	f.lock.Lock()
+	defer f.lock.Unlock()
+	f.events = append(f.events, event)
+}
+
+func (f *fakeDriver) Open(name string) (driver.Conn, error) { return &fakeConn{f}, nil }
+
+type fakeConn struct{ d *fakeDriver }
+
+func (c *fakeConn) Prepare(query string) (driver.Stmt, error) { return nil, errors.New("unsupported") }
+func (c *fakeConn) Close() error                              { return nil }
+func (c *fakeConn) Begin() (driver.Tx, error) {
+	c.d.record("begin")
+	return &fakeTx{c.d}, nil
+}
+
+type fakeTx struct{ d *fakeDriver }
+
+func (t *fakeTx) Commit() error {
+	t.d.record("commit")
+	if t.d.failCommit {
+		return errors.New("commit failed")
+	}
+	return nil
+}
+func (t *fakeTx) Rollback() error { t.d.record("rollback"); return nil }
+
+var registerOnce sync.Once
+var fake = &fakeDriver{}
+
+func newInjector(t *testing.T) *inject.SafeInjector {
+	registerOnce.Do(func() { sql.Register("injectsql-fake", fake) })
+	fake.lock.Lock()
+	fake.events = nil
+	fake.failCommit = false
+	fake.lock.Unlock()
+	db, err := sql.Open("injectsql-fake", "")
+	require.NoError(t, err)
+	t.Cleanup(func() { db.Close() })
+	i := inject.SafeNew()
+	err = i.Bind(db)
+	require.NoError(t, err)
+	return i
+}
+
+func TestTxScope(t *testing.T) {
+	i := newInjector(t)
+	scope, err := NewTxScope(context.Background(), i, nil)
+	require.NoError(t, err)
+	_, err = scope.Injector().Call(func(a, b *sql.Tx) { require.Equal(t, a, b) })
+	require.NoError(t, err)
+	err = scope.End(nil)
+	require.NoError(t, err)
+
+	scope, err = NewTxScope(context.Background(), i, nil)
+	require.NoError(t, err)
+	_, err = scope.Injector().Call(func(*sql.Tx) {})
+	require.NoError(t, err)
+	err = scope.End(errors.New("failed"))
+	require.NoError(t, err)
+
+	// Transactions are not begun unless used.
+	scope, err = NewTxScope(context.Background(), i, nil)
+	require.NoError(t, err)
+	err = scope.End(nil)
+	require.NoError(t, err)
+
+	require.Equal(t, []string{"begin", "commit", "begin", "rollback"}, fake.events)
+}
+
+func TestMiddleware(t *testing.T) {
+	i := newInjector(t)
+	handler := Middleware(i, nil)(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		_, err := FromContext(r.Context()).Injector().Call(func(*sql.Tx) {})
+		require.NoError(t, err)
+		if r.URL.Path == "/fail" {
+			w.WriteHeader(http.StatusInternalServerError)
+		}
+	}))
+	handler.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest("GET", "/", nil))
+	handler.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest("GET", "/fail", nil))
+	require.Equal(t, []string{"begin", "commit", "begin", "rollback"}, fake.events)
+}
+
+func TestMiddlewareCommitFailure(t *testing.T) {
+	i := newInjector(t)
+	fake.failCommit = true
+	var errs []error
+	onError := OnError(func(r *http.Request, err error) { errs = append(errs, err) })
+	handler := Middleware(i, nil, onError)(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		_, err := FromContext(r.Context()).Injector().Call(func(*sql.Tx) {})
+		require.NoError(t, err)
+		_, _ = w.Write([]byte("saved"))
+	}))
+	w := httptest.NewRecorder()
+	handler.ServeHTTP(w, httptest.NewRequest("POST", "/", nil))
+	require.Equal(t, http.StatusInternalServerError, w.Code)
+	// Database errors are not disclosed to the client.
+	require.Equal(t, "Internal Server Error\n", w.Body.String())
+	require.Len(t, errs, 1)
+	require.EqualError(t, errs[0], "commit failed")
+	require.Equal(t, []string{"begin", "commit"}, fake.events)
+}
+
+func TestMiddlewareUnbuffered(t *testing.T) {
+	i := newInjector(t)
+	fake.failCommit = true
+	var errs []error
+	onError := OnError(func(r *http.Request, err error) { errs = append(errs, err) })
+	handler := Middleware(i, nil, Unbuffered(), onError)(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		_, err := FromContext(r.Context()).Injector().Call(func(*sql.Tx) {})
+		require.NoError(t, err)
+		w.WriteHeader(http.StatusAccepted)
+		_, _ = w.Write([]byte("streamed"))
+		w.(http.Flusher).Flush()
+	}))
+	w := httptest.NewRecorder()
+	handler.ServeHTTP(w, httptest.NewRequest("POST", "/", nil))
+	require.True(t, w.Flushed)
+	require.Equal(t, http.StatusAccepted, w.Code)
+	require.Equal(t, "streamed", w.Body.String())
+	require.Len(t, errs, 1)
+	require.EqualError(t, errs[0], "commit failed")
+	require.Equal(t, []string{"begin", "commit"}, fake.events)
+}
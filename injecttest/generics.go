@@ -0,0 +1,22 @@
+//go:build go1.18
+// +build go1.18
+
+package injecttest
+
+import (
+	"reflect"
+
+	"github.com/alecthomas/inject"
+)
+
+// Get acquires a value of type T from the injector. If it can not be built, the test fails with an
+// explanation of how the type was resolved.
+func Get[T any](i *Injector) T {
+	i.t.Helper()
+	v, err := inject.GetAs[T](i.safe)
+	if err != nil {
+		key := inject.Key{Type: reflect.TypeOf((*T)(nil)).Elem()}
+		i.t.Fatalf("failed to get %s: %s\n%s", key, err, i.safe.Explain(key))
+	}
+	return v
+}
@@ -0,0 +1,19 @@
+//go:build go1.18
+// +build go1.18
+
+package injecttest
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestGet(t *testing.T) {
+	ft := &fakeT{}
+	closed := false
+	injector := New(ft, &clockModule{&closed})
+	require.Equal(t, "real", Get[clock](injector).Now())
+	require.Equal(t, 0, Get[int](injector))
+	require.Equal(t, []string{"failed to get int: unbound type int\nint ERROR: unbound type int\n"}, ft.failures)
+}
@@ -0,0 +1,108 @@
+// Package injecttest provides an injector for tests, which fails the test on any error and is
+// closed when the test completes.
+//
+//	func TestServer(t *testing.T) {
+//		injector := injecttest.New(t, &ServerModule{}).Override(&FakeClock{})
+//		server := injecttest.Get[*Server](injector)
+//		...
+//	}
+package injecttest
+
+import (
+	"fmt"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/alecthomas/inject"
+)
+
+// Injector wraps an inject.SafeInjector, failing the test on error.
+type Injector struct {
+	t    testing.TB
+	safe *inject.SafeInjector
+}
+
+// New creates an Injector with modules installed, that is closed when the test completes.
+func New(t testing.TB, modules ...interface{}) *Injector {
+	t.Helper()
+	i := &Injector{t: t, safe: inject.SafeNew()}
+	t.Cleanup(func() {
+		if err := i.safe.Close(); err != nil {
+			t.Errorf("failed to close injector: %s", err)
+		}
+	})
+	return i.Install(modules...)
+}
+
+// Safe returns the underlying SafeInjector.
+func (i *Injector) Safe() *inject.SafeInjector {
+	return i.safe
+}
+
+// Install modules, failing the test on error.
+func (i *Injector) Install(modules ...interface{}) *Injector {
+	i.t.Helper()
+	if err := i.safe.Install(modules...); err != nil {
+		i.t.Fatalf("failed to install modules: %s", err)
+	}
+	return i
+}
+
+// Bind values, failing the test on error.
+func (i *Injector) Bind(things ...interface{}) *Injector {
+	i.t.Helper()
+	if err := i.safe.Bind(things...); err != nil {
+		i.t.Fatalf("failed to bind: %s", err)
+	}
+	return i
+}
+
+// Override binds values, replacing any existing bindings of the same keys, such as fakes for the
+// bindings of installed modules. Fails the test on error.
+func (i *Injector) Override(things ...interface{}) *Injector {
+	i.t.Helper()
+	if err := i.safe.Override(things...); err != nil {
+		i.t.Fatalf("failed to override: %s", err)
+	}
+	return i
+}
+
+// Get acquires a value of the type of t, as with inject.SafeInjector.Get(). If it can not be
+// built, the test fails with an explanation of how the type was resolved.
+func (i *Injector) Get(t interface{}) interface{} {
+	i.t.Helper()
+	v, err := i.safe.Get(t)
+	if err != nil {
+		i.t.Fatalf("failed to get %s: %s\n%s", reflect.TypeOf(t), err, i.safe.Explain(t))
+	}
+	return v
+}
+
+// Call calls f, injecting its arguments. If f can not be called, or returns an error, the test
+// fails with an explanation of how each argument was resolved.
+func (i *Injector) Call(f interface{}) []interface{} {
+	i.t.Helper()
+	r, err := i.safe.Call(f)
+	if err != nil {
+		i.t.Fatalf("failed to call %s: %s\n%s", reflect.TypeOf(f), err, i.explainArgs(f))
+	}
+	return r
+}
+
+func (i *Injector) explainArgs(f interface{}) string {
+	ft := reflect.TypeOf(f)
+	if ft.Kind() != reflect.Func {
+		return ""
+	}
+	w := &strings.Builder{}
+	for j := 0; j < ft.NumIn(); j++ {
+		fmt.Fprintf(w, "argument %d:\n", j+1)
+		for _, line := range strings.SplitAfter(i.safe.Explain(inject.Key{Type: ft.In(j)}), "\n") {
+			if line != "" {
+				w.WriteString("  " + line)
+			}
+		}
+	}
+	return w.String()
+}
@@ -0,0 +1,71 @@
+package injecttest
+
+import (
+	"fmt"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/alecthomas/inject"
+)
+
+// fakeT records failures rather than failing the test.
+type fakeT struct {
+	testing.TB
+	failures []string
+	cleanups []func()
+}
+
+func (f *fakeT) Helper() {}
+
+func (f *fakeT) Cleanup(cleanup func()) { f.cleanups = append(f.cleanups, cleanup) }
+
+func (f *fakeT) Errorf(format string, args ...interface{}) {
+	f.failures = append(f.failures, fmt.Sprintf(format, args...))
+}
+
+func (f *fakeT) Fatalf(format string, args ...interface{}) {
+	f.Errorf(format, args...)
+}
+
+type clock interface{ Now() string }
+
+type realClock struct{}
+
+func (realClock) Now() string { return "real" }
+
+type fakeClock struct{}
+
+func (fakeClock) Now() string { return "fake" }
+
+type clockModule struct{ closed *bool }
+
+func (m *clockModule) ProvideClock(cleanup inject.Cleanup) clock {
+	cleanup(func() { *m.closed = true })
+	return realClock{}
+}
+
+func (m *clockModule) ProvideGreeting(c clock) string { return "the time is " + c.Now() }
+
+func TestInjector(t *testing.T) {
+	ft := &fakeT{}
+	closed := false
+	injector := New(ft, &clockModule{&closed})
+	require.Equal(t, "the time is real", injector.Get(""))
+
+	injector = New(ft, &clockModule{&closed}).Override(fakeClock{}, inject.As((*clock)(nil)))
+	r := injector.Call(func(s string) string { return s })
+	require.Equal(t, []interface{}{"the time is fake"}, r)
+	require.Empty(t, ft.failures)
+
+	injector.Call(func(s string, n int) {})
+	require.Len(t, ft.failures, 1)
+	require.True(t, strings.HasPrefix(ft.failures[0], "failed to call func(string, int): "), ft.failures[0])
+	require.Contains(t, ft.failures[0], "argument 2:\n  int ERROR: unbound type int\n")
+
+	for _, cleanup := range ft.cleanups {
+		cleanup()
+	}
+	require.True(t, closed)
+}
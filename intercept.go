@@ -0,0 +1,130 @@
+package inject
+
+import (
+	"fmt"
+	"reflect"
+	"sync"
+)
+
+// CallInfo describes a method call on an intercepted interface, passed to an Interceptor.
+type CallInfo struct {
+	// Interface whose method was called.
+	Interface reflect.Type
+	// Method name.
+	Method string
+	// Args passed to the method. A variadic method's final argument is a slice.
+	Args []interface{}
+	// Target is the value being proxied.
+	Target interface{}
+}
+
+// Invoke continues an intercepted call with args, returning the method's results. It calls the
+// remaining interceptors and ultimately the target's method.
+type Invoke func(args []interface{}) []interface{}
+
+// An Interceptor wraps method calls on an interface. It may call next any number of times, such as
+// to retry, and must return values of the method's result types.
+type Interceptor func(call CallInfo, next Invoke) []interface{}
+
+// A ProxyHandler receives the method calls of a proxy.
+type ProxyHandler func(method string, args ...interface{}) []interface{}
+
+// A ProxyFactory returns a proxy implementing an interface, which forwards each method call to
+// handler and returns its results.
+type ProxyFactory func(handler ProxyHandler) interface{}
+
+var (
+	proxiesLock sync.Mutex
+	proxies     = map[reflect.Type]ProxyFactory{}
+)
+
+// RegisterProxy registers the factory of proxies implementing the interface iface, specified as
+// with Has(). Go can not implement interfaces at runtime, so proxies are generated by the
+// injectproxy command, which registers them from an init() function:
+//
+//	//go:generate injectproxy -type Store
+//
+// The returned function restores the previous registration for iface, if any, such as at the end
+// of a test registering its own proxy.
+func RegisterProxy(iface interface{}, factory ProxyFactory) (unregister func()) {
+	it := keyOf(iface).Type
+	proxiesLock.Lock()
+	defer proxiesLock.Unlock()
+	previous, registered := proxies[it]
+	proxies[it] = factory
+	return func() {
+		proxiesLock.Lock()
+		defer proxiesLock.Unlock()
+		if registered {
+			proxies[it] = previous
+		} else {
+			delete(proxies, it)
+		}
+	}
+}
+
+// Intercept wraps the method calls of every value of the interface iface, specified as with Has(),
+// resolved by this injector or its children in a proxy that calls interceptor. Interceptors are
+// applied in the same order as middleware.
+//
+// A proxy for iface must have been registered with RegisterProxy().
+//
+//	injector.Intercept((*Store)(nil), func(call inject.CallInfo, next inject.Invoke) []interface{} {
+//		start := time.Now()
+//		defer func() { log.Printf("%s.%s took %s", call.Interface, call.Method, time.Since(start)) }()
+//		return next(call.Args)
+//	})
+func (s *SafeInjector) Intercept(iface interface{}, interceptor Interceptor) error {
+	it := keyOf(iface).Type
+	if it.Kind() != reflect.Interface {
+		return fmt.Errorf("Intercept() requires an interface but got %s", it)
+	}
+	if interceptor == nil {
+		return fmt.Errorf("Intercept() requires an interceptor for %s", it)
+	}
+	proxiesLock.Lock()
+	factory, ok := proxies[it]
+	proxiesLock.Unlock()
+	if !ok {
+		return fmt.Errorf("no proxy is registered for %s; generate one with injectproxy", it)
+	}
+	s.Use(func(req Request, next Next) (interface{}, error) {
+		v, err := next()
+		if err != nil || v == nil || req.Key.Type != it {
+			return v, err
+		}
+		return factory(proxyHandler(it, v, interceptor)), nil
+	})
+	return nil
+}
+
+// proxyHandler returns a ProxyHandler calling the methods of target through interceptor.
+func proxyHandler(it reflect.Type, target interface{}, interceptor Interceptor) ProxyHandler {
+	tv := reflect.ValueOf(target)
+	return func(method string, args ...interface{}) []interface{} {
+		m := tv.MethodByName(method)
+		mt := m.Type()
+		invoke := func(args []interface{}) []interface{} {
+			in := make([]reflect.Value, len(args))
+			for j, arg := range args {
+				if arg == nil {
+					in[j] = reflect.Zero(mt.In(j))
+				} else {
+					in[j] = reflect.ValueOf(arg)
+				}
+			}
+			var out []reflect.Value
+			if mt.IsVariadic() {
+				out = m.CallSlice(in)
+			} else {
+				out = m.Call(in)
+			}
+			results := make([]interface{}, len(out))
+			for j, r := range out {
+				results[j] = r.Interface()
+			}
+			return results
+		}
+		return interceptor(CallInfo{Interface: it, Method: method, Args: args, Target: target}, invoke)
+	}
+}
@@ -0,0 +1,237 @@
+package inject
+
+import (
+	"fmt"
+	"io"
+	"reflect"
+	"time"
+)
+
+// A BuildListener is called after a provider has been called to build a value of type t.
+//
+// err is the error returned by the provider, or the error resolving its arguments.
+type BuildListener func(t reflect.Type, v interface{}, err error)
+
+// OnBuild registers a listener that is called each time a provider bound to this injector, or to
+// any of its children, is called. Singletons are only reported when first built.
+//
+// Listeners are called synchronously, in the order they were registered, from the goroutine that
+// triggered the build.
+func (s *SafeInjector) OnBuild(listener BuildListener) {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	s.listeners = append(s.listeners, listener)
+}
+
+// A PostProcessor receives a value built by a provider and returns the value to use in its place,
+// which may be the same value after validation or side effects, or a replacement such as a wrapper.
+type PostProcessor func(v interface{}) (interface{}, error)
+
+type postProcessor struct {
+	t         reflect.Type
+	processor PostProcessor
+}
+
+// AfterBuild registers processor to be applied to each value built by a provider of this injector,
+// or of any of its children, whose type is t or implements the interface t. Types are specified as
+// with Get(). Singletons are only processed when first built, and values bound directly are never
+// processed.
+//
+// The value returned by processor must be assignable to the type of the provider, and an error
+// from processor is returned in place of the value. Processors of children are applied before
+// those of their parents, and within an injector in the order they were registered.
+//
+//	injector.AfterBuild((*http.Handler)(nil), func(v interface{}) (interface{}, error) {
+//		return Recover(v.(http.Handler)), nil
+//	})
+func (s *SafeInjector) AfterBuild(t interface{}, processor PostProcessor) {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	s.processors = append(s.processors, postProcessor{t: keyOf(t).Type, processor: processor})
+}
+
+// postProcess applies the post-processors of s and its parents matching t to v.
+func (s *SafeInjector) postProcess(t reflect.Type, v interface{}) (interface{}, error) {
+	for ; s != nil; s = s.parent {
+		s.lock.Lock()
+		processors := append([]postProcessor{}, s.processors...)
+		s.lock.Unlock()
+		for _, p := range processors {
+			if t != p.t && !(p.t.Kind() == reflect.Interface && t.Implements(p.t)) {
+				continue
+			}
+			out, err := p.processor(v)
+			if err != nil {
+				return nil, err
+			}
+			if out == nil || !reflect.TypeOf(out).AssignableTo(t) {
+				return nil, fmt.Errorf("post-processor returned %T for %s", out, t)
+			}
+			v = out
+		}
+	}
+	return v, nil
+}
+
+// An Observer is notified of provider calls and singleton cache hits, for instrumentation. See
+// SafeInjector.Observe().
+//
+// Observers are called synchronously from the goroutine that requested the value, so must be
+// safe for concurrent use.
+type Observer interface {
+	// Built is called after a provider of t has been called. The duration includes building the
+	// provider's dependencies.
+	Built(t reflect.Type, duration time.Duration, err error)
+	// CacheHit is called when a value of t is retrieved from a singleton cache.
+	CacheHit(t reflect.Type)
+}
+
+// Observe registers an Observer of provider calls and singleton cache hits in this injector and its
+// children.
+func (s *SafeInjector) Observe(observer Observer) {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	s.observers = append(s.observers, observer)
+}
+
+// notifyBuilt calls the build listeners and observers of s and its parents with the result of a
+// provider.
+func (s *SafeInjector) notifyBuilt(t reflect.Type, v interface{}, err error, duration time.Duration) {
+	for ; s != nil; s = s.parent {
+		s.lock.Lock()
+		listeners := append([]BuildListener{}, s.listeners...)
+		observers := append([]Observer{}, s.observers...)
+		s.lock.Unlock()
+		for _, listener := range listeners {
+			listener(t, v, err)
+		}
+		for _, observer := range observers {
+			observer.Built(t, duration, err)
+		}
+	}
+}
+
+// notifyCacheHit calls the observers of s and its parents when a singleton is retrieved from its
+// cache.
+func (s *SafeInjector) notifyCacheHit(t reflect.Type) {
+	for ; s != nil; s = s.parent {
+		s.lock.Lock()
+		observers := append([]Observer{}, s.observers...)
+		s.lock.Unlock()
+		for _, observer := range observers {
+			observer.CacheHit(t)
+		}
+	}
+}
+
+// Cleanup registers a function to be called when the injector that built a value is closed.
+//
+// A provider accepts a Cleanup to tie the lifetime of resources it acquires to the injector, or
+// child injector, that its binding belongs to:
+//
+//	func NewTempDir(cleanup inject.Cleanup) (TempDir, error) {
+//		dir, err := ioutil.TempDir("", "")
+//		if err != nil {
+//			return "", err
+//		}
+//		cleanup(func() { os.RemoveAll(dir) })
+//		return TempDir(dir), nil
+//	}
+type Cleanup func(f func())
+
+var cleanupType = reflect.TypeOf(Cleanup(nil))
+
+// cleanupBinding returns the binding providing the Cleanup of s.
+//
+// Each Cleanup belongs to the singleton that the value it is injected into is built for, if any.
+func (s *SafeInjector) cleanupBinding() *Binding {
+	if s.host != nil {
+		return s.host.cleanupBinding()
+	}
+	binding := &Binding{Provides: cleanupType}
+	binding.setBuild(func(r *resolving) (interface{}, error) {
+		of := r.singletonOwner()
+		return Cleanup(func(f func()) { s.onClose(of, f) }), nil
+	})
+	return binding
+}
+
+// builtEntry is either a built singleton or a registered Cleanup function.
+type builtEntry struct {
+	singleton *singleton
+	cleanup   func()
+	of        *singleton // Singleton whose build registered cleanup, if any.
+}
+
+// Close releases resources held by this injector, in the reverse order to which they were
+// acquired. Cleanup functions registered by its providers are called, and singleton values built
+// by this injector that implement io.Closer are closed then discarded, so they will be rebuilt if
+// requested again.
+//
+// All values are closed even if some fail, in which case an Errors value is returned.
+//
+// Singletons built and cleanups registered by parent injectors are not affected.
+func (s *SafeInjector) Close() error {
+	s.lock.Lock()
+	built := s.built
+	s.built = nil
+	s.lock.Unlock()
+	errs := Errors{}
+	for j := len(built) - 1; j >= 0; j-- {
+		entry := built[j]
+		if entry.cleanup != nil {
+			entry.cleanup()
+			continue
+		}
+		cache := entry.singleton
+		v, ok := cache.value()
+		if !ok {
+			continue
+		}
+		if closer, ok := v.(io.Closer); ok {
+			if err := closer.Close(); err != nil {
+				errs = append(errs, fmt.Errorf("failed to close %s: %s", cache.provides, err))
+			}
+		}
+		cache.reset()
+	}
+	if len(errs) > 0 {
+		return errs
+	}
+	return nil
+}
+
+func (s *SafeInjector) markBuilt(cache *singleton) {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	s.built = append(s.built, builtEntry{singleton: cache})
+}
+
+// takeCleanups removes and returns the cleanups registered while building cache, in the order they
+// were registered.
+func (s *SafeInjector) takeCleanups(cache *singleton) []func() {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	cleanups := []func(){}
+	kept := s.built[:0]
+	for _, b := range s.built {
+		if b.of == cache {
+			cleanups = append(cleanups, b.cleanup)
+		} else {
+			kept = append(kept, b)
+		}
+	}
+	s.built = kept
+	return cleanups
+}
+
+func (s *SafeInjector) unmarkBuilt(cache *singleton) {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	for j, b := range s.built {
+		if b.singleton == cache {
+			s.built = append(s.built[:j], s.built[j+1:]...)
+			return
+		}
+	}
+}
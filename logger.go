@@ -0,0 +1,34 @@
+package inject
+
+// A Logger receives internal events of an injector, such as bindings being added and singletons
+// being built, as a level ("debug" or "warn"), a message, and alternating keys and values.
+//
+//	injector.SetLogger(func(level, msg string, kv ...interface{}) {
+//		log.Println(append([]interface{}{level, msg}, kv...)...)
+//	})
+type Logger func(level, msg string, kv ...interface{})
+
+// SetLogger logs the internal events of this injector and its children to logger. A nil logger
+// disables logging, which is the default.
+//
+// Events are logged when a binding is added, a module is installed, an interface is first
+// resolved to a binding of a type implementing it, a singleton is built, a child injector is
+// created, and, as warnings, when a deprecated binding is first resolved.
+func (s *SafeInjector) SetLogger(logger Logger) {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	s.logger = logger
+}
+
+// log an event to the Logger of s or its closest ancestor, if any.
+func (s *SafeInjector) log(level, msg string, kv ...interface{}) {
+	for ; s != nil; s = s.parent {
+		s.lock.Lock()
+		logger := s.logger
+		s.lock.Unlock()
+		if logger != nil {
+			logger(level, msg, kv...)
+			return
+		}
+	}
+}
@@ -0,0 +1,142 @@
+package inject
+
+import (
+	"fmt"
+)
+
+// ConflictPolicy determines how a binding for an already bound type is handled.
+type ConflictPolicy int
+
+const (
+	// ConflictError fails with an error.
+	ConflictError ConflictPolicy = iota
+	// ConflictKeep keeps the existing binding and discards the new one.
+	ConflictKeep
+	// ConflictReplace replaces the existing binding with the new one.
+	ConflictReplace
+	// ConflictPanic panics with the error that ConflictError would return.
+	ConflictPanic
+)
+
+func (c ConflictPolicy) String() string {
+	switch c {
+	case ConflictError:
+		return "error"
+	case ConflictKeep:
+		return "keep"
+	case ConflictReplace:
+		return "replace"
+	case ConflictPanic:
+		return "panic"
+	}
+	return fmt.Sprintf("ConflictPolicy(%d)", int(c))
+}
+
+// SetConflictPolicy sets how Bind(), BindTo() and Provide() on this injector handle a binding for
+// an already bound key. The default is ConflictError. It can be overridden for a single call to
+// Bind() with the OnConflict() option.
+//
+//	// Tests replace production bindings with fakes.
+//	injector.SetConflictPolicy(inject.ConflictReplace)
+func (s *SafeInjector) SetConflictPolicy(policy ConflictPolicy) {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	s.conflicts = policy
+}
+
+func (s *SafeInjector) conflictPolicy() ConflictPolicy {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	return s.conflicts
+}
+
+// bindRecord is a successful call to Bind() or BindTo().
+type bindRecord struct {
+	as   interface{} // nil for Bind().
+	impl interface{}
+}
+
+// Merge the bindings and modules of other into this injector, resolving conflicting bindings
+// according to policy.
+//
+// Bindings are replayed against this injector in the order they were bound to other, so their
+// dependencies are resolved from the merged injector. Sequence and Mapping bindings are always
+// merged with existing bindings of the same type. Bindings of other's parent are not merged.
+//
+// Merge is not atomic: if an error occurs, bindings merged before it are retained.
+func (s *SafeInjector) Merge(other *SafeInjector, policy ConflictPolicy) error {
+	for _, record := range other.history {
+		var err error
+		if record.as == nil {
+			_, err = s.mergeBind(record.impl, policy)
+		} else {
+			_, err = s.bindTo(record.as, record.impl, policy)
+		}
+		if err != nil {
+			return err
+		}
+	}
+	for _, t := range other.moduleOrder {
+		if _, ok := s.modules[t]; !ok {
+			s.modules[t] = other.modules[t]
+			s.moduleOrder = append(s.moduleOrder, t)
+			s.moduleKeys[t] = append([]Key{}, other.moduleKeys[t]...)
+		}
+	}
+	return nil
+}
+
+func (s *SafeInjector) mergeBind(v interface{}, policy ConflictPolicy) (Key, error) {
+	annotation := Annotate(v)
+	if annotation.Is(&sequenceType{}) || annotation.Is(&mappingType{}) {
+		return s.bind(v)
+	}
+	binding, err := annotation.Build(s)
+	if err != nil {
+		return Key{}, err
+	}
+	if binding.disabled {
+		s.history = append(s.history, bindRecord{impl: v})
+		return Key{}, nil
+	}
+	key := Key{Type: binding.Provides, Name: binding.Name}
+	if ok, err := s.resolveConflict(key, policy); !ok || err != nil {
+		return key, err
+	}
+	if _, err := s.bindBuilt(binding, false); err != nil {
+		return Key{}, err
+	}
+	s.history = append(s.history, bindRecord{impl: v})
+	return key, nil
+}
+
+// resolveConflict applies policy if key is already bound, returning true if the new binding should
+// proceed.
+func (s *SafeInjector) resolveConflict(key Key, policy ConflictPolicy) (bool, error) {
+	if existing, ok := s.bindings[key]; !ok || existing.isDefault {
+		return true, nil
+	}
+	switch policy {
+	case ConflictKeep:
+		return false, nil
+	case ConflictReplace:
+		s.unbind(key)
+		return true, nil
+	case ConflictPanic:
+		panic(fmt.Errorf("%s is already bound", key))
+	default:
+		return false, fmt.Errorf("%s is already bound", key)
+	}
+}
+
+// unbind removes the binding for key from this injector.
+func (s *SafeInjector) unbind(key Key) {
+	delete(s.bindings, key)
+	for j, bk := range s.bindingOrder {
+		if bk == key {
+			s.bindingOrder = append(s.bindingOrder[:j], s.bindingOrder[j+1:]...)
+			break
+		}
+	}
+	s.invalidateResolutions()
+}
@@ -0,0 +1,68 @@
+package inject
+
+import (
+	"fmt"
+	"reflect"
+)
+
+// A Request describes the resolution of a value, passed to Middleware.
+type Request struct {
+	// Key being resolved.
+	Key Key
+	// Binding that will provide the value.
+	Binding *Binding
+	// Injector the value was requested from.
+	Injector *SafeInjector
+}
+
+// Next continues the resolution of a Request, returning the value or error of the remaining
+// middleware and ultimately the binding.
+type Next func() (interface{}, error)
+
+// Middleware wraps the resolution of values. It may return the value from next, possibly modified
+// or after side effects, or return its own value or error without calling next at all.
+//
+// A returned value must be assignable to the type of the key being resolved.
+type Middleware func(req Request, next Next) (interface{}, error)
+
+// Use appends middleware to the chain wrapping every value resolved by this injector and its
+// children, including arguments injected into Call() and providers, and singletons retrieved from
+// their caches.
+//
+// Middleware of parent injectors wraps that of their children, and within an injector earlier
+// middleware wraps later middleware.
+//
+//	// Fail a fraction of resolutions in chaos tests.
+//	injector.Use(func(req inject.Request, next inject.Next) (interface{}, error) {
+//		if rand.Float64() < 0.01 {
+//			return nil, fmt.Errorf("chaos: %s unavailable", req.Key)
+//		}
+//		return next()
+//	})
+func (s *SafeInjector) Use(middleware Middleware) {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	s.middleware = append(s.middleware, middleware)
+}
+
+// intercept resolves req with build, wrapped by the middleware of s and its ancestors.
+func (s *SafeInjector) intercept(req Request, build Next) (interface{}, error) {
+	chain := []Middleware{}
+	for p := s; p != nil; p = p.parent {
+		p.lock.Lock()
+		chain = append(append([]Middleware{}, p.middleware...), chain...)
+		p.lock.Unlock()
+	}
+	next := build
+	for j := len(chain) - 1; j >= 0; j-- {
+		middleware, inner := chain[j], next
+		next = func() (interface{}, error) {
+			v, err := middleware(req, inner)
+			if err == nil && v != nil && !reflect.TypeOf(v).AssignableTo(req.Key.Type) {
+				return nil, fmt.Errorf("middleware returned %T for %s", v, req.Key)
+			}
+			return v, err
+		}
+	}
+	return next()
+}
@@ -0,0 +1,181 @@
+package inject
+
+import (
+	"context"
+	"fmt"
+	"reflect"
+)
+
+var contextType = reflect.TypeOf((*context.Context)(nil)).Elem()
+
+// Start calls the Start method of each module installed in this injector that has one, in
+// dependency order, so that a module is started after the modules whose bindings it requires.
+//
+// Start methods must be of the form Start(ctx context.Context, deps...) error. ctx is the context
+// passed to Start(), and the remaining arguments are injected.
+//
+// If a module fails to start, the modules already started are stopped in reverse order.
+func (s *SafeInjector) Start(ctx context.Context) error {
+	if len(s.started) > 0 {
+		return fmt.Errorf("injector is already started")
+	}
+	order, err := s.moduleDependencyOrder()
+	if err != nil {
+		return err
+	}
+	for _, t := range order {
+		start, ok := s.moduleMethod(t, "Start")
+		if !ok {
+			continue
+		}
+		if err := checkLifecycleMethod(start.Type(), true); err != nil {
+			return fmt.Errorf("module %s: Start: %s", t, err)
+		}
+		if _, err := s.CallWith(start.Interface(), ctx); err != nil {
+			errs := Errors{fmt.Errorf("module %s: failed to start: %s", t, err)}
+			if err := s.Stop(ctx); err != nil {
+				errs = append(errs, err)
+			}
+			return errs
+		}
+		s.started = append(s.started, t)
+	}
+	return nil
+}
+
+// Stop calls the Stop method of each module started by Start() that has one, in the reverse order
+// to which they were started.
+//
+// Stop methods must be of the form Stop(ctx context.Context) error. All modules are stopped even
+// if some fail, in which case an Errors value is returned.
+func (s *SafeInjector) Stop(ctx context.Context) error {
+	started := s.started
+	s.started = nil
+	errs := Errors{}
+	for j := len(started) - 1; j >= 0; j-- {
+		t := started[j]
+		stop, ok := s.moduleMethod(t, "Stop")
+		if !ok {
+			continue
+		}
+		if err := checkLifecycleMethod(stop.Type(), false); err != nil {
+			errs = append(errs, fmt.Errorf("module %s: Stop: %s", t, err))
+			continue
+		}
+		out := stop.Call([]reflect.Value{reflect.ValueOf(ctx)})
+		if err, _ := out[0].Interface().(error); err != nil {
+			errs = append(errs, fmt.Errorf("module %s: failed to stop: %s", t, err))
+		}
+	}
+	if len(errs) > 0 {
+		return errs
+	}
+	return nil
+}
+
+// moduleMethod returns the named method of the installed module of type t, if any.
+func (s *SafeInjector) moduleMethod(t reflect.Type, name string) (reflect.Value, bool) {
+	m := s.modules[t]
+	if m.CanAddr() {
+		m = m.Addr()
+	}
+	method := m.MethodByName(name)
+	return method, method.IsValid()
+}
+
+// checkLifecycleMethod checks that ft is of the form func(context.Context[, deps...]) error.
+func checkLifecycleMethod(ft reflect.Type, deps bool) error {
+	if ft.NumIn() == 0 || ft.In(0) != contextType || (!deps && ft.NumIn() != 1) ||
+		ft.NumOut() != 1 || ft.Out(0) != errorType {
+		if deps {
+			return fmt.Errorf("must be of the form func(context.Context, ...) error, not %s", ft)
+		}
+		return fmt.Errorf("must be of the form func(context.Context) error, not %s", ft)
+	}
+	return nil
+}
+
+// moduleDependencyOrder returns the modules installed in this injector, sorted so that each module
+// comes after the modules whose bindings it, or its Start method, requires. Modules that do not
+// depend on each other remain in the order they were installed.
+func (s *SafeInjector) moduleDependencyOrder() ([]reflect.Type, error) {
+	const (
+		visiting = 1
+		visited  = 2
+	)
+	out := []reflect.Type{}
+	state := map[reflect.Type]int{}
+	var visit func(t reflect.Type, path []reflect.Type) error
+	visit = func(t reflect.Type, path []reflect.Type) error {
+		path = append(path[:len(path):len(path)], t)
+		switch state[t] {
+		case visited:
+			return nil
+		case visiting:
+			return fmt.Errorf("module dependency cycle %s", formatCycle(path))
+		}
+		state[t] = visiting
+		for _, dep := range s.moduleDependencies(t) {
+			if err := visit(dep, path); err != nil {
+				return err
+			}
+		}
+		state[t] = visited
+		out = append(out, t)
+		return nil
+	}
+	for _, t := range s.moduleOrder {
+		if err := visit(t, nil); err != nil {
+			return nil, err
+		}
+	}
+	return out, nil
+}
+
+// moduleDependencies returns the other modules of this injector that bind keys required, directly
+// or indirectly, by the bindings or Start method of module t.
+func (s *SafeInjector) moduleDependencies(t reflect.Type) []reflect.Type {
+	requires := []reflect.Type{}
+	for _, key := range s.moduleKeys[t] {
+		if binding, ok := s.bindings[key]; ok {
+			requires = append(requires, binding.Requires...)
+		}
+	}
+	if start, ok := s.moduleMethod(t, "Start"); ok {
+		for j := 1; j < start.Type().NumIn(); j++ {
+			requires = append(requires, start.Type().In(j))
+		}
+	}
+	out := []reflect.Type{}
+	seen := map[Key]bool{}
+	var walk func(key Key)
+	walk = func(key Key) {
+		if seen[key] {
+			return
+		}
+		seen[key] = true
+		binding, owner, err := s.resolveOwner(key)
+		if err != nil || owner != s {
+			return
+		}
+		if module, ok := s.moduleOf(key); ok && module != t && !containsType(out, module) {
+			out = append(out, module)
+		}
+		for _, req := range binding.Requires {
+			walk(Key{Type: req})
+		}
+	}
+	for _, req := range requires {
+		walk(Key{Type: req})
+	}
+	return out
+}
+
+func containsType(types []reflect.Type, t reflect.Type) bool {
+	for _, c := range types {
+		if c == t {
+			return true
+		}
+	}
+	return false
+}
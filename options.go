@@ -0,0 +1,233 @@
+package inject
+
+import (
+	"fmt"
+	"reflect"
+)
+
+// A BindOption modifies how the values passed alongside it to Bind() are bound.
+//
+//	injector.Bind(NewReplica, inject.Name("replica"), inject.As((*Database)(nil)))
+type BindOption interface {
+	applyBindOption(options *bindOptions)
+}
+
+type bindOptionFunc func(options *bindOptions)
+
+func (b bindOptionFunc) applyBindOption(options *bindOptions) { b(options) }
+
+type bindOptions struct {
+	as          interface{}
+	name        string
+	eager       bool
+	noSingleton bool
+	literal     bool
+	conflict    *ConflictPolicy
+}
+
+func (b *bindOptions) isZero() bool {
+	return *b == bindOptions{}
+}
+
+// As binds values to the given interface or type, as with BindTo().
+func As(as interface{}) BindOption {
+	return bindOptionFunc(func(options *bindOptions) { options.as = as })
+}
+
+// Name binds values with the given name. Named values are retrieved with GetKey() or CallKeyed().
+func Name(name string) BindOption {
+	return bindOptionFunc(func(options *bindOptions) { options.name = name })
+}
+
+// Eager builds values as soon as they are bound, returning any error from Bind().
+func Eager() BindOption {
+	return bindOptionFunc(func(options *bindOptions) { options.eager = true })
+}
+
+// NoSingleton removes any Singleton() annotation from values, so providers are called each time
+// their value is requested.
+func NoSingleton() BindOption {
+	return bindOptionFunc(func(options *bindOptions) { options.noSingleton = true })
+}
+
+// OnConflict overrides the ConflictPolicy of the injector for the values bound alongside it. See
+// SetConflictPolicy().
+func OnConflict(policy ConflictPolicy) BindOption {
+	return bindOptionFunc(func(options *bindOptions) { options.conflict = &policy })
+}
+
+// AsLiteral binds values as-is, as with Literal(), so that functions are bound as values of their
+// function type rather than as providers.
+//
+//	injector.Bind(buf.WriteString, inject.AsLiteral())
+//	injector.Call(func(write func(string) (int, error)) { ... })
+func AsLiteral() BindOption {
+	return bindOptionFunc(func(options *bindOptions) { options.literal = true })
+}
+
+// splitBindOptions separates BindOptions from the values to be bound.
+func splitBindOptions(things []interface{}) ([]interface{}, *bindOptions) {
+	values := []interface{}{}
+	options := &bindOptions{}
+	for _, thing := range things {
+		if option, ok := thing.(BindOption); ok {
+			option.applyBindOption(options)
+		} else {
+			values = append(values, thing)
+		}
+	}
+	return values, options
+}
+
+// bindWithOptions binds values with options, resolving conflicts with existing bindings according
+// to policy.
+func (s *SafeInjector) bindWithOptions(values []interface{}, options *bindOptions, policy ConflictPolicy) error {
+	for _, v := range values {
+		if options.literal {
+			v = Literal(v)
+		}
+		if options.noSingleton {
+			if singleton, ok := v.(*singletonType); ok {
+				v = singleton.v
+			}
+		}
+		if options.name != "" {
+			v = named(options.name, v)
+		}
+		var key Key
+		var err error
+		switch {
+		case options.as != nil:
+			key, err = s.bindTo(options.as, v, policy)
+		case policy != ConflictError:
+			key, err = s.mergeBind(v, policy)
+		default:
+			key, err = s.bind(v)
+		}
+		if err != nil {
+			return err
+		}
+		if options.eager && key.Type != nil {
+			if _, err := s.GetKey(key); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// named annotates v with name. Sequences and mappings merge with the previous binding of their
+// key, so the name is applied within them, to the key they merge with.
+func named(name string, v interface{}) interface{} {
+	switch v := v.(type) {
+	case *sequenceType:
+		return &sequenceType{named(name, v.v)}
+	case *mappingType:
+		return &mappingType{named(name, v.v)}
+	}
+	return &namedType{name, v}
+}
+
+// namedType annotates a binding with a name.
+type namedType struct {
+	name string
+	v    interface{}
+}
+
+func (n *namedType) Build(i *SafeInjector) (*Binding, error) {
+	binding, err := Annotate(n.v).Build(i)
+	if err != nil {
+		return &Binding{}, err
+	}
+	binding.Name = n.name
+	return binding, nil
+}
+
+func (n *namedType) Is(annotation Annotation) bool {
+	return reflect.TypeOf(annotation) == reflect.TypeOf(&namedType{}) || Annotate(n.v).Is(annotation)
+}
+
+// An InstallOption modifies how the modules passed alongside it to Install() are installed.
+//
+//	injector.Install(&LegacyModule{}, inject.WithPrefix("Give"))
+type InstallOption interface {
+	applyInstallOption(options *installOptions)
+}
+
+type installOptionFunc func(options *installOptions)
+
+func (i installOptionFunc) applyInstallOption(options *installOptions) { i(options) }
+
+// Markers are the substrings of a provider method name that select how its value is bound.
+type Markers struct {
+	// Multi providers are called each time their value is requested, rather than once.
+	Multi string
+	// Sequence providers contribute to a Sequence() of their return type.
+	Sequence string
+	// Mapping providers contribute to a Mapping() of their return type.
+	Mapping string
+}
+
+// DefaultMarkers returns the provider method name markers used if WithMarkers() is not specified.
+func DefaultMarkers() Markers {
+	return Markers{Multi: "Multi", Sequence: "Sequence", Mapping: "Mapping"}
+}
+
+type installOptions struct {
+	prefix  string
+	markers Markers
+	scope   func(v interface{}) Annotation
+	err     error // Invalid option, returned by Install().
+}
+
+// WithPrefix sets the method name prefix identifying provider methods of modules, in place of
+// "Provide". The prefix can not be empty, as every method of a module would then be a provider.
+func WithPrefix(prefix string) InstallOption {
+	return installOptionFunc(func(options *installOptions) {
+		if prefix == "" {
+			options.err = fmt.Errorf("WithPrefix() requires a non-empty prefix")
+			return
+		}
+		options.prefix = prefix
+	})
+}
+
+// WithMarkers sets the substrings of provider method names that select how they are bound, in
+// place of DefaultMarkers(). Empty markers are left at their defaults.
+func WithMarkers(markers Markers) InstallOption {
+	return installOptionFunc(func(options *installOptions) {
+		if markers.Multi != "" {
+			options.markers.Multi = markers.Multi
+		}
+		if markers.Sequence != "" {
+			options.markers.Sequence = markers.Sequence
+		}
+		if markers.Mapping != "" {
+			options.markers.Mapping = markers.Mapping
+		}
+	})
+}
+
+// WithDefaultScope sets the annotation applied to provider methods without a Multi, Sequence or
+// Mapping marker, and to function modules, in place of Singleton. Modules of builders or
+// per-request factories can be made transient by default:
+//
+//	injector.Install(&FactoryModule{}, inject.WithDefaultScope(inject.Transient))
+func WithDefaultScope(scope func(v interface{}) Annotation) InstallOption {
+	return installOptionFunc(func(options *installOptions) { options.scope = scope })
+}
+
+// splitInstallOptions separates InstallOptions from the modules to be installed, returning an error if
+// any option is invalid.
+func splitInstallOptions(things []interface{}) ([]interface{}, *installOptions, error) {
+	modules := []interface{}{}
+	options := &installOptions{prefix: "Provide", markers: DefaultMarkers(), scope: Singleton}
+	for _, thing := range things {
+		if option, ok := thing.(InstallOption); ok {
+			option.applyInstallOption(options)
+		} else {
+			modules = append(modules, thing)
+		}
+	}
+	return modules, options, options.err
+}
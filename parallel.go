@@ -0,0 +1,96 @@
+package inject
+
+import (
+	"fmt"
+	"reflect"
+	"sync"
+)
+
+// SetParallelism builds the injected arguments of each provider and Call() concurrently, in this
+// injector and its children, with at most workers of them being built at once. Workers less than 2
+// restore the default of building arguments one after the other.
+//
+// Arguments that require a common Singleton() share its single build, so only providers that are
+// safe to call concurrently with each other should be used with parallelism.
+func (s *SafeInjector) SetParallelism(workers int) {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	if workers < 2 {
+		s.workers = nil
+		return
+	}
+	// The calling goroutine is always one of the workers.
+	s.workers = make(chan struct{}, workers-1)
+}
+
+// workerPool returns the worker slots of s or its closest ancestor, if any.
+func (s *SafeInjector) workerPool() chan struct{} {
+	for ; s != nil; s = s.parent {
+		s.lock.Lock()
+		workers := s.workers
+		s.lock.Unlock()
+		if workers != nil {
+			return workers
+		}
+	}
+	return nil
+}
+
+// injectArg is an argument of a call injected from the binding for key.
+type injectArg struct {
+	index int
+	key   Key
+}
+
+// injectArgs builds the values of pending arguments of ft into args, as part of the resolution r.
+// With parallelism, each argument is built in a new goroutine if a worker slot is free, and in the
+// calling goroutine otherwise, so nested calls never wait on each other for a slot.
+func (s *SafeInjector) injectArgs(r *resolving, ft reflect.Type, args []reflect.Value, pending []injectArg) error {
+	workers := s.workerPool()
+	errs := make([]error, len(pending))
+	wg := sync.WaitGroup{}
+	for j, arg := range pending {
+		if workers != nil && j < len(pending)-1 {
+			select {
+			case workers <- struct{}{}:
+				wg.Add(1)
+				go func(j int, arg injectArg) {
+					defer func() {
+						<-workers
+						wg.Done()
+					}()
+					errs[j] = s.injectArg(r, ft, args, arg)
+				}(j, arg)
+				continue
+			default:
+			}
+		}
+		errs[j] = s.injectArg(r, ft, args, arg)
+		if errs[j] != nil && workers == nil {
+			return errs[j]
+		}
+	}
+	wg.Wait()
+	for _, err := range errs {
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (s *SafeInjector) injectArg(r *resolving, ft reflect.Type, args []reflect.Value, arg injectArg) error {
+	a, err := s.getKey(r, arg.key)
+	if berr, ok := err.(*BuildError); ok {
+		// The chain of a BuildError already describes what was being built.
+		return berr
+	} else if err != nil {
+		return fmt.Errorf("couldn't inject argument %d of %s: %s", arg.index+1, ft, err)
+	}
+	if a == nil {
+		args[arg.index] = reflect.Zero(ft.In(arg.index))
+	} else {
+		args[arg.index] = reflect.ValueOf(a)
+	}
+	return nil
+}
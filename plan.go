@@ -0,0 +1,81 @@
+package inject
+
+import (
+	"fmt"
+	"reflect"
+)
+
+// Plan returns the bindings whose providers would run to satisfy entrypoints, in the order they
+// would run, without running any of them. Each entry point is either a function, whose parameters
+// are satisfied as with Call(), or a key or value identifying the type to satisfy as with Has().
+//
+// Literal bindings and singletons that have already been built run no provider, so they and
+// their dependencies are omitted. Unbound optional requirements are skipped.
+//
+// An error is returned if a requirement is unbound or the bindings contain a dependency cycle.
+func (s *SafeInjector) Plan(entrypoints ...interface{}) ([]*BindingInfo, error) {
+	const (
+		visiting = 1
+		visited  = 2
+	)
+	out := []*BindingInfo{}
+	state := map[*Binding]int{}
+	var visit func(key Key, path []reflect.Type) error
+	visit = func(key Key, path []reflect.Type) error {
+		path = append(path[:len(path):len(path)], key.Type)
+		binding, owner, err := s.resolveOwner(key)
+		if err != nil && len(path) > 1 {
+			return fmt.Errorf("no binding for %s required by %s: %s", key.Type, path[len(path)-2], err)
+		} else if err != nil {
+			return err
+		}
+		switch state[binding] {
+		case visited:
+			return nil
+		case visiting:
+			for j, t := range path {
+				if t == key.Type {
+					return fmt.Errorf("recursive binding %s", formatCycle(path[j:]))
+				}
+			}
+		}
+		if binding.annotation == "Literal" || binding.Cached() {
+			state[binding] = visited
+			return nil
+		}
+		state[binding] = visiting
+		for _, req := range binding.Requires {
+			if binding.isOptional(req) && !s.canResolve(Key{Type: req}) {
+				continue
+			}
+			if err := visit(Key{Type: req}, path); err != nil {
+				return err
+			}
+		}
+		state[binding] = visited
+		out = append(out, bindingInfo(key, binding, owner))
+		return nil
+	}
+	for _, entrypoint := range entrypoints {
+		ft := reflect.TypeOf(entrypoint)
+		if ft == nil {
+			return nil, fmt.Errorf("Plan() requires a function, key or value but got nil")
+		}
+		if _, ok := entrypoint.(Key); ok || ft.Kind() != reflect.Func {
+			if err := visit(keyOf(entrypoint), nil); err != nil {
+				return nil, err
+			}
+			continue
+		}
+		for j := 0; j < ft.NumIn(); j++ {
+			at := ft.In(j)
+			if isVariadicArg(ft, j) && !s.canResolve(Key{Type: at}) {
+				continue
+			}
+			if err := visit(Key{Type: at}, nil); err != nil {
+				return nil, fmt.Errorf("couldn't satisfy argument %d of %s: %s", j, ft, err)
+			}
+		}
+	}
+	return out, nil
+}
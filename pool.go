@@ -0,0 +1,86 @@
+package inject
+
+import (
+	"fmt"
+	"reflect"
+	"sync"
+)
+
+// Pooled annotates a provider function to indicate that its values should be reused from a
+// sync.Pool, for expensive transient objects such as buffers or codecs.
+//
+// Each time the value is requested, one is taken from the pool, or built by the provider if the
+// pool is empty. It is returned to the pool when the injector it was requested from is closed, so
+// pooled values suit per-request child injectors. Values with a Reset() method are reset before
+// being returned to the pool.
+//
+//	injector.Bind(inject.Pooled(func() *bytes.Buffer { return &bytes.Buffer{} }))
+//	request := injector.Child()
+//	defer request.Close() // Returns the buffer to the pool.
+func Pooled(v interface{}) Annotation {
+	return &pooledType{v}
+}
+
+type pooledType struct {
+	v interface{}
+}
+
+type resetter interface {
+	Reset()
+}
+
+func (p *pooledType) Build(i *SafeInjector) (*Binding, error) {
+	next := Annotate(p.v)
+	if !next.Is(&providerType{}) {
+		return &Binding{}, fmt.Errorf("only providers can be pooled")
+	}
+	if next.Is(&singletonType{}) || next.Is(&cachedType{}) {
+		return &Binding{}, fmt.Errorf("Singleton() and Cached() providers can not be pooled")
+	}
+	binding, err := next.Build(i)
+	if err != nil || binding.disabled {
+		return binding, err
+	}
+	pool := &sync.Pool{}
+	build := binding.builder()
+	binding.annotation = "Pooled"
+	binding.setBuild(func(r *resolving) (interface{}, error) {
+		if v := pool.Get(); v != nil {
+			return v, nil
+		}
+		return build(r)
+	})
+	binding.release = func(v interface{}) {
+		if v == nil {
+			return
+		}
+		if r, ok := v.(resetter); ok {
+			r.Reset()
+		}
+		pool.Put(v)
+	}
+	return binding, nil
+}
+
+func (p *pooledType) Is(annotation Annotation) bool {
+	return reflect.TypeOf(annotation) == reflect.TypeOf(&pooledType{}) ||
+		Annotate(p.v).Is(annotation)
+}
+
+// onClose registers f to be called when s is closed.
+//
+// Cleanups registered for values built by the singleton of, if not nil, instead belong to the
+// singleton: they are called when the injector caching it is closed, or when it is reset.
+func (s *SafeInjector) onClose(of *singleton, f func()) {
+	if s.host != nil {
+		s.host.onClose(of, f)
+		return
+	}
+	entry := builtEntry{cleanup: f, of: of}
+	if of != nil {
+		s = of.owner
+	}
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	s.built = append(s.built, entry)
+}
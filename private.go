@@ -0,0 +1,87 @@
+package inject
+
+import (
+	"reflect"
+)
+
+// A PrivateModule keeps the values of its providers internal to the module, except for those of
+// the types returned by Exposes(), specified as with Get(). Private values can be injected into the
+// module's other providers, but are not visible to the injector the module is installed in, so
+// downstream code can't come to depend on them.
+//
+//	func (m *DBModule) Exposes() []interface{} { return []interface{}{&UserStore{}} }
+//	func (m *DBModule) ProvideConn() *sql.DB { ... }
+//	func (m *DBModule) ProvideUsers(db *sql.DB) *UserStore { ... }
+type PrivateModule interface {
+	Exposes() []interface{}
+}
+
+// exposedTypes returns the types exposed by module, or nil if it is not a PrivateModule.
+func exposedTypes(module interface{}) map[reflect.Type]bool {
+	private, ok := module.(PrivateModule)
+	if !ok {
+		return nil
+	}
+	exposed := map[reflect.Type]bool{}
+	for _, t := range private.Exposes() {
+		exposed[keyOf(t).Type] = true
+	}
+	return exposed
+}
+
+// isPrivateProvider returns true if method is a private provider of a module exposing exposed.
+func isPrivateProvider(method reflect.Value, exposed map[reflect.Type]bool) bool {
+	if exposed == nil {
+		return false
+	}
+	mt := method.Type()
+	return mt.NumOut() == 0 || !exposed[mt.Out(0)]
+}
+
+// privateChild creates an injector for the private providers of a module. Its bindings are only
+// visible to the public providers of the module, while its singletons and cleanups belong to s.
+func (s *SafeInjector) privateChild() *SafeInjector {
+	c := s.Child()
+	c.host = s
+	return c
+}
+
+// privateScopeType is a public module provider that resolves its arguments from the module's
+// private injector.
+type privateScopeType struct {
+	scope *SafeInjector
+	v     interface{}
+}
+
+func (p *privateScopeType) Build(i *SafeInjector) (*Binding, error) {
+	binding, err := Annotate(p.v).Build(p.scope)
+	if err != nil || binding.disabled {
+		return binding, err
+	}
+	// Private bindings are not visible outside the module, so are replaced by their requirements.
+	binding.Requires = p.scope.publicRequires(binding.Requires, map[reflect.Type]bool{})
+	return binding, nil
+}
+
+func (p *privateScopeType) Is(annotation Annotation) bool {
+	return reflect.TypeOf(annotation) == reflect.TypeOf(&privateScopeType{}) ||
+		Annotate(p.v).Is(annotation)
+}
+
+// publicRequires returns requires with each type bound privately in s replaced by its own
+// requirements, recursively.
+func (s *SafeInjector) publicRequires(requires []reflect.Type, seen map[reflect.Type]bool) []reflect.Type {
+	out := []reflect.Type{}
+	for _, req := range requires {
+		if seen[req] {
+			continue
+		}
+		seen[req] = true
+		if binding, ok := s.bindings[Key{Type: req}]; ok {
+			out = append(out, s.publicRequires(binding.Requires, seen)...)
+		} else {
+			out = append(out, req)
+		}
+	}
+	return out
+}
@@ -0,0 +1,79 @@
+package inject
+
+import (
+	"reflect"
+	"strings"
+	"sync"
+)
+
+// A Registry collects modules registered by the packages of an application, typically from their
+// init() functions, so that the application can be assembled without listing every module.
+//
+//	func init() {
+//		inject.Register(&BillingModule{})
+//	}
+type Registry struct {
+	lock    sync.Mutex
+	modules []interface{}
+}
+
+// NewRegistry creates a new, empty Registry.
+func NewRegistry() *Registry {
+	return &Registry{}
+}
+
+// DefaultRegistry is the Registry used by Register() and InstallRegistered().
+var DefaultRegistry = NewRegistry()
+
+// Register modules with the DefaultRegistry.
+func Register(modules ...interface{}) {
+	DefaultRegistry.Register(modules...)
+}
+
+// Register modules with the registry. Modules are anything accepted by Install().
+func (r *Registry) Register(modules ...interface{}) {
+	r.lock.Lock()
+	defer r.lock.Unlock()
+	r.modules = append(r.modules, modules...)
+}
+
+// Modules returns the registered modules selected by filter, in the order they were registered. A
+// nil filter selects every module.
+func (r *Registry) Modules(filter ModuleFilter) []interface{} {
+	r.lock.Lock()
+	defer r.lock.Unlock()
+	out := []interface{}{}
+	for _, module := range r.modules {
+		if filter == nil || filter(module) {
+			out = append(out, module)
+		}
+	}
+	return out
+}
+
+// A ModuleFilter selects registered modules to install.
+type ModuleFilter func(module interface{}) bool
+
+// InPackages selects modules whose types are defined in packages with any of the given import path
+// prefixes, such as "example.com/app/features/". Functions registered as modules are never
+// selected.
+func InPackages(prefixes ...string) ModuleFilter {
+	return func(module interface{}) bool {
+		t := reflect.TypeOf(module)
+		if t.Kind() == reflect.Ptr {
+			t = t.Elem()
+		}
+		for _, prefix := range prefixes {
+			if strings.HasPrefix(t.PkgPath(), prefix) {
+				return true
+			}
+		}
+		return false
+	}
+}
+
+// InstallRegistered installs the modules of the DefaultRegistry selected by filter, in the order
+// they were registered. A nil filter installs every module.
+func (s *SafeInjector) InstallRegistered(filter ModuleFilter) error {
+	return s.Install(DefaultRegistry.Modules(filter)...)
+}
@@ -0,0 +1,171 @@
+package inject
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"reflect"
+	"time"
+)
+
+// Reload re-provides the bindings of the given types, such as configuration that has changed,
+// along with everything in this injector that transitively requires them. Types are specified as
+// with Has(). Values bound as literals should be replaced with Override() before reloading.
+//
+// The singletons of the affected bindings are closed, as by Close(), and discarded. Those that had
+// been built are then rebuilt, dependencies first, so that BuildListeners see the new values and
+// provider errors are reported immediately. Finally, modules started by Start() whose bindings or
+// Start method depend on the reloaded types are stopped and started again.
+//
+// All affected values are reloaded even if some fail, in which case an Errors value is returned.
+func (s *SafeInjector) Reload(ctx context.Context, types ...interface{}) error {
+	affected := s.dependents(types)
+	// Discard affected singletons in the reverse order to which they were built.
+	s.lock.Lock()
+	built := append([]builtEntry{}, s.built...)
+	s.lock.Unlock()
+	caches := map[*singleton]Key{}
+	for _, key := range s.bindingOrder {
+		if binding := s.bindings[key]; affected[binding] && binding.cache != nil {
+			if _, ok := caches[binding.cache]; !ok {
+				caches[binding.cache] = key
+			}
+		}
+	}
+	errs := Errors{}
+	rebuild := []Key{}
+	for j := len(built) - 1; j >= 0; j-- {
+		key, ok := caches[built[j].singleton]
+		if !ok {
+			continue
+		}
+		if err := built[j].singleton.discard(); err != nil {
+			errs = append(errs, err)
+		}
+		rebuild = append([]Key{key}, rebuild...)
+	}
+	for _, key := range rebuild {
+		if _, err := s.GetKey(key); err != nil {
+			errs = append(errs, fmt.Errorf("failed to reload %s: %s", key, err))
+		}
+	}
+	if err := s.restartModules(ctx, affected); err != nil {
+		errs = append(errs, err)
+	}
+	if len(errs) > 0 {
+		return errs
+	}
+	return nil
+}
+
+// dependents returns the bindings of types, and the bindings of this injector that transitively
+// require them.
+func (s *SafeInjector) dependents(types []interface{}) map[*Binding]bool {
+	affected := map[*Binding]bool{}
+	for _, t := range types {
+		if binding, _, err := s.resolveOwner(keyOf(t)); err == nil {
+			affected[binding] = true
+		}
+	}
+	for changed := true; changed; {
+		changed = false
+		for _, key := range s.bindingOrder {
+			binding := s.bindings[key]
+			if !affected[binding] && s.requiresAny(binding.Requires, affected) {
+				affected[binding] = true
+				changed = true
+			}
+		}
+	}
+	return affected
+}
+
+// requiresAny returns true if any of requires resolves to one of bindings.
+func (s *SafeInjector) requiresAny(requires []reflect.Type, bindings map[*Binding]bool) bool {
+	for _, req := range requires {
+		if binding, _, err := s.resolveOwner(Key{Type: req}); err == nil && bindings[binding] {
+			return true
+		}
+	}
+	return false
+}
+
+// restartModules stops then starts the modules started by Start() that bind, or whose Start method
+// requires, any of the affected bindings.
+func (s *SafeInjector) restartModules(ctx context.Context, affected map[*Binding]bool) error {
+	restart := map[reflect.Type]bool{}
+	for _, t := range s.started {
+		for _, key := range s.moduleKeys[t] {
+			restart[t] = restart[t] || affected[s.bindings[key]]
+		}
+		if start, ok := s.moduleMethod(t, "Start"); ok {
+			requires := []reflect.Type{}
+			for j := 1; j < start.Type().NumIn(); j++ {
+				requires = append(requires, start.Type().In(j))
+			}
+			restart[t] = restart[t] || s.requiresAny(requires, affected)
+		}
+	}
+	errs := Errors{}
+	for j := len(s.started) - 1; j >= 0; j-- {
+		t := s.started[j]
+		if !restart[t] {
+			continue
+		}
+		if stop, ok := s.moduleMethod(t, "Stop"); ok && checkLifecycleMethod(stop.Type(), false) == nil {
+			out := stop.Call([]reflect.Value{reflect.ValueOf(ctx)})
+			if err, _ := out[0].Interface().(error); err != nil {
+				errs = append(errs, fmt.Errorf("module %s: failed to stop: %s", t, err))
+			}
+		}
+	}
+	started := []reflect.Type{}
+	for _, t := range s.started {
+		if restart[t] {
+			start, _ := s.moduleMethod(t, "Start")
+			if _, err := s.CallWith(start.Interface(), ctx); err != nil {
+				errs = append(errs, fmt.Errorf("module %s: failed to start: %s", t, err))
+				continue
+			}
+		}
+		started = append(started, t)
+	}
+	s.started = started
+	if len(errs) > 0 {
+		return errs
+	}
+	return nil
+}
+
+// WatchFile calls Reload() with types each time the file at path changes, as detected by polling
+// its size and modification time every interval, until ctx is done. Reload errors are passed to
+// onError, if it is not nil.
+//
+//	go injector.WatchFile(ctx, "config.yaml", time.Second, logError, &Config{})
+func (s *SafeInjector) WatchFile(ctx context.Context, path string, interval time.Duration, onError func(error), types ...interface{}) error {
+	last, err := os.Stat(path)
+	if err != nil {
+		return err
+	}
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return nil
+		case <-ticker.C:
+		}
+		info, err := os.Stat(path)
+		if err != nil {
+			// The file may be being replaced.
+			continue
+		}
+		if info.Size() == last.Size() && info.ModTime().Equal(last.ModTime()) {
+			continue
+		}
+		last = info
+		if err := s.Reload(ctx, types...); err != nil && onError != nil {
+			onError(err)
+		}
+	}
+}
@@ -0,0 +1,75 @@
+package inject
+
+// A resolution is a cached result of resolveOwner().
+type resolution struct {
+	binding *Binding
+	owner   *SafeInjector
+	err     error
+	// Generations of the injectors the key was resolved from, when it was resolved.
+	generations []uint64
+}
+
+// resolveOwner resolves the binding for key, and the injector (this one or a parent) that owns it.
+//
+// Results are cached per injector until the bindings of it or any injector it resolves from
+// change, so that deep chains of children don't repeatedly scan their ancestors' bindings.
+// Results are not cached if any of those injectors has a Resolver, which may not be repeatable.
+func (s *SafeInjector) resolveOwner(key Key) (*Binding, *SafeInjector, error) {
+	generations, cacheable := s.resolutionGenerations(nil)
+	if cacheable {
+		s.lock.Lock()
+		r, ok := s.resolutions[key]
+		s.lock.Unlock()
+		if ok && equalGenerations(r.generations, generations) {
+			return r.binding, r.owner, r.err
+		}
+	}
+	binding, owner, err := s.resolveOwnerUncached(key)
+	if cacheable {
+		s.lock.Lock()
+		if s.resolutions == nil {
+			s.resolutions = map[Key]*resolution{}
+		}
+		s.resolutions[key] = &resolution{binding: binding, owner: owner, err: err, generations: generations}
+		s.lock.Unlock()
+	}
+	return binding, owner, err
+}
+
+// resolutionGenerations appends the generations of s and every injector it may resolve from to
+// generations, and reports whether none of them has a Resolver.
+func (s *SafeInjector) resolutionGenerations(generations []uint64) ([]uint64, bool) {
+	cacheable := true
+	for p := s; p != nil; p = p.parent {
+		p.lock.Lock()
+		generations = append(generations, p.generation)
+		cacheable = cacheable && len(p.resolvers) == 0
+		p.lock.Unlock()
+		for _, parent := range p.parents {
+			var ok bool
+			generations, ok = parent.resolutionGenerations(generations)
+			cacheable = cacheable && ok
+		}
+	}
+	return generations, cacheable
+}
+
+func equalGenerations(a, b []uint64) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	for j := range a {
+		if a[j] != b[j] {
+			return false
+		}
+	}
+	return true
+}
+
+// invalidateResolutions discards cached resolutions that depend on the bindings of s, when they
+// change.
+func (s *SafeInjector) invalidateResolutions() {
+	s.lock.Lock()
+	s.generation++
+	s.lock.Unlock()
+}
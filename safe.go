@@ -1,26 +1,70 @@
 package inject
 
 import (
+	"context"
 	"fmt"
 	"reflect"
+	"runtime/debug"
 	"strings"
-
-	"github.com/jinzhu/copier"
+	"sync"
 )
 
 // SafeInjector is an IoC container.
 type SafeInjector struct {
+	*injectorState
+	// Build that this view of the injector was injected into, if any, see view().
+	requester *resolving
+}
+
+// injectorState is the state of a SafeInjector, shared by the views of it injected into providers.
+type injectorState struct {
 	parent       *SafeInjector
-	bindings     map[reflect.Type]*Binding
-	bindingOrder []reflect.Type
-	stack        map[reflect.Type]bool
+	bindings     map[Key]*Binding
+	bindingOrder []Key
 	modules      map[reflect.Type]reflect.Value
+	singletons   []*singleton
+	built        []builtEntry // Singletons and cleanups in the order they were built or registered.
+	lock         sync.Mutex
+	history      []bindRecord           // Successful Bind() and BindTo() calls, for Merge().
+	installing   []reflect.Type         // Stack of modules currently being installed.
+	moduleOrder  []reflect.Type         // Module types in the order they were installed.
+	moduleKeys   map[reflect.Type][]Key // Keys bound by each module, for ModuleBindings().
+	listeners    []BuildListener
+	observers    []Observer
+	tracing      *Tracer
+	shared       *sharedSingletons     // Singleton caches shared with siblings, see ShareSingletons().
+	host         *SafeInjector         // Injector owning the lifecycle of a module's private bindings.
+	started      []reflect.Type        // Modules started by Start(), in the order they were started.
+	resolvers    []Resolver            // Fallbacks for unbound keys, see AddResolver().
+	warnings     []string              // Warnings about deprecated bindings, see Warnings().
+	warned       map[Key]bool          // Keys that have been warned about.
+	workers      chan struct{}         // Worker slots for building arguments, see SetParallelism().
+	strict       bool                  // Check requirements when binding, see SetStrict().
+	expected     map[reflect.Type]bool // Types expected to be bound later, see Expect().
+	binding      int                   // Depth of nested strictly() calls.
+	unchecked    []Key                 // Keys bound since the outermost strictly() call.
+	logger       Logger                // See SetLogger().
+	middleware   []Middleware          // See Use().
+	conflicts    ConflictPolicy        // See SetConflictPolicy().
+	collect      bool                  // See SetCollectBindings().
+	parents      []*SafeInjector       // Additional parents, see WithParents().
+	scopes       scopes                // Children created by Scoped().
+	processors   []postProcessor       // See AfterBuild().
+	adapt        bool                  // See SetAdaptPointers().
+	ctx          context.Context       // See SetContext().
+	generation   uint64                // Incremented when bindings change, see resolveOwner().
+	resolutions  map[Key]*resolution   // Cached results of resolveOwner().
+	// Singleton caches shared by children created with ShareSingletons(true).
+	childSingletons *sharedSingletons
 }
 
 type SafeBinder interface {
 	Bind(things ...interface{}) error
 	BindTo(to interface{}, impl interface{}) error
 	Install(module ...interface{}) error
+	Has(t interface{}) bool
+	Override(things ...interface{}) error
+	Provide(name string, provider interface{}) error
 }
 
 var _ SafeBinder = &SafeInjector{}
@@ -29,13 +73,16 @@ var _ SafeBinder = &SafeInjector{}
 //
 // The injector itself is already bound, as is an implementation of the Binder interface.
 func SafeNew() *SafeInjector {
-	s := &SafeInjector{
-		bindings: map[reflect.Type]*Binding{},
-		stack:    map[reflect.Type]bool{},
-		modules:  map[reflect.Type]reflect.Value{},
-	}
+	s := &SafeInjector{injectorState: &injectorState{
+		bindings:   map[Key]*Binding{},
+		modules:    map[reflect.Type]reflect.Value{},
+		moduleKeys: map[reflect.Type][]Key{},
+	}}
 	s.Bind(s)
 	s.BindTo((*SafeBinder)(nil), s)
+	s.history = nil
+	s.bindViews(func(r *resolving) interface{} { return s.view(r) },
+		reflect.TypeOf(s), reflect.TypeOf((*SafeBinder)(nil)).Elem())
 	return s
 }
 
@@ -44,21 +91,85 @@ func (s *SafeInjector) Unsafe() *Injector {
 }
 
 // Install installs a module. See Injector.Install() for details.
-func (s *SafeInjector) Install(modules ...interface{}) (err error) { // nolint: gocyclo
+func (s *SafeInjector) Install(modules ...interface{}) error {
+	modules, options, err := splitInstallOptions(modules)
+	if err != nil {
+		return err
+	}
+	return s.strictly(func() error { return s.install(modules, options) })
+}
+
+func (s *SafeInjector) install(modules []interface{}, options *installOptions) (err error) { // nolint: gocyclo
 	// Capture panics and return them as errors.
+	depth := len(s.installing)
 	defer func() {
+		var module reflect.Type
+		if len(s.installing) > depth {
+			module = s.installing[len(s.installing)-1]
+		}
+		s.installing = s.installing[:depth]
 		if e := recover(); e != nil {
-			err = e.(error)
+			// Errors are panicked by the Injector passed to Module.Configure().
+			if perr, ok := e.(error); ok {
+				err = perr
+			} else {
+				err = &PanicError{Module: module, Value: e, Stack: debug.Stack()}
+			}
 		}
 	}()
 	for _, module := range modules {
+		// Provider bundles and function modules.
+		switch module := module.(type) {
+		case []interface{}:
+			if err := s.install(module, options); err != nil {
+				return err
+			}
+			continue
+		case Annotation:
+			if err := s.Bind(module); err != nil {
+				return err
+			}
+			continue
+		}
+		if reflect.TypeOf(module).Kind() == reflect.Func {
+			if err := s.Bind(options.scope(module)); err != nil {
+				return err
+			}
+			continue
+		}
 		m := reflect.ValueOf(module)
+		moduleType := m.Type()
+		// Modules passed by value are copied, so that methods with pointer receivers are also found.
+		if m.Kind() == reflect.Struct {
+			p := reflect.New(m.Type())
+			p.Elem().Set(m)
+			m, module = p, p.Interface()
+		}
 		im := reflect.Indirect(m)
 		// Duplicate module?
 		if existing, ok := s.modules[im.Type()]; ok {
-			return s.handleDuplicate(existing.Addr(), m)
+			if err := s.handleDuplicate(existing.Addr(), m); err != nil {
+				return err
+			}
+			continue
+		}
+		if im.Kind() == reflect.Struct {
+			if err := s.injectModuleFields(m); err != nil {
+				return err
+			}
+		}
+		s.installing = append(s.installing, im.Type())
+		if module, ok := module.(ValidatingModule); ok {
+			if err := module.Validate(); err != nil {
+				return fmt.Errorf("module %s: Validate: %s", im.Type(), err)
+			}
 		}
-		if module, ok := module.(Module); ok {
+		switch module := module.(type) {
+		case SafeModule:
+			if err := module.Configure(s); err != nil {
+				return err
+			}
+		case Module:
 			// Unsafe panics are captured by the enclosing defer().
 			unsafe := &Injector{safe: s}
 			if err := module.Configure(unsafe); err != nil {
@@ -66,139 +177,530 @@ func (s *SafeInjector) Install(modules ...interface{}) (err error) { // nolint:
 			}
 		}
 		s.modules[im.Type()] = im
+		s.moduleOrder = append(s.moduleOrder, im.Type())
 		if reflect.Indirect(m).Kind() != reflect.Struct {
 			return fmt.Errorf("only structs may be used as modules but got %s", m.Type())
 		}
 		mt := m.Type()
-		for j := 0; j < m.NumMethod(); j++ {
-			method := m.Method(j)
-			methodType := mt.Method(j)
-			if strings.HasPrefix(methodType.Name, "Provide") {
-				provider := Provider(method.Interface())
+		// Private providers are bound first, so that public providers can depend on them.
+		exposed := exposedTypes(module)
+		var private *SafeInjector
+		errs := Errors{}
+		for _, privatePass := range []bool{true, false} {
+			for j := 0; j < m.NumMethod(); j++ {
+				method := m.Method(j)
+				methodType := mt.Method(j)
+				if !strings.HasPrefix(methodType.Name, options.prefix) || isPrivateProvider(method, exposed) != privatePass {
+					continue
+				}
+				// Prefer the declared method to the wrapper generated for the pointer's method set.
+				fn := methodType.Func
+				if vm, ok := im.Type().MethodByName(methodType.Name); ok {
+					fn = vm.Func
+				}
+				var provider Annotation = &providerType{v: method.Interface(), name: funcName(fn), id: methodID(m, methodType.Name)}
+				target := s
+				if privatePass {
+					if private == nil {
+						private = s.privateChild()
+					}
+					target = private
+				} else if private != nil {
+					provider = &privateScopeType{private, provider}
+				}
 				switch {
-				case strings.Contains(methodType.Name, "Mapping"):
+				case strings.Contains(methodType.Name, options.markers.Mapping):
 					provider = Mapping(provider)
-				case strings.Contains(methodType.Name, "Sequence"):
+				case strings.Contains(methodType.Name, options.markers.Sequence):
 					provider = Sequence(provider)
-				case !strings.Contains(methodType.Name, "Multi"):
-					provider = Singleton(provider)
+				case strings.Contains(methodType.Name, options.markers.Multi):
+					provider = Transient(provider)
+				default:
+					provider = options.scope(provider)
 				}
-				if err := s.Bind(provider); err != nil {
-					return err
+				if err := target.Bind(provider); err != nil {
+					errs = append(errs, &ProviderError{Module: moduleType, Method: methodType.Name, Err: err})
 				}
 			}
 		}
+		if len(errs) > 0 {
+			return errs
+		}
+		if module, ok := module.(DeprecatedModule); ok {
+			s.deprecateModule(im.Type(), module.Deprecated())
+		}
+		s.log("debug", "installed module", "module", im.Type())
+		s.installing = s.installing[:len(s.installing)-1]
 	}
 	return nil
 }
 
+// handleDuplicate merges incoming into the existing module of the same type, field by field.
+//
+// Non-zero exported fields of incoming fill zero fields of existing, and fields that are non-zero
+// in both must be equal. Unexported fields can't be merged individually, so are treated as a
+// whole: those of incoming are used if all of existing's are zero, and must otherwise be zero or
+// equal. Injected fields are not part of a module's configuration, so are ignored.
 func (s *SafeInjector) handleDuplicate(existing reflect.Value, incoming reflect.Value) error {
-	if reflect.DeepEqual(incoming.Interface(), existing.Interface()) {
-		return nil
+	e := existing.Elem()
+	t := e.Type()
+	in := reflect.Indirect(incoming)
+	injected := map[int]bool{}
+	for _, j := range injectedFields(t) {
+		injected[j] = true
+	}
+	merged := reflect.New(t).Elem()
+	merged.Set(e)
+	hiddenConflict := false
+	switch hidden, incomingHidden := unexportedFields(e), unexportedFields(in); {
+	case incomingHidden.IsZero() || reflect.DeepEqual(hidden.Interface(), incomingHidden.Interface()):
+	case hidden.IsZero():
+		merged.Set(in)
+		for j := 0; j < t.NumField(); j++ {
+			if t.Field(j).PkgPath == "" {
+				merged.Field(j).Set(e.Field(j))
+			}
+		}
+	default:
+		hiddenConflict = true
+	}
+	conflicts := []string{}
+	for j := 0; j < t.NumField(); j++ {
+		if injected[j] || t.Field(j).PkgPath != "" {
+			continue
+		}
+		mf, inf := merged.Field(j), in.Field(j)
+		switch {
+		case inf.IsZero() || reflect.DeepEqual(mf.Interface(), inf.Interface()):
+		case mf.IsZero():
+			mf.Set(inf)
+		default:
+			conflicts = append(conflicts, t.Field(j).Name)
+		}
+	}
+	if hiddenConflict {
+		conflicts = append(conflicts, "unexported fields")
 	}
-	zero := reflect.New(incoming.Type().Elem()).Interface()
-	// Incoming is the zero value, we keep our existing copy.
-	if reflect.DeepEqual(incoming.Interface(), zero) {
+	if len(conflicts) > 0 {
+		return fmt.Errorf("duplicate module %s has conflicting values for %s", t, strings.Join(conflicts, ", "))
+	}
+	e.Set(merged)
+	return nil
+}
+
+// unexportedFields returns a copy of the struct v with its exported fields cleared.
+func unexportedFields(v reflect.Value) reflect.Value {
+	out := reflect.New(v.Type()).Elem()
+	out.Set(v)
+	for j := 0; j < out.NumField(); j++ {
+		if out.Type().Field(j).PkgPath == "" {
+			out.Field(j).Set(reflect.Zero(out.Field(j).Type()))
+		}
+	}
+	return out
+}
+
+// injectModuleFields populates fields of the module tagged with `inject:""` from existing bindings.
+func (s *SafeInjector) injectModuleFields(m reflect.Value) error {
+	im := reflect.Indirect(m)
+	fields := injectedFields(im.Type())
+	if len(fields) == 0 {
 		return nil
-	} else if reflect.DeepEqual(existing.Interface(), zero) {
-		return copier.Copy(existing.Interface(), incoming.Interface())
 	}
-	return fmt.Errorf("duplicate unequal module: %#v != %#v", incoming.Interface(), existing.Interface())
+	for _, j := range fields {
+		f := im.Type().Field(j)
+		if f.PkgPath != "" {
+			return fmt.Errorf("injected field %s.%s must be exported", im.Type(), f.Name)
+		}
+		v, err := s.getReflected(f.Type)
+		if err != nil {
+			return fmt.Errorf("couldn't inject field %s.%s: %s", im.Type(), f.Name, err)
+		}
+		if v != nil {
+			im.Field(j).Set(reflect.ValueOf(v))
+		}
+	}
+	return nil
+}
+
+// injectedFields returns the indices of struct fields tagged with `inject:""`.
+func injectedFields(t reflect.Type) []int {
+	out := []int{}
+	for j := 0; j < t.NumField(); j++ {
+		if _, ok := t.Field(j).Tag.Lookup("inject"); ok {
+			out = append(out, j)
+		}
+	}
+	return out
 }
 
 // Bind binds a value to the injector. See Injector.Bind() for details.
 func (s *SafeInjector) Bind(things ...interface{}) error {
-	for _, v := range things {
-		annotation := Annotate(v)
-		binding, err := annotation.Build(s)
-		if err != nil {
-			return err
+	values, options := splitBindOptions(things)
+	policy := s.conflictPolicy()
+	if options.conflict != nil {
+		policy = *options.conflict
+	}
+	return s.strictly(func() error {
+		if !options.isZero() || policy != ConflictError {
+			return s.bindWithOptions(values, options, policy)
 		}
-		if _, ok := s.bindings[binding.Provides]; ok && !(annotation.Is(&sequenceType{}) ||
-			annotation.Is(&mappingType{})) {
-			return fmt.Errorf("%s is already bound", binding.Provides)
+		for _, v := range values {
+			if _, err := s.bind(v); err != nil {
+				return err
+			}
 		}
-		s.bindings[binding.Provides] = binding
-		s.bindingOrder = append(s.bindingOrder, binding.Provides)
+		return nil
+	})
+}
+
+// bind a single value, returning the key it was bound to, or the zero Key if an If() condition
+// prevented it from being bound.
+func (s *SafeInjector) bind(v interface{}) (Key, error) {
+	key, err := s.bindAnnotation(Annotate(v))
+	if err != nil {
+		return Key{}, err
 	}
-	return nil
+	s.history = append(s.history, bindRecord{impl: v})
+	return key, nil
+}
+
+func (s *SafeInjector) bindAnnotation(annotation Annotation) (Key, error) {
+	binding, err := annotation.Build(s)
+	if err != nil || binding.disabled {
+		return Key{}, err
+	}
+	return s.bindBuilt(binding, annotation.Is(&sequenceType{}) || annotation.Is(&mappingType{}))
+}
+
+// bindBuilt binds binding to the key it provides. merges is true if the binding merges with an
+// existing binding, as sequences and mappings do.
+func (s *SafeInjector) bindBuilt(binding *Binding, merges bool) (Key, error) {
+	key := Key{Type: binding.Provides, Name: binding.Name}
+	if skip, err := s.checkRebind(key, binding, merges); err != nil || skip {
+		return key, err
+	}
+	if err := s.addAcyclicBinding(key, binding); err != nil {
+		return Key{}, err
+	}
+	if isOutStruct(key.Type) {
+		if err := s.bindOutFields(key); err != nil {
+			return Key{}, err
+		}
+	}
+	return key, nil
+}
+
+// checkRebind checks whether binding may be bound to key given any existing binding, returning
+// skip if binding is a Default() that should be silently dropped.
+//
+// merges is true if the binding merges with an existing binding, as sequences and mappings do.
+func (s *SafeInjector) checkRebind(key Key, binding *Binding, merges bool) (skip bool, err error) {
+	existing, ok := s.bindings[key]
+	switch {
+	case !ok:
+		return false, nil
+	case binding.isDefault:
+		return true, nil
+	case existing.isDefault || merges:
+		return false, nil
+	}
+	return false, fmt.Errorf("%s is already bound", key)
+}
+
+func (s *SafeInjector) addBinding(key Key, binding *Binding) {
+	if _, ok := s.bindings[key]; !ok {
+		s.bindingOrder = append(s.bindingOrder, key)
+	}
+	s.bindings[key] = binding
+	s.invalidateResolutions()
+	if s.binding > 0 {
+		s.unchecked = append(s.unchecked, key)
+	}
+	s.log("debug", "bound", "key", key, "annotation", binding.annotation)
+	if len(s.installing) > 0 {
+		module := s.installing[len(s.installing)-1]
+		if !containsKey(s.moduleKeys[module], key) {
+			s.moduleKeys[module] = append(s.moduleKeys[module], key)
+		}
+	}
+}
+
+func containsKey(keys []Key, key Key) bool {
+	for _, k := range keys {
+		if k == key {
+			return true
+		}
+	}
+	return false
+}
+
+// Has returns true if a value of type t can be resolved by the injector, including from bindings
+// of parent injectors. Types are specified as with Get(), or as a Key for named bindings.
+func (s *SafeInjector) Has(t interface{}) bool {
+	return s.canResolve(keyOf(t))
+}
+
+// keyOf returns the key for t, which is either a Key or a value of the type to resolve, with
+// pointers to interfaces identifying the interface.
+func keyOf(t interface{}) Key {
+	key, ok := t.(Key)
+	if !ok {
+		key = Key{Type: reflect.TypeOf(t)}
+	}
+	if key.Type.Kind() == reflect.Ptr && key.Type.Elem().Kind() == reflect.Interface {
+		key.Type = key.Type.Elem()
+	}
+	return key
+}
+
+// Override binds values as with Bind(), replacing any existing bindings of the same keys.
+func (s *SafeInjector) Override(things ...interface{}) error {
+	values, options := splitBindOptions(things)
+	return s.strictly(func() error { return s.bindWithOptions(values, options, ConflictReplace) })
+}
+
+// Provide binds provider under name. It is equivalent to Bind(provider, Name(name)).
+func (s *SafeInjector) Provide(name string, provider interface{}) error {
+	return s.Bind(provider, Name(name))
 }
 
 // BindTo binds an implementation to an interface. See Injector.BindTo() for details.
 func (s *SafeInjector) BindTo(as interface{}, impl interface{}) error {
+	return s.strictly(func() error {
+		_, err := s.bindTo(as, impl, s.conflictPolicy())
+		return err
+	})
+}
+
+// bindTo binds impl to as, returning the key it was bound to. An existing binding of the key is
+// resolved according to policy.
+func (s *SafeInjector) bindTo(as interface{}, impl interface{}, policy ConflictPolicy) (Key, error) {
 	ift := reflect.TypeOf(as)
 	binding, err := Annotate(impl).Build(s)
 	if err != nil {
-		return err
+		return Key{}, err
 	}
-	if _, ok := s.bindings[ift]; ok {
-		return fmt.Errorf("%s is already bound", ift)
+	if binding.disabled {
+		s.history = append(s.history, bindRecord{as: as, impl: impl})
+		return Key{}, nil
 	}
 	// Pointer to an interface...
-	if ift.Kind() == reflect.Ptr && ift.Elem().Kind() == reflect.Interface {
+	isInterface := ift.Kind() == reflect.Ptr && ift.Elem().Kind() == reflect.Interface
+	if isInterface {
 		ift = ift.Elem()
+	}
+	key := Key{Type: ift, Name: binding.Name}
+	if policy != ConflictError {
+		if ok, err := s.resolveConflict(key, policy); !ok || err != nil {
+			return key, err
+		}
+	}
+	if skip, err := s.checkRebind(key, binding, false); err != nil {
+		return Key{}, err
+	} else if skip {
+		s.history = append(s.history, bindRecord{as: as, impl: impl})
+		return key, nil
+	}
+	bound := *binding
+	bound.Provides = ift
+	if isInterface {
 		if !binding.Provides.Implements(ift) {
-			return fmt.Errorf("implementation %s does not implement interface %s", binding.Provides, ift)
-		}
-		s.bindings[ift] = binding
-	} else if binding.Provides.ConvertibleTo(ift) {
-		s.bindings[ift] = &Binding{
-			Provides: binding.Provides,
-			Requires: binding.Requires,
-			Build: func() (interface{}, error) {
-				v, err := binding.Build()
-				if err != nil {
-					return nil, err
-				}
-				return reflect.ValueOf(v).Convert(ift).Interface(), nil
-			},
+			return Key{}, fmt.Errorf("implementation %s does not implement interface %s", binding.Provides, ift)
 		}
+	} else if binding.Provides.Kind() == reflect.Interface && ift.Implements(binding.Provides) {
+		// Values provided as an interface are asserted to the concrete type when built.
+		bound.setBuild(func(r *resolving) (interface{}, error) {
+			v, err := binding.buildIn(r)
+			if err != nil {
+				return nil, err
+			}
+			if reflect.TypeOf(v) != ift {
+				return nil, fmt.Errorf("%s value %T can not be converted to %s", binding.Provides, v, ift)
+			}
+			return v, nil
+		})
+	} else if convertibleTo(binding.Provides, ift) {
+		bound.setBuild(func(r *resolving) (interface{}, error) {
+			v, err := binding.buildIn(r)
+			if err != nil {
+				return nil, err
+			}
+			return convert(reflect.ValueOf(v), ift).Interface(), nil
+		})
 	} else {
-		return fmt.Errorf("implementation %s can not be converted to %s", binding.Provides, ift)
+		return Key{}, fmt.Errorf("implementation %s can not be converted to %s", binding.Provides, ift)
 	}
-	s.bindingOrder = append(s.bindingOrder, ift)
-	return nil
+	if err := s.addAcyclicBinding(key, &bound); err != nil {
+		return Key{}, err
+	}
+	s.history = append(s.history, bindRecord{as: as, impl: impl})
+	return key, nil
+}
+
+// Bindings returns the bindings of this injector, excluding those of any parent, in the order
+// they were first bound.
+func (s *SafeInjector) Bindings() []*Binding {
+	out := make([]*Binding, 0, len(s.bindingOrder))
+	for _, key := range s.bindingOrder {
+		out = append(out, s.bindings[key])
+	}
+	return out
+}
+
+// Modules returns the modules installed in this injector, excluding those of any parent, in the
+// order they were installed. Modules installed by pointer are returned as pointers.
+func (s *SafeInjector) Modules() []interface{} {
+	out := make([]interface{}, 0, len(s.moduleOrder))
+	for _, t := range s.moduleOrder {
+		m := s.modules[t]
+		if m.CanAddr() {
+			out = append(out, m.Addr().Interface())
+		} else {
+			out = append(out, m.Interface())
+		}
+	}
+	return out
 }
 
-func (s *SafeInjector) resolveSlice(t reflect.Type) (*Binding, error) {
+// ModuleBindings returns the bindings contributed by an installed module, from both its Provide*
+// methods and its Configure method. module may be a module value or a pointer to one.
+//
+// Bindings made by modules that module itself installs are attributed to those modules.
+func (s *SafeInjector) ModuleBindings(module interface{}) ([]*Binding, error) {
+	t := reflect.Indirect(reflect.ValueOf(module)).Type()
+	if _, ok := s.modules[t]; !ok {
+		return nil, fmt.Errorf("module %s is not installed", t)
+	}
+	out := []*Binding{}
+	for _, key := range s.moduleKeys[t] {
+		if binding, ok := s.bindings[key]; ok {
+			out = append(out, binding)
+		}
+	}
+	return out, nil
+}
+
+// moduleOf returns the type of the installed module that bound key, if any.
+func (s *SafeInjector) moduleOf(key Key) (reflect.Type, bool) {
+	for _, t := range s.moduleOrder {
+		if containsKey(s.moduleKeys[t], key) {
+			return t, true
+		}
+	}
+	return nil, false
+}
+
+// SetCollectBindings controls whether requests for a slice []T made to this injector or its
+// children also collect the unnamed bindings of single values assignable to T, such as
+// individually bound implementations of an interface, alongside any Sequence() bindings. An
+// explicit binding of []T itself still takes precedence.
+func (s *SafeInjector) SetCollectBindings(collect bool) {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	s.collect = collect
+	s.generation++
+}
+
+// isCollecting returns true if s or any of its ancestors collects bindings into slices.
+func (s *SafeInjector) isCollecting() bool {
+	for ; s != nil; s = s.parent {
+		s.lock.Lock()
+		collect := s.collect
+		s.lock.Unlock()
+		if collect {
+			return true
+		}
+	}
+	return false
+}
+
+// resolveSlice returns a binding merging all slice bindings whose elements are assignable to
+// elements of t, and, if collecting, single bindings assignable to them, and the number of
+// bindings merged.
+func (s *SafeInjector) resolveSlice(t reflect.Type) (*Binding, int) {
 	et := t.Elem()
+	collect := s.isCollecting()
 	bindings := []*Binding{}
-	for _, bt := range s.bindingOrder {
-		binding := s.bindings[bt]
-		if bt.Kind() == reflect.Slice && bt.Elem().Implements(et) {
+	single := map[*Binding]bool{}
+	for _, key := range s.bindingOrder {
+		binding, bt := s.bindings[key], key.Type
+		switch {
+		case key.Name != "" || bt == t:
+		case bt.Kind() == reflect.Slice && bt.Elem().AssignableTo(et):
+			bindings = append(bindings, binding)
+		case collect && bt.AssignableTo(et):
 			bindings = append(bindings, binding)
+			single[binding] = true
 		}
 	}
 	requires := []reflect.Type{}
 	for _, binding := range bindings {
 		requires = append(requires, binding.Requires...)
 	}
-	return &Binding{
+	merged := &Binding{
 		Provides: t,
 		Requires: requires,
-		Build: func() (interface{}, error) {
-			out := reflect.MakeSlice(t, 0, 0)
-			for _, binding := range bindings {
-				fout, err := binding.Build()
-				if err != nil {
-					return nil, err
-				}
-				foutv := reflect.ValueOf(fout)
-				for s := 0; s < foutv.Len(); s++ {
-					out = reflect.Append(out, foutv.Index(s))
+	}
+	merged.setBuild(func(r *resolving) (interface{}, error) {
+		out := reflect.MakeSlice(t, 0, 0)
+		for _, binding := range bindings {
+			fout, err := binding.buildIn(r)
+			if err != nil {
+				return nil, err
+			}
+			if single[binding] {
+				if fout == nil {
+					out = reflect.Append(out, reflect.Zero(et))
+				} else {
+					out = reflect.Append(out, reflect.ValueOf(fout))
 				}
+				continue
 			}
-			return out.Interface(), nil
-		},
-	}, nil
+			foutv := reflect.ValueOf(fout)
+			for s := 0; s < foutv.Len(); s++ {
+				out = reflect.Append(out, foutv.Index(s))
+			}
+		}
+		return out.Interface(), nil
+	})
+	return merged, len(bindings)
+}
+
+// resolveChan returns a binding converting the bidirectional channel bound to this injector with
+// the same element type and name as key to the directional channel key.Type, or nil.
+func (s *SafeInjector) resolveChan(key Key) *Binding {
+	t := key.Type
+	if t.Kind() != reflect.Chan || t.ChanDir() == reflect.BothDir {
+		return nil
+	}
+	bidi, ok := s.bindings[Key{Type: reflect.ChanOf(reflect.BothDir, t.Elem()), Name: key.Name}]
+	if !ok {
+		return nil
+	}
+	binding := &Binding{
+		Provides: t,
+		Name:     key.Name,
+		Requires: []reflect.Type{bidi.Provides},
+	}
+	binding.setBuild(func(r *resolving) (interface{}, error) {
+		v, err := bidi.buildIn(r)
+		if err != nil {
+			return nil, err
+		}
+		return reflect.ValueOf(v).Convert(t).Interface(), nil
+	})
+	return binding
 }
 
-func (s *SafeInjector) resolveMapping(t reflect.Type) (*Binding, error) {
+// resolveMapping returns a binding merging all map bindings with the same key type as t whose
+// values are assignable to values of t, and the number of bindings merged.
+func (s *SafeInjector) resolveMapping(t reflect.Type) (*Binding, int) {
 	et := t.Elem()
 	bindings := []*Binding{}
-	for _, bt := range s.bindingOrder {
-		binding := s.bindings[bt]
-		if bt.Kind() == reflect.Map && bt.Key() == t.Key() && bt.Elem().Implements(et) {
+	for _, key := range s.bindingOrder {
+		binding, bt := s.bindings[key], key.Type
+		if key.Name == "" && bt != t && bt.Kind() == reflect.Map && bt.Key() == t.Key() && bt.Elem().AssignableTo(et) {
 			bindings = append(bindings, binding)
 		}
 	}
@@ -206,91 +708,386 @@ func (s *SafeInjector) resolveMapping(t reflect.Type) (*Binding, error) {
 	for _, binding := range bindings {
 		requires = append(requires, binding.Requires...)
 	}
-	return &Binding{
+	merged := &Binding{
 		Provides: t,
 		Requires: requires,
-		Build: func() (interface{}, error) {
-			out := reflect.MakeMap(t)
-			for _, binding := range bindings {
-				fout, err := binding.Build()
-				if err != nil {
-					return nil, err
-				}
-				foutv := reflect.ValueOf(fout)
-				for _, key := range foutv.MapKeys() {
-					out.SetMapIndex(key, foutv.MapIndex(key))
-				}
+	}
+	merged.setBuild(func(r *resolving) (interface{}, error) {
+		out := reflect.MakeMap(t)
+		for _, binding := range bindings {
+			fout, err := binding.buildIn(r)
+			if err != nil {
+				return nil, err
+			}
+			foutv := reflect.ValueOf(fout)
+			for _, key := range foutv.MapKeys() {
+				out.SetMapIndex(key, foutv.MapIndex(key))
 			}
-			return out.Interface(), nil
-		},
-	}, nil
+		}
+		return out.Interface(), nil
+	})
+	return merged, len(bindings)
 }
 
 func (s *SafeInjector) resolve(t reflect.Type) (*Binding, error) {
-	if binding, ok := s.bindings[t]; ok {
-		return binding, nil
+	binding, _, err := s.resolveOwner(Key{Type: t})
+	return binding, err
+}
+
+// resolveOwnerUncached resolves the binding for key, and the injector that owns it, without
+// consulting the cache of resolveOwner().
+func (s *SafeInjector) resolveOwnerUncached(key Key) (*Binding, *SafeInjector, error) {
+	if binding, ok := s.bindings[key]; ok {
+		return binding, s, nil
+	}
+	// context.Context is resolved from the nearest injector with a context.
+	if key == (Key{Type: contextType}) {
+		if binding := s.contextBinding(); binding != nil {
+			return binding, s, nil
+		}
 	}
-	// If type is an interface attempt to find type that conforms to the interface.
+	t := key.Type
+	// If type is an interface attempt to find type with the same name that conforms to the interface.
 	if t.Kind() == reflect.Interface {
-		for bt, binding := range s.bindings {
-			if bt.Implements(t) {
-				return binding, nil
+		if binding := s.implementor(key); binding != nil {
+			return binding, s, nil
+		}
+	}
+	// Directional channels are converted from bidirectional channels bound to this injector.
+	if binding := s.resolveChan(key); binding != nil {
+		return binding, s, nil
+	}
+	// Slices of MapEntry structs are built from the matching map.
+	if binding := s.resolveEntries(key); binding != nil {
+		return binding, s, nil
+	}
+	// Maps of factory functions build named bindings on demand.
+	if binding := s.resolveFactories(key); binding != nil {
+		return binding, s, nil
+	}
+	// If type is a slice, attempt to find providers that provide slices of types assignable to its
+	// elements, such as implementations of an interface. Slices of interfaces always resolve in the
+	// root injector, even if empty.
+	if key.Name == "" && t.Kind() == reflect.Slice {
+		if binding, n := s.resolveSlice(t); n > 0 || (t.Elem().Kind() == reflect.Interface && s.parent == nil) {
+			return binding, s, nil
+		}
+	}
+	// Similarly for maps, whose keys must match.
+	if key.Name == "" && t.Kind() == reflect.Map {
+		if binding, n := s.resolveMapping(t); n > 0 || (t.Elem().Kind() == reflect.Interface && s.parent == nil) {
+			return binding, s, nil
+		}
+	}
+	// Each injector provides its own Cleanup.
+	if key == (Key{Type: cleanupType}) {
+		return s.cleanupBinding(), s, nil
+	}
+	// Parameter structs are built from their fields.
+	if key.Name == "" && isInStruct(t) {
+		return s.resolveIn(t), s, nil
+	}
+
+	if s.parent != nil {
+		binding, owner, err := s.parent.resolveOwner(key)
+		if err != nil {
+			for _, parent := range s.parents {
+				if binding, owner, perr := parent.resolveOwner(key); perr == nil {
+					return binding, owner, nil
+				}
 			}
+			return s.fallback(key, err)
 		}
+		return binding, owner, nil
+	}
+	if key.Name != "" {
+		return s.fallback(key, fmt.Errorf("unbound key %s", key))
+	}
+	hint := s.instantiationHint(t)
+	if hint == "" {
+		hint = s.suggestType(t)
 	}
-	// If type is a slice of interfaces, attempt to find providers that provide slices
-	// of types that implement that interface.
-	if t.Kind() == reflect.Slice && t.Elem().Kind() == reflect.Interface {
-		return s.resolveSlice(t)
+	return s.fallback(key, fmt.Errorf("unbound type %s%s", t.String(), hint))
+}
+
+// instantiationHint returns a hint listing the bound instantiations of the same generic type as t,
+// if t is an instantiation of a generic type.
+func (s *SafeInjector) instantiationHint(t reflect.Type) string {
+	origin := genericOrigin(t)
+	if origin == "" {
+		return ""
 	}
-	// If type is a map of interface values, attempt to find providers that provide maps of values
-	// that implement that interface. Keys must match.
-	if t.Kind() == reflect.Map && t.Elem().Kind() == reflect.Interface {
-		return s.resolveMapping(t)
+	bound := []string{}
+	for _, key := range s.bindingOrder {
+		if genericOrigin(key.Type) == origin {
+			bound = append(bound, key.Type.String())
+		}
+	}
+	if len(bound) == 0 {
+		return ""
+	}
+	return fmt.Sprintf(" (only %s are bound; generic providers must be bound for each instantiation, see BindInstantiations())", strings.Join(bound, ", "))
+}
+
+// genericOrigin returns the name of the generic type that t is an instantiation of, or "".
+func genericOrigin(t reflect.Type) string {
+	name := t.String()
+	if j := strings.Index(name, "["); j > 0 && strings.HasSuffix(name, "]") {
+		return name[:j]
 	}
+	return ""
+}
 
+// Implementations returns the types bound to this injector or its ancestors that implement the
+// interface iface, specified as with Has(), without building them. Types bound by ancestors come
+// first, then each injector's types in the order they were bound. Each type is listed once, even
+// if bound under several names.
+func (s *SafeInjector) Implementations(iface interface{}) []reflect.Type {
+	it := keyOf(iface).Type
+	if it.Kind() != reflect.Interface {
+		return nil
+	}
+	var out []reflect.Type
 	if s.parent != nil {
-		return s.parent.resolve(t)
+		out = s.parent.Implementations(iface)
+	}
+	for _, key := range s.bindingOrder {
+		if key.Type.Implements(it) && !containsType(out, key.Type) {
+			out = append(out, key.Type)
+		}
+	}
+	return out
+}
+
+// GetAll builds every value bound to this injector or its ancestors, under any name, whose type
+// implements the interface iface, specified as with Has(). Values are in the order of
+// Implementations(), with each type's unnamed binding first. A key bound by both an injector and
+// its ancestor is only built once, from the nearest binding.
+//
+//	subscribers, err := injector.GetAll((*EventSubscriber)(nil))
+func (s *SafeInjector) GetAll(iface interface{}) ([]interface{}, error) {
+	it := keyOf(iface).Type
+	if it.Kind() != reflect.Interface {
+		return nil, fmt.Errorf("GetAll() requires an interface but got %s", it)
+	}
+	// Ancestors first.
+	chain := []*SafeInjector{}
+	for p := s; p != nil; p = p.parent {
+		chain = append([]*SafeInjector{p}, chain...)
+	}
+	keys := []Key{}
+	for _, t := range s.Implementations(iface) {
+		for _, named := range []bool{false, true} {
+			for _, p := range chain {
+				for _, key := range p.bindingOrder {
+					if key.Type == t && (key.Name != "") == named && !containsKey(keys, key) {
+						keys = append(keys, key)
+					}
+				}
+			}
+		}
+	}
+	out := make([]interface{}, 0, len(keys))
+	for _, key := range keys {
+		v, err := s.GetKey(key)
+		if err != nil {
+			return nil, fmt.Errorf("couldn't build %s: %s", key, err)
+		}
+		out = append(out, v)
+	}
+	return out, nil
+}
+
+// implementor returns the first binding with the same name as key whose type implements the
+// interface key.Type, preferring bindings of interface types, or nil.
+func (s *SafeInjector) implementor(key Key) *Binding {
+	s.lock.Lock()
+	var found *Binding
+	for _, bk := range s.bindingOrder {
+		if bk.Name != key.Name || !bk.Type.Implements(key.Type) {
+			continue
+		}
+		// Bindings explicitly providing an interface take precedence over concrete implementations.
+		if bk.Type.Kind() == reflect.Interface {
+			found = s.bindings[bk]
+			break
+		}
+		if found == nil {
+			found = s.bindings[bk]
+		}
+	}
+	s.lock.Unlock()
+	if found != nil {
+		s.log("debug", "resolved interface to implementation", "key", key, "implementation", found.Provides)
 	}
-	return &Binding{}, fmt.Errorf("unbound type %s", t.String())
+	return found
 }
 
-// Get acquires a value of type t from the injector.
+// Get acquires a value of the type of t from the injector, where t is an example value such as
+// &Config{}, or a nil pointer to an interface. Use GetType() when the type is a reflect.Type.
 //
 // It is usually preferable to use Call().
 func (s *SafeInjector) Get(t interface{}) (interface{}, error) {
-	return s.getReflected(reflect.TypeOf(t))
+	return s.GetType(reflect.TypeOf(t))
 }
 
-func (s *SafeInjector) getReflected(t reflect.Type) (interface{}, error) {
-	if t.Kind() == reflect.Ptr && t.Elem().Kind() == reflect.Interface {
-		t = t.Elem()
+// GetType acquires a value of type t from the injector.
+//
+// As with Get(), a pointer to an interface refers to the interface itself.
+func (s *SafeInjector) GetType(t reflect.Type) (interface{}, error) {
+	return s.GetKey(Key{Type: t})
+}
+
+// buildRecovered builds a binding as part of the resolution r, converting any panic into a
+// *PanicError.
+func buildRecovered(r *resolving, key Key, binding *Binding) (v interface{}, err error) {
+	defer func() {
+		if p := recover(); p != nil {
+			err = &PanicError{Key: key, Provider: binding.provider, Value: p, Stack: debug.Stack()}
+		}
+	}()
+	return binding.buildIn(r)
+}
+
+// GetKey acquires the value bound to key from the injector.
+//
+// As with Get(), a key type that is a pointer to an interface refers to the interface itself.
+func (s *SafeInjector) GetKey(key Key) (interface{}, error) {
+	if key.Type.Kind() == reflect.Ptr && key.Type.Elem().Kind() == reflect.Interface {
+		key.Type = key.Type.Elem()
 	}
-	binding, err := s.resolve(t)
+	v, err := s.getKey(s.requester.live(), key)
+	if tracer := s.tracer(); tracer != nil {
+		tracer.record(s.traceKey(key, map[cycleNode]bool{}), err)
+	}
+	return v, err
+}
+
+// getKey builds the value bound to key as part of the resolution r.
+func (s *SafeInjector) getKey(r *resolving, key Key) (interface{}, error) {
+	binding, owner, err := s.resolveOwner(key)
 	if err != nil {
 		return nil, err
 	}
-	// Detect recursive bindings.
-	if s.stack[binding.Provides] {
-		return nil, fmt.Errorf("recursive binding")
+	if r.building(binding) {
+		return nil, fmt.Errorf("recursive binding %s: it was requested again while being built", key)
+	}
+	owner.warnDeprecated(key, binding)
+	v, err := s.intercept(Request{Key: key, Binding: binding, Injector: s.view(r)}, func() (interface{}, error) {
+		in := r.push(binding, nil)
+		defer in.finish()
+		v, err := buildRecovered(in, key, binding)
+		if err == nil && binding.release != nil {
+			s.onClose(r.singletonOwner(), func() { binding.release(v) })
+		}
+		return v, err
+	})
+	if berr, ok := err.(*BuildError); ok {
+		return nil, berr.requiredBy(key, owner, binding)
 	}
-	s.stack[binding.Provides] = true
-	defer func() { delete(s.stack, binding.Provides) }()
-	return binding.Build()
+	if err != nil && binding.Description != "" {
+		return nil, fmt.Errorf("%s (%s): %w", key, binding.Description, err)
+	}
+	return v, err
+}
+
+func (s *SafeInjector) getReflected(t reflect.Type) (interface{}, error) {
+	return s.getKey(s.requester.live(), Key{Type: t})
+}
+
+// Prewarm eagerly builds the values of the given types and everything they require, so that
+// singletons on a critical path are constructed up front rather than on first use.
+//
+// Types are specified as with Get(), or as a Key for named bindings. All types are built even if
+// some fail, in which case an Errors value is returned.
+func (s *SafeInjector) Prewarm(types ...interface{}) error {
+	errs := Errors{}
+	for _, t := range types {
+		key, ok := t.(Key)
+		if !ok {
+			key = Key{Type: reflect.TypeOf(t)}
+		}
+		if _, err := s.GetKey(key); err != nil {
+			errs = append(errs, fmt.Errorf("couldn't prewarm %s: %s", key, err))
+		}
+	}
+	if len(errs) > 0 {
+		return errs
+	}
+	return nil
 }
 
 // Call f, injecting any arguments.
 func (s *SafeInjector) Call(f interface{}) ([]interface{}, error) {
+	return s.CallWith(f)
+}
+
+// CallKeyed calls f, injecting any arguments, resolving parameters whose type matches that of one
+// of keys using that key. This allows named bindings to be injected.
+func (s *SafeInjector) CallKeyed(f interface{}, keys ...Key) ([]interface{}, error) {
+	return s.tracedCall(f, nil, keys)
+}
+
+// CallWith calls f, injecting any arguments, with values in extras taking precedence over bindings.
+//
+// Each parameter of f is satisfied by the first value in extras of exactly the same type, or
+// failing that by the first value assignable to it. Remaining parameters are injected. Extras are
+// only used for the parameters of f itself, not for any dependencies built to satisfy them.
+func (s *SafeInjector) CallWith(f interface{}, extras ...interface{}) ([]interface{}, error) {
+	return s.tracedCall(f, extras, nil)
+}
+
+func (s *SafeInjector) tracedCall(f interface{}, extras []interface{}, keys []Key) ([]interface{}, error) {
+	out, err := s.call(s.requester.live(), f, extras, keys)
+	if tracer := s.tracer(); tracer != nil {
+		tracer.record(s.traceCall(f, extras, keys), err)
+	}
+	return out, err
+}
+
+func (s *SafeInjector) call(r *resolving, f interface{}, extras []interface{}, keys []Key) ([]interface{}, error) {
+	args, err := s.callArgs(r, f, extras, keys)
+	if err != nil {
+		return nil, err
+	}
+	return invoke(f, args)
+}
+
+// callArgs injects the arguments of f as part of the resolution r, with values in extras taking
+// precedence over bindings, and parameters whose type matches one of keys resolved using that key.
+func (s *SafeInjector) callArgs(r *resolving, f interface{}, extras []interface{}, keys []Key) ([]reflect.Value, error) {
 	ft := reflect.TypeOf(f)
-	args := []reflect.Value{}
+	args := make([]reflect.Value, ft.NumIn())
+	pending := []injectArg{}
 	for ai := 0; ai < ft.NumIn(); ai++ {
-		a, err := s.getReflected(ft.In(ai))
-		if err != nil {
-			return nil, fmt.Errorf("couldn't inject argument %d of %s: %s", ai+1, ft, err)
+		at := ft.In(ai)
+		if extra, ok := matchExtra(at, extras); ok {
+			args[ai] = extra
+			continue
+		}
+		key := matchKey(at, keys)
+		if isVariadicArg(ft, ai) && !s.canResolve(key) {
+			args[ai] = reflect.MakeSlice(at, 0, 0)
+			continue
 		}
-		args = append(args, reflect.ValueOf(a))
+		pending = append(pending, injectArg{ai, key})
+	}
+	if err := s.injectArgs(r, ft, args, pending); err != nil {
+		return nil, err
+	}
+	return args, nil
+}
+
+// invoke calls f with args, returning its results, or the error it returned if its last result is
+// a non-nil error.
+func invoke(f interface{}, args []reflect.Value) ([]interface{}, error) {
+	ft := reflect.TypeOf(f)
+	var returns []reflect.Value
+	if ft.IsVariadic() {
+		returns = reflect.ValueOf(f).CallSlice(args)
+	} else {
+		returns = reflect.ValueOf(f).Call(args)
 	}
-	returns := reflect.ValueOf(f).Call(args)
 	last := len(returns) - 1
 	if len(returns) > 0 && returns[last].Type() == errorType && !returns[last].IsNil() {
 		return nil, returns[last].Interface().(error)
@@ -302,15 +1099,110 @@ func (s *SafeInjector) Call(f interface{}) ([]interface{}, error) {
 	return out, nil
 }
 
+// isVariadicArg returns true if argument ai of ft is its variadic parameter, which is injected with
+// the merged Sequence() of its element type, or an empty slice if there is none.
+func isVariadicArg(ft reflect.Type, ai int) bool {
+	return ft.IsVariadic() && ai == ft.NumIn()-1
+}
+
+// canResolve returns true if a binding for key can be resolved, without building it.
+func (s *SafeInjector) canResolve(key Key) bool {
+	_, _, err := s.resolveOwner(key)
+	return err == nil
+}
+
+// matchExtra finds the value in extras that best matches type t.
+func matchExtra(t reflect.Type, extras []interface{}) (reflect.Value, bool) {
+	for _, extra := range extras {
+		if reflect.TypeOf(extra) == t {
+			return reflect.ValueOf(extra), true
+		}
+	}
+	for _, extra := range extras {
+		if extra != nil && reflect.TypeOf(extra).AssignableTo(t) {
+			return reflect.ValueOf(extra), true
+		}
+	}
+	return reflect.Value{}, false
+}
+
+// matchKey returns the key in keys for type t, or an unnamed key for t.
+func matchKey(t reflect.Type, keys []Key) Key {
+	for _, key := range keys {
+		kt := key.Type
+		if kt.Kind() == reflect.Ptr && kt.Elem().Kind() == reflect.Interface {
+			kt = kt.Elem()
+		}
+		if kt == t {
+			return Key{Type: t, Name: key.Name}
+		}
+	}
+	return Key{Type: t}
+}
+
 // Child creates a child SafeInjector whose bindings overlay those of the parent.
 //
 // The parent will never be modified by the child.
 func (s *SafeInjector) Child() *SafeInjector {
 	c := SafeNew()
 	c.parent = s
+	s.log("debug", "created child injector")
 	return c
 }
 
+// ResetSingleton discards the cached value of the singleton providing the type of t, so that it
+// will be rebuilt the next time it is requested. As with Close(), a value implementing io.Closer
+// is closed, and Cleanup functions registered while building it are called.
+//
+// As with Get(), interfaces are specified with a nil pointer to the interface.
+func (s *SafeInjector) ResetSingleton(t interface{}) error {
+	rt := reflect.TypeOf(t)
+	if rt.Kind() == reflect.Ptr && rt.Elem().Kind() == reflect.Interface {
+		rt = rt.Elem()
+	}
+	found := false
+	errs := Errors{}
+	for _, cache := range s.singletons {
+		if cache.provides == rt {
+			if err := cache.discard(); err != nil {
+				errs = append(errs, err)
+			}
+			found = true
+		}
+	}
+	if !found {
+		return fmt.Errorf("no singleton bound for %s", rt)
+	}
+	if len(errs) > 0 {
+		return errs
+	}
+	return nil
+}
+
+// ResetAllSingletons discards the cached values of all singletons in this injector, including
+// those shared by its children, closing them as with ResetSingleton(). See ShareSingletons().
+//
+// Singletons in parent injectors are not affected. All values are discarded even if some fail to
+// close, in which case an Errors value is returned.
+func (s *SafeInjector) ResetAllSingletons() error {
+	errs := Errors{}
+	for _, cache := range s.singletons {
+		if err := cache.discard(); err != nil {
+			errs = append(errs, err)
+		}
+	}
+	s.lock.Lock()
+	shared := s.childSingletons
+	s.lock.Unlock()
+	if shared != nil {
+		errs = append(errs, shared.discard()...)
+	}
+	if len(errs) > 0 {
+		return errs
+	}
+	return nil
+}
+
 // Validate that the function f can be called by the injector.
 func (s *SafeInjector) Validate(f interface{}) error {
 	ft := reflect.TypeOf(f)
@@ -320,7 +1212,7 @@ func (s *SafeInjector) Validate(f interface{}) error {
 	// First, check that all existing bindings are satisfiable.
 	for _, binding := range s.bindings {
 		for _, req := range binding.Requires {
-			if _, err := s.resolve(req); err != nil {
+			if _, err := s.resolve(req); err != nil && !binding.isOptional(req) {
 				return fmt.Errorf("no binding for %s required by %s: %s", req, binding.Provides, err)
 			}
 		}
@@ -328,9 +1220,15 @@ func (s *SafeInjector) Validate(f interface{}) error {
 	// Next, check the function arguments are satisfiable.
 	for j := 0; j < ft.NumIn(); j++ {
 		at := ft.In(j)
+		if isVariadicArg(ft, j) && !s.canResolve(Key{Type: at}) {
+			continue
+		}
 		if _, err := s.resolve(at); err != nil {
 			return fmt.Errorf("couldn't satisfy argument %d of %s: %s", j, ft, err)
 		}
+		if err := s.validateIn(at); err != nil {
+			return fmt.Errorf("couldn't satisfy argument %d of %s: %s", j, ft, err)
+		}
 	}
 	return nil
 }
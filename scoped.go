@@ -0,0 +1,94 @@
+package inject
+
+import (
+	"sync"
+)
+
+// scopes are the child injectors of an injector created by Scoped(), keyed by scope key.
+type scopes struct {
+	lock     sync.Mutex
+	children map[interface{}]*SafeInjector
+	order    []interface{}
+	setup    []func(key interface{}, scope *SafeInjector) error
+}
+
+// OnScope registers a function that configures each child injector created by Scoped(), such as
+// by binding values for the user or tenant identified by key. Functions are called in the order
+// they were registered, and must not call Scoped() or Evict() on this injector.
+func (s *SafeInjector) OnScope(setup func(key interface{}, scope *SafeInjector) error) {
+	s.scopes.lock.Lock()
+	defer s.scopes.lock.Unlock()
+	s.scopes.setup = append(s.scopes.setup, setup)
+}
+
+// Scoped returns the child injector for key, such as a user, tenant or connection ID, creating it
+// and configuring it with the OnScope() functions if there is none. key must be comparable.
+//
+// The child is retained until evicted with Evict() or EvictAll(). If configuring a new child
+// fails it is closed and not retained.
+func (s *SafeInjector) Scoped(key interface{}) (*SafeInjector, error) {
+	s.scopes.lock.Lock()
+	defer s.scopes.lock.Unlock()
+	if child, ok := s.scopes.children[key]; ok {
+		return child, nil
+	}
+	child := s.Child()
+	for _, setup := range s.scopes.setup {
+		if err := setup(key, child); err != nil {
+			_ = child.Close()
+			return nil, err
+		}
+	}
+	if s.scopes.children == nil {
+		s.scopes.children = map[interface{}]*SafeInjector{}
+	}
+	s.scopes.children[key] = child
+	s.scopes.order = append(s.scopes.order, key)
+	return child, nil
+}
+
+// Scopes returns the keys of the child injectors created by Scoped() that have not been evicted,
+// in the order they were created.
+func (s *SafeInjector) Scopes() []interface{} {
+	s.scopes.lock.Lock()
+	defer s.scopes.lock.Unlock()
+	return append([]interface{}{}, s.scopes.order...)
+}
+
+// Evict closes and discards the child injector for key created by Scoped(), if any, returning any
+// error from its Close().
+func (s *SafeInjector) Evict(key interface{}) error {
+	s.scopes.lock.Lock()
+	child, ok := s.scopes.children[key]
+	if ok {
+		delete(s.scopes.children, key)
+		for j, k := range s.scopes.order {
+			if k == key {
+				s.scopes.order = append(s.scopes.order[:j], s.scopes.order[j+1:]...)
+				break
+			}
+		}
+	}
+	s.scopes.lock.Unlock()
+	if !ok {
+		return nil
+	}
+	return child.Close()
+}
+
+// EvictAll closes and discards every child injector created by Scoped(), in the reverse order to
+// which they were created. All are closed even if some fail, in which case an Errors value is
+// returned.
+func (s *SafeInjector) EvictAll() error {
+	keys := s.Scopes()
+	errs := Errors{}
+	for j := len(keys) - 1; j >= 0; j-- {
+		if err := s.Evict(keys[j]); err != nil {
+			errs = append(errs, err)
+		}
+	}
+	if len(errs) > 0 {
+		return errs
+	}
+	return nil
+}
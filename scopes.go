@@ -0,0 +1,67 @@
+package inject
+
+import (
+	"fmt"
+)
+
+// ValidateScopes checks that no binding visible to this injector outlives a value it requires,
+// which is a "captive dependency". Call it on a child injector, such as one created per request,
+// once its bindings are complete.
+//
+// A binding lives as long as the injector it is bound to, or for singletons the injector that
+// caches their value. Such a binding is reported if it requires a type that is only bound in a
+// child of that injector, such as a parent singleton requiring a per-request *http.Request, or a
+// singleton shared between children with ShareSingletons() requiring a value bound in each child.
+// Singletons requiring a context.Context are also reported, as they capture the context of
+// whichever injector first builds them.
+//
+// All violations are returned, as an Errors value.
+func (s *SafeInjector) ValidateScopes() error {
+	chain := []*SafeInjector{}
+	for p := s; p != nil; p = p.parent {
+		chain = append(chain, p)
+	}
+	depth := func(injector *SafeInjector) int {
+		for j, p := range chain {
+			if p == injector {
+				return len(chain) - j
+			}
+		}
+		return -1
+	}
+	errs := Errors{}
+	for _, p := range chain {
+		for _, key := range p.bindingOrder {
+			binding := p.bindings[key]
+			lifetime := depth(p)
+			if binding.cache != nil && depth(binding.cache.owner) > 0 {
+				lifetime = depth(binding.cache.owner)
+			}
+			for _, req := range binding.Requires {
+				if req == contextType && binding.cache != nil {
+					errs = append(errs, fmt.Errorf("%s (%s) requires context.Context, which would be captured by the first value built",
+						key, binding.annotation))
+					continue
+				}
+				if binding.isOptional(req) {
+					continue
+				}
+				_, owner, err := p.resolveOwner(Key{Type: req})
+				if err != nil {
+					// Resolvable only from further down the chain, if at all.
+					if _, owner, err = s.resolveOwner(Key{Type: req}); err != nil {
+						continue
+					}
+				}
+				if depth(owner) > lifetime {
+					errs = append(errs, fmt.Errorf("%s (%s) requires %s, which is only bound in a shorter-lived child injector",
+						key, binding.annotation, req))
+				}
+			}
+		}
+	}
+	if len(errs) > 0 {
+		return errs
+	}
+	return nil
+}
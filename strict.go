@@ -0,0 +1,137 @@
+package inject
+
+import (
+	"fmt"
+	"reflect"
+)
+
+// SetStrict makes Bind(), BindTo(), Provide(), Override() and Install() on this injector and its
+// children fail if a provider they bind requires a type that is not bound, rather than failing on
+// the first Get(). Requirements are checked once each call returns, so the values bound or
+// modules installed together may require each other in any order. Types that will be bound later
+// can be declared with Expect().
+func (s *SafeInjector) SetStrict(strict bool) {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	s.strict = strict
+}
+
+// Expect declares types that are not yet bound but will be, so that strict binding does not fail
+// on providers requiring them. Types are specified as with Has(). See SetStrict().
+func (s *SafeInjector) Expect(types ...interface{}) {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	if s.expected == nil {
+		s.expected = map[reflect.Type]bool{}
+	}
+	for _, t := range types {
+		s.expected[keyOf(t).Type] = true
+	}
+}
+
+// isStrict returns true if s or any of its ancestors is strict, along with the types they expect.
+func (s *SafeInjector) isStrict() (strict bool, expected map[reflect.Type]bool) {
+	expected = map[reflect.Type]bool{}
+	for ; s != nil; s = s.parent {
+		s.lock.Lock()
+		strict = strict || s.strict
+		for t := range s.expected {
+			expected[t] = true
+		}
+		s.lock.Unlock()
+	}
+	return strict, expected
+}
+
+// strictly runs bind, then checks the requirements of the bindings it made if the injector is
+// strict. Nested calls are checked when the outermost returns.
+func (s *SafeInjector) strictly(bind func() error) error {
+	s.binding++
+	err := func() error {
+		defer func() { s.binding-- }()
+		return bind()
+	}()
+	if s.binding > 0 {
+		return err
+	}
+	unchecked := s.unchecked
+	s.unchecked = nil
+	if err != nil {
+		return err
+	}
+	strict, expected := s.isStrict()
+	if !strict {
+		return nil
+	}
+	for _, key := range unchecked {
+		binding, ok := s.bindings[key]
+		if !ok {
+			continue
+		}
+		for _, req := range binding.Requires {
+			if expected[req] || binding.isOptional(req) {
+				continue
+			}
+			if _, err := s.resolve(req); err != nil {
+				return fmt.Errorf("no binding for %s required by %s%s", req, key, s.suggestType(req))
+			}
+		}
+	}
+	return nil
+}
+
+// suggestType returns a hint naming a bound key that t may have been mistaken for, if any: the
+// pointer or value type of t, a type of the same name in another package, a named binding of t,
+// or, if t is an interface, a named binding implementing it or a bound type whose pointer
+// implements it.
+//
+// Only keys bound directly are considered, so that this can be used while reporting resolution
+// errors.
+func (s *SafeInjector) suggestType(t reflect.Type) string {
+	keys := []Key{}
+	for p := s; p != nil; p = p.parent {
+		keys = append(keys, p.bindingOrder...)
+	}
+	if t.Kind() == reflect.Ptr && containsKey(keys, Key{Type: t.Elem()}) {
+		return fmt.Sprintf(" (did you mean %s? it is bound as a value, not a pointer; see SetAdaptPointers())", t.Elem())
+	}
+	if containsKey(keys, Key{Type: reflect.PtrTo(t)}) {
+		return fmt.Sprintf(" (did you mean %s? it is bound as a pointer, not a value; see SetAdaptPointers())", reflect.PtrTo(t))
+	}
+	for _, key := range keys {
+		if key.Name == "" && key.Type != t && sameNameInOtherPackage(key.Type, t) {
+			return fmt.Sprintf(" (did you mean %s from package %q?)", key.Type, elemType(key.Type).PkgPath())
+		}
+	}
+	for _, key := range keys {
+		if key.Name != "" && (key.Type == t || (t.Kind() == reflect.Interface && key.Type.Implements(t))) {
+			return fmt.Sprintf(" (did you mean %s? it is bound with a name)", key)
+		}
+	}
+	if t.Kind() == reflect.Interface {
+		for _, key := range keys {
+			if key.Type.Kind() != reflect.Ptr && reflect.PtrTo(key.Type).Implements(t) {
+				return fmt.Sprintf(" (did you mean to bind %s? %s is bound, but only %s implements %s)",
+					reflect.PtrTo(key.Type), key.Type, reflect.PtrTo(key.Type), t)
+			}
+		}
+	}
+	return ""
+}
+
+// sameNameInOtherPackage returns true if a and b are, or point to, named types with the same name
+// declared in different packages.
+func sameNameInOtherPackage(a, b reflect.Type) bool {
+	for a.Kind() == reflect.Ptr && b.Kind() == reflect.Ptr {
+		a, b = a.Elem(), b.Elem()
+	}
+	return a.Name() != "" && a.Name() == b.Name() && a.PkgPath() != b.PkgPath()
+}
+
+// elemType returns the type that t points to, through any number of pointers.
+func elemType(t reflect.Type) reflect.Type {
+	for t.Kind() == reflect.Ptr {
+		t = t.Elem()
+	}
+	return t
+}
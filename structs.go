@@ -0,0 +1,192 @@
+package inject
+
+import (
+	"fmt"
+	"reflect"
+)
+
+// In is embedded in a struct accepted by a provider or Call() target to indicate that each
+// exported field of the struct should be injected individually, rather than the struct itself.
+//
+// Fields tagged with `name:"..."` are injected from the named binding. Fields tagged with
+// `optional:"true"` are left as the zero value if their type is not bound. Slice fields tagged
+// with `group:"..."` are injected with the named value group, or left empty if it has no elements.
+//
+//	type Params struct {
+//		inject.In
+//
+//		DB         *sql.DB
+//		Replica    *sql.DB      `name:"replica"`
+//		Log        *log.Logger  `optional:"true"`
+//		Migrations []Migration  `group:"migrations"`
+//	}
+//
+//	func NewUserStore(params Params) *UserStore { ... }
+type In struct{}
+
+var inType = reflect.TypeOf(In{})
+
+// Out is embedded in a struct returned by a provider to indicate that each exported field of the
+// struct should also be bound individually.
+//
+// Fields tagged with `name:"..."` are bound with that name. Fields tagged with `group:"..."`
+// contribute to a Sequence() of their type, named by the group.
+//
+//	type Storage struct {
+//		inject.Out
+//
+//		Users   *UserStore
+//		Replica *sql.DB `name:"replica"`
+//		Checks  Check   `group:""`
+//	}
+//
+//	func NewStorage(db *sql.DB) Storage { ... }
+type Out struct{}
+
+var outType = reflect.TypeOf(Out{})
+
+// isInStruct returns true if t is a struct embedding In.
+func isInStruct(t reflect.Type) bool {
+	return embeds(t, inType)
+}
+
+// validateIn checks that the required fields of t are bound, if it is an In struct.
+func (s *SafeInjector) validateIn(t reflect.Type) error {
+	if !isInStruct(t) {
+		return nil
+	}
+	for j := 0; j < t.NumField(); j++ {
+		f := t.Field(j)
+		key, optional := inFieldKey(f)
+		if f.Type == inType || f.PkgPath != "" || optional {
+			continue
+		}
+		if _, _, err := s.resolveOwner(key); err != nil {
+			return fmt.Errorf("field %s.%s: %s", t, f.Name, err)
+		}
+	}
+	return nil
+}
+
+// isOutStruct returns true if t is a struct embedding Out.
+func isOutStruct(t reflect.Type) bool {
+	return embeds(t, outType)
+}
+
+func embeds(t reflect.Type, marker reflect.Type) bool {
+	if t.Kind() != reflect.Struct {
+		return false
+	}
+	for j := 0; j < t.NumField(); j++ {
+		if f := t.Field(j); f.Anonymous && f.Type == marker {
+			return true
+		}
+	}
+	return false
+}
+
+// inFieldKey returns the key injected into field f of an In struct, and whether it may be left
+// unbound.
+func inFieldKey(f reflect.StructField) (key Key, optional bool) {
+	if group, ok := f.Tag.Lookup("group"); ok {
+		return Key{Type: f.Type, Name: group}, true
+	}
+	return Key{Type: f.Type, Name: f.Tag.Get("name")}, f.Tag.Get("optional") == "true"
+}
+
+// resolveIn returns a binding that builds the In struct t by injecting each of its fields.
+func (s *SafeInjector) resolveIn(t reflect.Type) *Binding {
+	requires := []reflect.Type{}
+	for j := 0; j < t.NumField(); j++ {
+		f := t.Field(j)
+		if key, optional := inFieldKey(f); f.Type == inType || f.PkgPath != "" || key.Name != "" || optional {
+			continue
+		}
+		requires = append(requires, f.Type)
+	}
+	binding := &Binding{
+		Provides: t,
+		Requires: requires,
+	}
+	binding.setBuild(func(r *resolving) (interface{}, error) {
+		out := reflect.New(t).Elem()
+		for j := 0; j < t.NumField(); j++ {
+			f := t.Field(j)
+			if f.Type == inType || f.PkgPath != "" {
+				continue
+			}
+			key, optional := inFieldKey(f)
+			if optional && !s.canResolve(key) {
+				continue
+			}
+			v, err := s.getKey(r, key)
+			if berr, ok := err.(*BuildError); ok {
+				return nil, berr
+			} else if err != nil {
+				return nil, fmt.Errorf("couldn't inject field %s.%s: %s", t, f.Name, err)
+			}
+			if v != nil {
+				out.Field(j).Set(reflect.ValueOf(v))
+			}
+		}
+		return out.Interface(), nil
+	})
+	return binding
+}
+
+// bindOutFields binds each exported field of the Out struct bound to key.
+func (s *SafeInjector) bindOutFields(key Key) error {
+	t := key.Type
+	for j := 0; j < t.NumField(); j++ {
+		f := t.Field(j)
+		if f.Type == outType || f.PkgPath != "" {
+			continue
+		}
+		var annotation Annotation = &outFieldType{key, j, false}
+		if group, ok := f.Tag.Lookup("group"); ok {
+			annotation = Sequence(&namedType{group, &outFieldType{key, j, true}})
+		} else if name := f.Tag.Get("name"); name != "" {
+			annotation = &namedType{name, annotation}
+		}
+		if _, err := s.bindAnnotation(annotation); err != nil {
+			return fmt.Errorf("%s.%s: %s", t, f.Name, err)
+		}
+	}
+	return nil
+}
+
+// outFieldType binds a field of an Out struct.
+type outFieldType struct {
+	owner Key
+	field int
+	slice bool // Provide the field wrapped in a single element slice.
+}
+
+func (o *outFieldType) Build(i *SafeInjector) (*Binding, error) {
+	ft := o.owner.Type.Field(o.field).Type
+	provides := ft
+	if o.slice {
+		provides = reflect.SliceOf(ft)
+	}
+	binding := &Binding{
+		Provides: provides,
+		Requires: []reflect.Type{o.owner.Type},
+	}
+	binding.setBuild(func(r *resolving) (interface{}, error) {
+		v, err := i.getKey(r, o.owner)
+		if err != nil {
+			return nil, err
+		}
+		field := reflect.ValueOf(v).Field(o.field)
+		if o.slice {
+			out := reflect.MakeSlice(provides, 0, 1)
+			return reflect.Append(out, field).Interface(), nil
+		}
+		return field.Interface(), nil
+	})
+	return binding, nil
+}
+
+func (o *outFieldType) Is(annotation Annotation) bool {
+	return reflect.TypeOf(annotation) == reflect.TypeOf(&outFieldType{})
+}
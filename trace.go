@@ -0,0 +1,224 @@
+package inject
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"reflect"
+	"strings"
+	"sync"
+)
+
+// A Trace describes how a requested key was resolved: the binding chosen to satisfy it, and the
+// traces of that binding's requirements.
+type Trace struct {
+	Key string `json:"key"`
+	// Binding identifies the binding chosen, by provider function where there is one.
+	Binding     string `json:"binding,omitempty"`
+	Description string `json:"description,omitempty"`
+	// Parent is true if the binding was found in a parent injector.
+	Parent bool `json:"parent,omitempty"`
+	// Repeated is true if the key has already been traced earlier in the same tree, in which case
+	// its requirements are not repeated.
+	Repeated bool   `json:"repeated,omitempty"`
+	Error    string `json:"error,omitempty"`
+	// Annotation is the annotation that created the binding, such as "Singleton".
+	Annotation string `json:"annotation,omitempty"`
+	// Module is the type of the installed module that bound the binding, if any.
+	Module string `json:"module,omitempty"`
+	// Cached is true if the binding is a singleton whose value has already been built.
+	Cached       bool     `json:"cached,omitempty"`
+	Requirements []*Trace `json:"requirements,omitempty"`
+}
+
+// String renders the trace as an indented tree.
+func (t *Trace) String() string {
+	w := &strings.Builder{}
+	t.write(w, "", false)
+	return w.String()
+}
+
+// write the trace to w, including the annotation, module and cache state of each binding if
+// verbose.
+func (t *Trace) write(w *strings.Builder, indent string, verbose bool) {
+	fmt.Fprintf(w, "%s%s", indent, t.Key)
+	if t.Binding != "" {
+		fmt.Fprintf(w, " <- %s", t.Binding)
+	}
+	if verbose && t.Annotation != "" {
+		fmt.Fprintf(w, " via %s()", t.Annotation)
+	}
+	if verbose && t.Module != "" {
+		fmt.Fprintf(w, " from module %s", t.Module)
+	}
+	if t.Description != "" {
+		fmt.Fprintf(w, " (%s)", t.Description)
+	}
+	if t.Parent {
+		w.WriteString(" [parent]")
+	}
+	if verbose && t.Cached {
+		w.WriteString(" [cached]")
+	}
+	if t.Repeated {
+		w.WriteString(" [repeated]")
+	}
+	if t.Error != "" {
+		fmt.Fprintf(w, " ERROR: %s", t.Error)
+	}
+	w.WriteString("\n")
+	for _, req := range t.Requirements {
+		req.write(w, indent+"  ", verbose)
+	}
+}
+
+// A Tracer records the resolution of every Get() and Call() made on the injectors it is attached
+// to. See SafeInjector.SetTracer().
+//
+// Tracing walks the bindings of the injector for every request, so should only be enabled when
+// debugging.
+type Tracer struct {
+	lock   sync.Mutex
+	traces []*Trace
+}
+
+// NewTracer creates a new Tracer.
+func NewTracer() *Tracer {
+	return &Tracer{}
+}
+
+func (t *Tracer) record(trace *Trace, err error) {
+	if err != nil && trace.Error == "" {
+		trace.Error = err.Error()
+	}
+	t.lock.Lock()
+	defer t.lock.Unlock()
+	t.traces = append(t.traces, trace)
+}
+
+// Traces returns the recorded traces, in the order the requests completed.
+func (t *Tracer) Traces() []*Trace {
+	t.lock.Lock()
+	defer t.lock.Unlock()
+	return append([]*Trace{}, t.traces...)
+}
+
+// Reset discards all recorded traces.
+func (t *Tracer) Reset() {
+	t.lock.Lock()
+	defer t.lock.Unlock()
+	t.traces = nil
+}
+
+// WriteText writes the recorded traces to w as indented trees.
+func (t *Tracer) WriteText(w io.Writer) error {
+	for _, trace := range t.Traces() {
+		if _, err := io.WriteString(w, trace.String()); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// WriteJSON writes the recorded traces to w as a JSON array.
+func (t *Tracer) WriteJSON(w io.Writer) error {
+	enc := json.NewEncoder(w)
+	enc.SetIndent("", "  ")
+	return enc.Encode(t.Traces())
+}
+
+// SetTracer records the resolution of every subsequent Get() and Call() on this injector, and its
+// children, into tracer. A nil tracer disables tracing.
+func (s *SafeInjector) SetTracer(tracer *Tracer) {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	s.tracing = tracer
+}
+
+// tracer returns the Tracer of s or its closest ancestor, if any.
+func (s *SafeInjector) tracer() *Tracer {
+	for ; s != nil; s = s.parent {
+		s.lock.Lock()
+		tracer := s.tracing
+		s.lock.Unlock()
+		if tracer != nil {
+			return tracer
+		}
+	}
+	return nil
+}
+
+// Explain describes how a value of type t would be resolved, without building it: the binding that
+// would be used, the annotation that created it, the module that bound it, whether its value is
+// already cached, and the same for each of its requirements, recursively. Types are specified as
+// with Has().
+//
+//	string <- main.NewGreeting via Singleton() from module main.GreetingModule [cached]
+//	  int <- int via Literal()
+func (s *SafeInjector) Explain(t interface{}) string {
+	w := &strings.Builder{}
+	s.traceKey(keyOf(t), map[cycleNode]bool{}).write(w, "", true)
+	return w.String()
+}
+
+// traceKey traces the resolution of key, as GetKey() would resolve it.
+func (s *SafeInjector) traceKey(key Key, done map[cycleNode]bool) *Trace {
+	trace := &Trace{Key: key.String()}
+	binding, owner, err := s.resolveOwner(key)
+	if err != nil {
+		trace.Error = err.Error()
+		return trace
+	}
+	trace.Binding = binding.provider
+	if trace.Binding == "" {
+		trace.Binding = binding.Provides.String()
+	}
+	trace.Description = binding.Description
+	trace.Parent = owner != s
+	trace.Annotation = binding.annotation
+	if module, ok := owner.moduleOf(key); ok {
+		trace.Module = module.String()
+	}
+	if binding.cache != nil {
+		_, trace.Cached = binding.cache.value()
+	}
+	node := cycleNode{owner, key}
+	if done[node] {
+		trace.Repeated = true
+		return trace
+	}
+	done[node] = true
+	for _, req := range binding.Requires {
+		if binding.isOptional(req) && !owner.canResolve(Key{Type: req}) {
+			trace.Requirements = append(trace.Requirements, &Trace{Key: req.String(), Binding: "empty variadic argument"})
+			continue
+		}
+		trace.Requirements = append(trace.Requirements, owner.traceKey(Key{Type: req}, done))
+	}
+	return trace
+}
+
+// traceCall traces the resolution of the arguments of f, as call() would resolve them.
+func (s *SafeInjector) traceCall(f interface{}, extras []interface{}, keys []Key) *Trace {
+	fv := reflect.ValueOf(f)
+	trace := &Trace{Key: fv.Type().String()}
+	if fv.Kind() != reflect.Func {
+		return trace
+	}
+	trace.Binding = funcName(fv)
+	done := map[cycleNode]bool{}
+	for ai := 0; ai < fv.Type().NumIn(); ai++ {
+		at := fv.Type().In(ai)
+		if _, ok := matchExtra(at, extras); ok {
+			trace.Requirements = append(trace.Requirements, &Trace{Key: at.String(), Binding: "extra argument"})
+			continue
+		}
+		key := matchKey(at, keys)
+		if isVariadicArg(fv.Type(), ai) && !s.canResolve(key) {
+			trace.Requirements = append(trace.Requirements, &Trace{Key: at.String(), Binding: "empty variadic argument"})
+			continue
+		}
+		trace.Requirements = append(trace.Requirements, s.traceKey(key, done))
+	}
+	return trace
+}
@@ -0,0 +1,77 @@
+package inject
+
+import (
+	"context"
+	"fmt"
+	"reflect"
+	"sync"
+)
+
+// A Worker is a long-running background service, such as a queue consumer. Workers bound in a
+// Sequence() are run by Run().
+type Worker interface {
+	// Run until ctx is cancelled or the worker fails.
+	Run(ctx context.Context) error
+}
+
+var workerType = reflect.TypeOf((*Worker)(nil)).Elem()
+
+// Run runs each Worker bound in a Sequence() of Worker, or of types implementing it, in this
+// injector or its parents, concurrently until they have all returned.
+//
+// If any worker fails, the context passed to the others is cancelled and the first error is
+// returned once they have returned. Cancelling ctx likewise stops every worker.
+//
+//	injector.Bind(inject.Sequence([]inject.Worker{&EmailSender{}, &ReportBuilder{}}))
+//	err := injector.Run(ctx)
+func (s *SafeInjector) Run(ctx context.Context) error {
+	workers, err := s.boundWorkers()
+	if err != nil {
+		return err
+	}
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+	wg := sync.WaitGroup{}
+	once := sync.Once{}
+	var first error
+	for _, worker := range workers {
+		wg.Add(1)
+		go func(worker Worker) {
+			defer wg.Done()
+			if err := worker.Run(ctx); err != nil {
+				once.Do(func() {
+					first = fmt.Errorf("worker %T failed: %s", worker, err)
+					cancel()
+				})
+			}
+		}(worker)
+	}
+	wg.Wait()
+	return first
+}
+
+// boundWorkers builds every unnamed slice of Workers, or of types implementing Worker, bound to s or its
+// ancestors.
+func (s *SafeInjector) boundWorkers() ([]Worker, error) {
+	keys := []Key{}
+	for p := s; p != nil; p = p.parent {
+		for _, key := range p.bindingOrder {
+			if key.Name == "" && key.Type.Kind() == reflect.Slice && key.Type.Elem().Implements(workerType) &&
+				!containsKey(keys, key) {
+				keys = append(keys, key)
+			}
+		}
+	}
+	workers := []Worker{}
+	for _, key := range keys {
+		v, err := s.GetKey(key)
+		if err != nil {
+			return nil, err
+		}
+		rv := reflect.ValueOf(v)
+		for j := 0; j < rv.Len(); j++ {
+			workers = append(workers, rv.Index(j).Interface().(Worker))
+		}
+	}
+	return workers, nil
+}